@@ -1,11 +1,23 @@
 import argparse
+import sys
 from source.CP import cp_model
 from source.SAT import sat_model
+from source.SAT.build_model import RESTARTS as SAT_RESTARTS
 from source.MIP import mip_model
+from source.MIP.backends import PROFILES as MIP_PROFILES
 from source.SMT import smt_model
+from source.SMT.build_model import VARIANTS as SMT_VARIANTS
+from source.SMT.smt_utils import load_z3_config
+from source.checker.problems import PROBLEMS as CHECKER_PROBLEMS
+from source.results.merge import POLICIES as MERGE_POLICIES
+from source.results.logs import VERBOSITY as LOG_VERBOSITY, configure_logs
+from source.results.push import set_push_url
+from source.results.regression import DEFAULT_SLOWDOWN, report_batch
+from source.results.runs import set_run_id
+from source.results.sqlite_store import DEFAULT_DATABASE, set_database
 
 
-def run_all_models(selected_model=None):
+def run_all_models(selected_model=None, model_options=None):
     models = {
         "cp": cp_model,
         "sat": sat_model,
@@ -13,16 +25,19 @@ def run_all_models(selected_model=None):
         "mip": mip_model
     }
 
+    # Per-model keyword arguments forwarded to run_all (e.g. {"sat": {"options": {...}}})
+    model_options = model_options or {}
+
     if selected_model:
         if selected_model in models:
             print(f"Running all configurations for model: {selected_model}")
-            models[selected_model].run_all()
+            models[selected_model].run_all(**model_options.get(selected_model, {}))
         else:
             print(f"Model '{selected_model}' not implemented.")
     else:
         for model_name, model in models.items():
             print(f"Running all configurations for model: {model_name}")
-            model.run_all()
+            model.run_all(**model_options.get(model_name, {}))
 
 
 def main():
@@ -31,54 +46,371 @@ def main():
     mode.add_argument("--all", action="store_true",
                       help="Run all configurations for all models (or one model if --model is given)")
     mode.add_argument("--single", action="store_true", help="Run a single configuration")
+    mode.add_argument("--crosscheck", action="store_true",
+                      help="Check the SAT encoding against a brute force enumeration on tiny instances")
+    mode.add_argument("--reference", action="store_true",
+                      help="Compare the optimal objectives of every approach with an exhaustive solver on tiny "
+                           "instances")
+    mode.add_argument("--report", type=str, choices=["table", "latex", "csv", "md", "profile", "vbs", "score",
+                                                       "hardness"],
+                      help="Report the results under res/: table=configuration x instance table per approach, "
+                           "latex=the same tables in LaTeX (booktabs), csv=one row per run with all its metrics, "
+                           "md=markdown summary, profile=performance profiles and cactus plots, "
+                           "vbs=virtual best solver and the contribution of every approach, "
+                           "score=ranking of the configurations by MiniZinc Challenge (Borda) score, "
+                           "hardness=ranking of the instances by difficulty")
+    mode.add_argument("--wilcoxon", nargs=2, metavar="SIDE",
+                      help="Paired Wilcoxon signed-rank tests of the time and objective of two approaches (SAT) or "
+                           "configurations (SAT/z3_sb_opt) over the instances under res/")
+    mode.add_argument("--runs", action="store_true",
+                      help="List the runs of the versioned result directories under runs/")
+    mode.add_argument("--regressions", nargs="?", const="", metavar="RUN_ID",
+                      help="Compare a run of runs/ (default: the latest) with the previous run of every "
+                           "configuration: newly unsolved instances, worse objectives and slowdowns")
+    mode.add_argument("--archive", nargs="?", const="", metavar="RUN_ID",
+                      help="Bundle the results, logs, artifacts and an environment report of a run of runs/ "
+                           "(default: the latest) into a tarball with a manifest, in archives/ or --output")
+    mode.add_argument("--sqlite-import", nargs="+", metavar="DIR",
+                      help="Import result directories laid out as res/ into the SQLite database of --sqlite")
+    mode.add_argument("--merge", nargs="+", metavar="DIR",
+                      help="Merge result directories laid out as res/ (e.g. from different machines) into the "
+                           "--output directory (default: merged)")
+    mode.add_argument("--check", nargs="*", metavar="PATH",
+                      help="Validate the result JSON files under res/ (or the given files / directories) and "
+                           "exit non-zero if any is invalid")
     parser.add_argument("--teams", type=int, default=6, help="Number of teams (for --single)")
     parser.add_argument("--sb", action="store_true", help="Enable symmetry breaking")
     parser.add_argument("--hf", type=int, choices=[1, 2, 3, 4], default=1,
                         help="Search strategy to use: "
                              "1=default, 2=dom/wdeg, 3=dom/wdeg+luby, 4=dom/wdeg+luby+LNS")
     parser.add_argument("--opt", action="store_true", help="Enable optimization")
-    parser.add_argument("--solver", type=str, choices=["gecode", "chuffed", "gurobi", "cplex", "z3", "glucose"],
-                        help="Solver to use (CP: gecode, chuffed | MIP: gurobi, cplex | SAT: z3, glucose | SMT: z3)")
-    parser.add_argument("--model", type=str, choices=["cp", "sat", "smt", "mip"],
-                        help="Which model to run")
+    parser.add_argument("--solver", type=str,
+                        choices=["gecode", "chuffed", "gurobi", "cplex", "highs", "z3", "glucose", "optimathsat", "cvc5"],
+                        help="Solver to use (CP: gecode, chuffed | MIP: gurobi, cplex, highs | SAT: z3, glucose | "
+                             "SMT: z3, optimathsat, cvc5)")
+    parser.add_argument("--model", type=str,
+                        choices=["cp", "sat", "smt", "mip"] + [f"smt_{v}" for v in SMT_VARIANTS if v != "lia"]
+                                + ["smt_compare", "mip_slot", "mip_compare", "mip_auto"],
+                        help="Which model to run (smt_<variant> selects an alternative SMT encoding, "
+                             "smt_compare runs all of them, mip_slot the slot-indexed MIP formulation, "
+                             "mip_compare both MIP formulations, mip_auto picks one by instance size)")
+    parser.add_argument("--seed", type=int, help="Random seed of the SAT solver")
+    parser.add_argument("--restart", type=str, choices=[r for restarts in SAT_RESTARTS.values() for r in restarts],
+                        help="Restart strategy of the SAT solver (Z3: luby, geometric, ema, static | "
+                             "Glucose: glucose)")
+    parser.add_argument("--var-decay", type=float,
+                        help="Variable activity decay factor of the SAT solver, in (0, 1)")
+    parser.add_argument("--reuse-learnts", action="store_true",
+                        help="Reuse the clauses learned by Glucose between consecutive bound queries (SAT --opt)")
+    parser.add_argument("--cubes", type=int, metavar="DEPTH",
+                        help="SAT cube-and-conquer mode: fix the period of team 1 in the first DEPTH weeks "
+                             "and solve the cubes in parallel (Z3 only)")
+    parser.add_argument("--workers", type=int, help="Number of parallel workers (default: all cores)")
+    parser.add_argument("--hybrid", action="store_true",
+                        help="SAT hybrid mode: fix the heuristic schedule of the first teams and let SAT "
+                             "complete the rest, unfixing on UNSAT (Z3 only)")
+    parser.add_argument("--fixed-teams", type=int,
+                        help="Number of teams initially fixed by --hybrid (default: half of them)")
+    parser.add_argument("--lexicographic", action="store_true",
+                        help="After the max imbalance, minimize the total imbalance over the teams (SAT --opt, Z3)")
+    parser.add_argument("--clause-budget", type=int, metavar="CLAUSES",
+                        help="Refuse SAT formulas estimated above this many clauses (outcome 'encoding too large')")
+    parser.add_argument("--proof", action="store_true",
+                        help="Store the DRAT proof of every UNSAT Glucose answer, with its CNF, under artifacts/SAT")
+    parser.add_argument("--disable", nargs="*", metavar="GROUP",
+                        choices=["pairs", "weekly", "period_limit", "channeling", "implied", "symmetry"],
+                        help="Guard the SAT constraint groups with activation literals and switch off the given "
+                             "ones; on UNSAT (Z3 satisfaction) the groups in the core are reported")
+    parser.add_argument("--smt-strategy", type=str, choices=["binary", "omt", "assumptions", "linear", "compare"],
+                        default="binary",
+                        help="SMT optimization strategy with Z3: binary=bound search, omt=z3.Optimize, "
+                             "assumptions=bound search under assumption literals, linear=SAT-UNSAT search, "
+                             "compare=run all (with --all) and report the best per instance size")
+    parser.add_argument("--export-smt2", action="store_true",
+                        help="Write every SMT formula (with the current bound) in SMT-LIB2 under artifacts/SMT")
+    parser.add_argument("--warm-start", action="store_true",
+                        help="Seed the Z3 SMT solver with the greedy schedule: phase hints and first bound "
+                             "(binary), upper bound and soft constraints (omt)")
+    parser.add_argument("--smt-core", action="store_true",
+                        help="Track every Z3 SMT assertion and report the UNSAT cores as constraint descriptions")
+    parser.add_argument("--period-order", action="store_true",
+                        help="With --sb, also order the SMT periods by their lowest team in the first week")
+    parser.add_argument("--chunk", type=float, metavar="SECONDS",
+                        help="Split the SMT optimization budget into chunks, keeping the best model and "
+                             "tightening the bound at the end of each one")
+    parser.add_argument("--z3-config", type=str, metavar="FILE",
+                        help="JSON dictionary of Z3 parameters for the SMT solver (random_seed, restart.*, "
+                             "smt.arith.*, smt.relevancy, ...), overridden by --z3-param")
+    parser.add_argument("--lazy-period", action="store_true",
+                        help="Leave the SMT period limit out of the model and add it on demand (CEGAR) for the "
+                             "(team, period) pairs violated by the candidate models")
+    parser.add_argument("--smt-threads", type=int, metavar="N",
+                        help="Run the Z3 SMT solver in parallel mode with N threads (capped by the cores)")
+    parser.add_argument("--relaxation", action="store_true",
+                        help="Probe the real relaxation of the SMT objective for a lower bound before the "
+                             "integer search")
+    parser.add_argument("--reuse-formula", action="store_true",
+                        help="Build every SMT formula once per instance shape and configuration, and reuse its "
+                             "assertions in the following runs")
+    parser.add_argument("--tactic", type=str,
+                        help='Z3 tactic pipeline for the SMT solver, e.g. "simplify; solve-eqs; smt(arith.solver=2)"')
+    parser.add_argument("--z3-param", nargs="*", metavar="KEY=VALUE", dest="z3_params",
+                        help="Z3 parameters for the SMT solver, e.g. smt.phase_selection=5")
+    parser.add_argument("--mip-threads", type=int, metavar="N",
+                        help="Number of threads of the MIP solver (default: the CPU quota of the container)")
+    parser.add_argument("--mip-gap", type=float, metavar="GAP",
+                        help="Relative MIP gap at which the MIP solver stops (e.g. 0.01)")
+    parser.add_argument("--mip-profile", type=str, choices=MIP_PROFILES,
+                        help="MIP parameter profile, mapped to the settings of each backend: balanced=defaults, "
+                             "feasibility=find good solutions early, proof=move the bound")
+    parser.add_argument("--export-lp", action="store_true",
+                        help="Write every MIP model in LP and MPS format under artifacts/MIP")
+    parser.add_argument("--mip-relax-fix", type=int, metavar="WEEKS",
+                        help="MIP relax-and-fix heuristic: make WEEKS weeks integer at a time, with the following "
+                             "ones relaxed, and fix them once solved")
+    parser.add_argument("--mip-fix-optimize", type=int, metavar="SECONDS",
+                        help="Keep SECONDS of the MIP budget (--opt) for a fix-and-optimize phase improving the "
+                             "incumbent two weeks at a time")
+    parser.add_argument("--mip-disaggregated", action="store_true",
+                        help="Express the MIP period limit on per-team period indicators instead of the "
+                             "aggregated sums over the matches")
+    parser.add_argument("--mip-pool", type=int, metavar="N",
+                        help="Keep up to N solutions in the MIP solution pool (Gurobi, CPLEX) and store their "
+                             "count and objectives")
+    parser.add_argument("--mip-lazy-period", action="store_true",
+                        help="Leave the MIP period limit out of the model and add it for the violated "
+                             "(team, period) pairs in a cutting loop")
+    parser.add_argument("--mip-warm-start", action="store_true",
+                        help="Give the greedy heuristic schedule to the MIP solver as its MIP start")
+    parser.add_argument("--mip-lp-bound", action="store_true",
+                        help="Solve the root LP relaxation of the MIP model first and store its bound")
+    parser.add_argument("--mip-bound", type=int, metavar="SECONDS",
+                        help="Run a truncated MIP of SECONDS (0: its LP relaxation) as a bound oracle and use its "
+                             "bound as the initial lower bound of the CP / SAT / SMT optimization")
+    parser.add_argument("--problem", type=str, choices=list(CHECKER_PROBLEMS), default="sts",
+                        help="With --check, the problem definition the results are checked against "
+                             "(sts_total: total imbalance reported as total_imbalance)")
+    parser.add_argument("--variant", type=str, metavar="NAME",
+                        help="With --check, also check the per-team limits of the variant NAME of "
+                             "source/checker/variants.json")
+    parser.add_argument("-o", "--output", metavar="PATH",
+                        help="With --report, file the report is written to (default: standard output; "
+                             "profile: output directory, default artifacts/analysis; hardness: CSV written besides "
+                             "the table); with --merge, the merged results directory; with --archive, the tarball")
+    parser.add_argument("--run-id", metavar="ID",
+                        help="ID of the run, tagging its versioned result directory runs/<timestamp>_<ID> "
+                             "(default: RUN_ID or a random one)")
+    parser.add_argument("--sqlite", nargs="?", const=DEFAULT_DATABASE, metavar="PATH",
+                        help="Also store every result written in the SQLite database PATH (default: runs/results.db); "
+                             "with --sqlite-import, the database imported into")
+    parser.add_argument("--push-url", metavar="URL",
+                        help="POST every result file written (JSON, with the approach, instance, run ID and host) "
+                             "to URL, with the bearer token PUSH_TOKEN if set")
+    parser.add_argument("--solver-log", type=str, choices=LOG_VERBOSITY,
+                        help="Capture a log of every run in logs/<approach>/<n>_<run_id>.log, linked from the result "
+                             "metadata: errors=stderr of the solver subprocesses, normal=also the console output, "
+                             "full=also the stdout of the subprocesses")
+    parser.add_argument("--log-max-bytes", type=int, metavar="N",
+                        help="With --solver-log, characters kept of every subprocess output, half from its head and "
+                             "half from its tail (default: 100000)")
+    parser.add_argument("--slowdown", type=float, default=DEFAULT_SLOWDOWN, metavar="PCT",
+                        help="Time increase of a result proven optimal by both runs reported as a regression, after "
+                             "--all, --single and with --regressions (default: 20)")
+    parser.add_argument("--merge-policy", type=str, choices=MERGE_POLICIES, default="objective",
+                        help="With --merge, result kept when directories disagree: objective=lowest objective, "
+                             "then optimal, then fastest; proof=optimal, then fastest (fastest proof)")
+    parser.add_argument("--against", metavar="DIR",
+                        help="With --check, fail if any result of the golden results directory DIR regressed in res/")
+    parser.add_argument("--fix", action="store_true",
+                        help="With --check, first normalize benign format issues of the result files in place")
+    parser.add_argument("--summary-json", metavar="PATH",
+                        help="With --check, write a JSON summary of the verdicts to PATH ('-' for the standard "
+                             "output, replacing the table)")
+    parser.add_argument("--trials", type=int, default=5,
+                        help="Number of random instances checked by --crosscheck")
+    parser.add_argument("--objective-encoding", type=str, choices=["pb", "totalizer"], default="pb",
+                        help="Encoding of the SAT objective bound: pb=cardinality constraints, "
+                             "totalizer=sorted outputs checked as assumptions")
+    parser.add_argument("--breakid", action="store_true",
+                        help="Extend the exported CNF with the symmetry breaking clauses found by BreakID "
+                             "(Glucose only)")
 
     args = parser.parse_args()
+    if args.restart and args.solver in SAT_RESTARTS and args.restart not in SAT_RESTARTS[args.solver]:
+        parser.error(f"--restart {args.restart} is not supported by {args.solver} "
+                     f"(available: {', '.join(SAT_RESTARTS[args.solver])})")
+    if args.run_id:
+        set_run_id(args.run_id)
+    if args.sqlite and not args.sqlite_import:
+        set_database(args.sqlite)
+    if args.push_url:
+        set_push_url(args.push_url)
+    if args.solver_log:
+        configure_logs(args.solver_log, args.log_max_bytes)
+
+    sat_options = {
+        "seed": args.seed,
+        "restart": args.restart,
+        "var_decay": args.var_decay,
+        "reuse_learnts": args.reuse_learnts,
+        "cube_depth": args.cubes,
+        "workers": args.workers,
+        "hybrid": args.hybrid,
+        "fixed_teams": args.fixed_teams,
+        "objective_encoding": args.objective_encoding,
+        "breakid": args.breakid,
+        "disable": args.disable,
+        "proof": args.proof,
+        "lexicographic": args.lexicographic,
+        "clause_budget": args.clause_budget,
+        "mip_bound": args.mip_bound
+    }
+    cp_options = {"mip_bound": args.mip_bound}
+
+    mip_options = {"threads": args.mip_threads, "mip_gap": args.mip_gap, "lazy_period": args.mip_lazy_period,
+                   "profile": args.mip_profile, "export_lp": args.export_lp,
+                   "relax_fix": args.mip_relax_fix, "disaggregated": args.mip_disaggregated,
+                   "pool": args.mip_pool, "fix_optimize": args.mip_fix_optimize,
+                   "warm_start": args.mip_warm_start, "lp_bound": args.mip_lp_bound}
+
+    # smt_<variant> runs the SMT approach with an alternative encoding
+    model_name = args.model
+    smt_options = {"variant": "lia", "strategy": args.smt_strategy, "export_smt2": args.export_smt2,
+                   "tactic": args.tactic, "z3_params": args.z3_params, "warm_start": args.warm_start,
+                   "track": args.smt_core, "period_order": args.period_order,
+                   "chunk": args.chunk, "lazy_period": args.lazy_period,
+                   "threads": args.smt_threads, "relaxation": args.relaxation,
+                   "reuse_formula": args.reuse_formula, "mip_bound": args.mip_bound,
+                   "z3_config": load_z3_config(args.z3_config) if args.z3_config else None}
+    if model_name and model_name.startswith("smt_"):
+        model_name, smt_options["variant"] = "smt", model_name[len("smt_"):]
+    elif model_name and model_name.startswith("mip_"):
+        model_name, mip_options["formulation"] = "mip", model_name[len("mip_"):]
 
     if args.all:
-        run_all_models(selected_model=args.model)
+        run_all_models(selected_model=model_name,
+                       model_options={"cp": {"options": cp_options}, "sat": {"options": sat_options},
+                                      "smt": {"options": smt_options},
+                                      "mip": {"options": mip_options}})
+        report_batch(slowdown=args.slowdown)
+
+    elif args.report == "table":
+        from source.report.table import run_table
+        run_table()
+
+    elif args.report == "latex":
+        from source.report.latex import run_latex
+        run_latex(output=args.output)
+
+    elif args.report == "csv":
+        from source.report.csv_export import run_csv
+        run_csv(output=args.output)
+
+    elif args.report == "md":
+        from source.report.markdown import run_markdown
+        run_markdown(output=args.output)
+
+    elif args.report == "profile":
+        from source.report.profiles import run_profiles
+        run_profiles(output=args.output)
+
+    elif args.report == "vbs":
+        from source.report.vbs import run_vbs
+        run_vbs()
+
+    elif args.report == "score":
+        from source.report.scoring import run_scores
+        run_scores()
+
+    elif args.report == "hardness":
+        from source.report.hardness import run_hardness
+        run_hardness(output=args.output)
+
+    elif args.runs:
+        from source.results.runs import print_runs
+        print_runs()
+
+    elif args.regressions is not None:
+        from source.results.regression import print_regressions
+        print_regressions(run_id=args.regressions or None, slowdown=args.slowdown)
+
+    elif args.archive is not None:
+        from source.results.archive import run_archive
+        try:
+            run_archive(run_id=args.archive or None, output=args.output)
+        except ValueError as e:
+            parser.error(str(e))
+
+    elif args.sqlite_import:
+        from source.results.sqlite_store import import_directories
+        import_directories(args.sqlite_import, path=args.sqlite or DEFAULT_DATABASE)
+
+    elif args.merge:
+        from source.results.merge import run_merge
+        run_merge(args.merge, args.output or "merged", policy=args.merge_policy)
+
+    elif args.wilcoxon:
+        from source.report.significance import run_significance
+        try:
+            run_significance(*args.wilcoxon)
+        except ValueError as e:
+            parser.error(str(e))
+
+    elif args.check is not None:
+        from source.checker.checker import run_check
+        if not run_check(args.check, summary_path=args.summary_json, problem=args.problem,
+                         workers=args.workers, fix=args.fix, against=args.against,
+                         variant=args.variant):
+            sys.exit(1)
+
+    elif args.reference:
+        from source.checker.reference import run_reference
+        if not run_reference(options={"cp": cp_options, "sat": sat_options, "smt": smt_options,
+                                      "mip": mip_options}):
+            sys.exit(1)
+
+    elif args.crosscheck:
+        from source.SAT.crosscheck import run_crosscheck
+        if not run_crosscheck(trials=args.trials, seed=args.seed, options=sat_options):
+            sys.exit(1)
 
     elif args.single:
-        if args.model == "cp":
+        if model_name == "cp":
             cp_model.run_single_instance(
                 n=args.teams,
                 solver=args.solver,
                 use_sb=args.sb,
                 use_heuristics=args.hf,
-                use_optimization=args.opt
+                use_optimization=args.opt,
+                options=cp_options
             )
-        elif args.model == "sat":
+        elif model_name == "sat":
             sat_model.run_single_instance(
                 n=args.teams,
                 solver=args.solver,
                 use_sb=args.sb,
-                use_optimization=args.opt
+                use_optimization=args.opt,
+                options=sat_options
             )
-        elif args.model == "mip":
+        elif model_name == "mip":
             mip_model.run_single_instance(
                 n=args.teams,
                 solver=args.solver,
                 use_sb=args.sb,
-                use_optimization=args.opt
+                use_optimization=args.opt,
+                options=mip_options
             )
-        elif args.model == "smt":
+        elif model_name == "smt":
             smt_model.run_single_instance(
                 n=args.teams,
                 solver=args.solver,
                 use_sb=args.sb,
-                use_optimization=args.opt
+                use_optimization=args.opt,
+                options=smt_options
             )
         else:
             print(f"Model error")
+        report_batch(slowdown=args.slowdown)
 
 
 if __name__ == "__main__":
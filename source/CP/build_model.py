@@ -1,7 +1,7 @@
 from minizinc import Model
 
 
-def build_model(path, use_sb=False, heuristic=1, use_optimization=False):
+def build_model(path, use_sb=False, heuristic=1, use_optimization=False, lower_bound=1):
     """
     Builds dynamically a MiniZinc model from the given path with specified options.
 
@@ -14,6 +14,7 @@ def build_model(path, use_sb=False, heuristic=1, use_optimization=False):
             3 -> dom/wdeg + random value + restarts (Luby L=250)
             4 -> dom/wdeg + random value + restarts + LNS (85% fixed)
         use_optimization: Boolean indicating if optimization is used.
+        lower_bound: Initial lower bound on the max imbalance (e.g. from the MIP bound oracle).
     Returns:
         A tuple containing:
             - model: A MiniZinc Model object.
@@ -23,6 +24,9 @@ def build_model(path, use_sb=False, heuristic=1, use_optimization=False):
     model = Model()
     model.add_file(path)
 
+    if use_optimization and lower_bound > 1:
+        model.add_string(f"constraint max_imbalance >= {lower_bound};")
+
     # Objective function
     if use_optimization:
         solve_prefix = "solve minimize max_imbalance;"
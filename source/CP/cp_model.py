@@ -2,6 +2,9 @@ from source.CP.instance_solver import solve_instance
 from source.CP.build_model import build_model
 from minizinc import Solver
 from source.CP import cp_utils as utils
+from source.common.bounds import initial_lower_bound
+from source.common.metadata import run_metadata
+from source.results.logs import open_log, close_log
 import os
 import os.path as pt
 
@@ -11,7 +14,7 @@ DEFAULT_CP_MODEL_FILE = pt.join(current_dir, 'source/CP/model/cp_model.mzn')
 DEFAULT_CP_OUTPUT_DIR = pt.join(current_dir, 'res/CP')
 
 
-def cp_solver(n_instances, solver, use_sb=False, hf=False, use_optimization=False):
+def cp_solver(n_instances, solver, use_sb=False, hf=False, use_optimization=False, options=None, trace=None):
     """
     Solves the CP model using the specified solver and parameters.
     Params:
@@ -20,6 +23,8 @@ def cp_solver(n_instances, solver, use_sb=False, hf=False, use_optimization=Fals
         use_sb: Whether to use symmetry breaking
         hf: Heuristic function to use (1-4)
         use_optimization: Whether to use optimization techniques
+        options: Dictionary of options ("mip_bound" for the MIP bound oracle)
+        trace: List receiving the [time, objective] pairs of the intermediate solutions
     Returns:
         result: The result of the solver
     """
@@ -27,13 +32,14 @@ def cp_solver(n_instances, solver, use_sb=False, hf=False, use_optimization=Fals
     solver_instance = Solver.lookup(solver)
     path = DEFAULT_CP_MODEL_FILE
 
-    model, extra_params = build_model(path, use_sb, hf, use_optimization)
+    lower_bound = initial_lower_bound(n_instances, options) if use_optimization else 1
+    model, extra_params = build_model(path, use_sb, hf, use_optimization, lower_bound)
 
-    result = solve_instance(n_instances, solver_instance, model, extra_params)
+    result = solve_instance(n_instances, solver_instance, model, extra_params, trace)
     return result
 
 
-def run_model(results_dict, n, solver, sb, hf, opt):
+def run_model(results_dict, n, solver, sb, hf, opt, options=None):
     """
     Runs the CP model with the given parameters and updates the results dictionary.
     
@@ -44,6 +50,7 @@ def run_model(results_dict, n, solver, sb, hf, opt):
         sb: Whether to use symmetry breaking
         hf: Whether to use heuristics
         opt: Whether to use optimization techniques
+        options: Dictionary of options ("mip_bound" for the MIP bound oracle)
     """
 
     key = utils.make_key(solver, sb, hf, opt)
@@ -55,6 +62,9 @@ def run_model(results_dict, n, solver, sb, hf, opt):
         4: "dom/wdeg + indomain_min + luby + lns"
     }
 
+    trace = []
+    log = open_log("CP", n, key)
+
     try:
         print(
             f"\nRunning CP instance with"
@@ -67,7 +77,8 @@ def run_model(results_dict, n, solver, sb, hf, opt):
 
         result = cp_solver(n_instances=n, solver=solver,
                            use_sb=sb, hf=hf,
-                           use_optimization=opt)
+                           use_optimization=opt, options=options,
+                           trace=trace if opt else None)
 
         time, optimal, solution, obj = utils.process_result(result, opt)
 
@@ -77,7 +88,8 @@ def run_model(results_dict, n, solver, sb, hf, opt):
             "sol": solution,
             "time": time,
             "optimal": optimal,
-            "obj": obj
+            "obj": obj,
+            "trace": trace
         }
 
     except Exception as e:
@@ -88,11 +100,14 @@ def run_model(results_dict, n, solver, sb, hf, opt):
             "optimal": False,
             "obj": None
         }
+    close_log()
+    results_dict[key]["metadata"] = run_metadata("CP", solver, utils.solver_version(solver),
+                                                 variant=heuristic_map.get(hf, f"h{hf}"), log=log)
 
     return results_dict
 
 
-def run_single_instance(n, solver, use_sb=False, use_heuristics=False, use_optimization=False):
+def run_single_instance(n, solver, use_sb=False, use_heuristics=False, use_optimization=False, options=None):
     """
     Runs a single instance of the CP model with the given parameters.
 
@@ -102,6 +117,7 @@ def run_single_instance(n, solver, use_sb=False, use_heuristics=False, use_optim
         use_sb: Whether to use symmetry breaking
         use_heuristics: Whether to use heuristics
         use_optimization: Whether to use optimization techniques
+        options: Dictionary of options ("mip_bound" for the MIP bound oracle)
     """
 
     if solver is None:
@@ -112,14 +128,17 @@ def run_single_instance(n, solver, use_sb=False, use_heuristics=False, use_optim
 
     results_dict = {}
 
-    results_dict = run_model(results_dict, n, solver, use_sb, use_heuristics, use_optimization)
+    results_dict = run_model(results_dict, n, solver, use_sb, use_heuristics, use_optimization, options)
 
     utils.write_solution(output_dir, n, results_dict)
 
 
-def run_all():
+def run_all(options=None):
     """
     Runs all configurations for the CP model.
+
+    Params:
+        options: Dictionary of options ("mip_bound" for the MIP bound oracle)
     """
 
     solvers = ["gecode", "chuffed"]
@@ -134,6 +153,6 @@ def run_all():
             for sb in [False, True]:
                 for hf in [1, 2, 3, 4]:  # Heuristic functions
                     for opt in [False, True]:
-                        results_dict = run_model(results_dict, n, solver, sb, hf, opt)
+                        results_dict = run_model(results_dict, n, solver, sb, hf, opt, options)
 
         utils.write_solution(output_dir, n, results_dict)
@@ -1,7 +1,6 @@
-import os
-import json
 import math
 from minizinc import Status
+from source.results.writer import write_solution
 
 
 def print_solution(time, optimal, solution, obj):
@@ -155,26 +154,12 @@ def process_result(result, use_optimization):
     return time_val, is_optimal, solution, obj
 
 
-def write_solution(output_dir, n, results_dict):
+def solver_version(solver):
     """
-    Writes the results to a JSON file.
-    Params:
-        output_dir: The directory where the results will be saved.
-        n: An identifier for the results (e.g., number of teams).
-        results_dict: A dictionary containing the results to be written.
+    Returns the version of a MiniZinc solver, None if it cannot be looked up.
     """
-
-    out_path = os.path.join(output_dir, f"{n}.json")
-    with open(out_path, 'w') as f:
-        f.write('{\n')
-        for i, (key, val) in enumerate(results_dict.items()):
-            f.write(f'  "{key}": {{\n')
-
-            sol_str = json.dumps(val["sol"], separators=(',', ':'))
-            f.write(f'    "time": {val["time"]},\n')
-            f.write(f'    "optimal": {"true" if val["optimal"] else "false"},\n')
-            f.write(f'    "obj": {json.dumps(val["obj"])},\n')
-            f.write(f'    "sol": {sol_str}\n')
-
-            f.write('  }' + (',' if i < len(results_dict) - 1 else '') + '\n')
-        f.write('}\n')
+    try:
+        from minizinc import Solver
+        return Solver.lookup(solver).version
+    except Exception:
+        return None
@@ -1,8 +1,10 @@
-from minizinc import Instance
+from minizinc import Instance, Result, Status
+import asyncio
 import datetime
+import time
 
 
-def solve_instance(num_teams, solver, model, extra_params):
+def solve_instance(num_teams, solver, model, extra_params, trace=None):
     """
     Solves a MiniZinc instance with the given parameters.
 
@@ -11,6 +13,8 @@ def solve_instance(num_teams, solver, model, extra_params):
         solver: The name of the solver to use.
         model: The MiniZinc model to solve.
         extra_params: A dictionary of additional parameters for the instance.
+        trace: If given, the list to which the [time, objective] pairs of the intermediate
+               solutions are appended (time in seconds since the start of the solve).
     Returns:
         A MiniZinc result object containing the solution.
     """
@@ -27,9 +31,36 @@ def solve_instance(num_teams, solver, model, extra_params):
         if value:
             name_parts.append(str(key))
 
+    if trace is not None:
+        return asyncio.run(solve_with_trace(instance, extra_params, trace))
+
     result = instance.solve(
             timeout=datetime.timedelta(minutes=5),
             free_search=not extra_params.get("hf", False),
         )
 
     return result
+
+
+async def solve_with_trace(instance, extra_params, trace):
+    """
+    Solves the instance reading its intermediate solutions, and records the objective of each
+    one in trace. The result is assembled as by Instance.solve (last solution, final status).
+    """
+    start_time = time.time()
+    status, solution, statistics = Status.UNKNOWN, None, {}
+
+    async for result in instance.solutions(
+            timeout=datetime.timedelta(minutes=5),
+            free_search=not extra_params.get("hf", False),
+            intermediate_solutions=True,
+    ):
+        status = result.status
+        statistics.update(result.statistics)
+        if result.solution is not None:
+            solution = result.solution
+            objective = getattr(solution, "objective", None)
+            if objective is not None:
+                trace.append([round(time.time() - start_time, 3), objective])
+
+    return Result(status, solution, statistics)
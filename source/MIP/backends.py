@@ -0,0 +1,143 @@
+import os
+import tempfile
+
+# Environment variables of a Gurobi Web License Service (WLS) license, as in the gurobi.lic file
+WLS_KEYS = ["WLSACCESSID", "WLSSECRET", "LICENSEID"]
+
+# Named parameter profiles (--mip-profile), mapped by every backend to its own settings
+PROFILES = ["balanced", "feasibility", "proof"]
+
+
+class MipBackend:
+    """
+    A MIP solver run by AMPL through its amplpy module: maps the generic parameters of a run
+    (time_limit, threads, mip_gap, best_bound, write_model, pool_stub, pool_limit, iis) to the
+    option names of the solver; the backends without a solution pool or IIS computation have no
+    pool_* or iis options.
+
+    Params:
+        name: Solver name known by AMPL (and used in the result keys)
+        module: amplpy module shipping the solver
+        params: Option name of the solver for each generic parameter
+        profiles: Solver settings of each parameter profile
+    """
+
+    def __init__(self, name, module, params, profiles):
+        self.name = name
+        self.module = module
+        self.params = params
+        self.profiles = profiles
+
+    def solver_options(self, params):
+        """
+        Returns the option string of the solver (the value of "<name>_options") for the
+        generic parameters that are set, followed by the settings of params["profile"].
+        """
+        profile = params.get("profile")
+        if profile is not None and profile not in self.profiles:
+            raise ValueError(f"Unknown MIP profile '{profile}' (available: {', '.join(self.profiles)})")
+
+        options = [f"{self.params[key]}={int(value) if isinstance(value, bool) else value}"
+                   for key, value in params.items() if value is not None and key in self.params]
+        if profile is not None:
+            options.append(self.profiles[profile])
+        return " ".join(option for option in options if option)
+
+    def supports(self, key):
+        """
+        Returns whether the solver has an option for the generic parameter key.
+        """
+        return key in self.params
+
+    def prepare(self):
+        """
+        Sets up the environment of the solver before a run (e.g. its license).
+        """
+        pass
+
+    def configure(self, ampl, params):
+        """
+        Selects the solver on an AMPL object and sets its options.
+
+        Returns:
+            dict: The parameters of the run, with the backend name, stored in the results
+        """
+        self.prepare()
+        ampl.setOption("solver", self.name)
+        ampl.setOption(f"{self.name}_options", self.solver_options(params))
+        return {"backend": self.name, **{key: value for key, value in params.items() if value is not None}}
+
+
+class GurobiBackend(MipBackend):
+    """
+    Gurobi, licensed either by the AMPL license or by a WLS license given through the
+    GRB_WLSACCESSID, GRB_WLSSECRET and GRB_LICENSEID environment variables (Docker).
+    """
+
+    def __init__(self):
+        super().__init__("gurobi", "gurobi", {"time_limit": "TimeLimit", "threads": "Threads",
+                                              "mip_gap": "MIPGap", "best_bound": "bestbound",
+                                              "write_model": "writeprob", "pool_stub": "sol:stub",
+                                              "pool_limit": "sol:poollimit", "iis": "iisfind"},
+                         {"balanced": "", "feasibility": "MIPFocus=1 Heuristics=0.2",
+                          "proof": "MIPFocus=3 Cuts=2"})
+
+    def prepare(self):
+        license_file = write_wls_license()
+        if license_file:
+            os.environ["GRB_LICENSE_FILE"] = license_file
+
+
+def default_threads():
+    """
+    Default thread count of the MIP solvers: the CPU quota of the container (cgroup v2
+    cpu.max or v1 cfs quota) when one is set, since the solvers otherwise size their thread
+    pool on the cores of the host, else the available cores.
+    """
+    cpus = os.cpu_count() or 1
+    try:
+        with open("/sys/fs/cgroup/cpu.max") as f:
+            quota, period = f.read().split()[:2]
+        if quota != "max":
+            return max(1, min(cpus, int(quota) // int(period)))
+    except (OSError, ValueError):
+        pass
+    try:
+        with open("/sys/fs/cgroup/cpu/cpu.cfs_quota_us") as f:
+            quota = int(f.read())
+        with open("/sys/fs/cgroup/cpu/cpu.cfs_period_us") as f:
+            period = int(f.read())
+        if quota > 0:
+            return max(1, min(cpus, quota // period))
+    except (OSError, ValueError):
+        pass
+    return cpus
+
+
+def write_wls_license():
+    """
+    Writes the gurobi.lic file of a WLS license from the GRB_<key> environment variables.
+
+    Returns:
+        str: Path of the license file, None if the variables are not all set
+    """
+    values = {key: os.getenv(f"GRB_{key}") for key in WLS_KEYS}
+    if not all(values.values()):
+        return None
+    path = os.path.join(tempfile.gettempdir(), "gurobi.lic")
+    with open(path, "w") as f:
+        f.write("".join(f"{key}={value}\n" for key, value in values.items()))
+    return path
+
+
+BACKENDS = {
+    "gurobi": GurobiBackend(),
+    "cplex": MipBackend("cplex", "cplex", {"time_limit": "timelimit", "threads": "threads", "mip_gap": "mipgap",
+                                           "best_bound": "bestbound", "write_model": "writeprob",
+                                           "pool_stub": "sol:stub", "pool_limit": "sol:poollimit", "iis": "iisfind"},
+                        {"balanced": "", "feasibility": "mipemphasis=1", "proof": "mipemphasis=3 mipcuts=2"}),
+    # Open-source, no license needed
+    "highs": MipBackend("highs", "highs", {"time_limit": "lim:time", "threads": "tech:threads", "mip_gap": "mip:gap",
+                                           "best_bound": "mip:bestbound", "write_model": "tech:writemodel"},
+                        {"balanced": "", "feasibility": "mip:heuristiceffort=0.3", "proof": "mip:heuristiceffort=0"}),
+}
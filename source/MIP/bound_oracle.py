@@ -0,0 +1,56 @@
+from amplpy import AMPL
+import math
+import time
+from source.MIP.backends import BACKENDS
+from source.MIP.mip_model import FORMULATIONS
+from source.MIP.relaxation import lp_relaxation_bound
+
+
+def mip_lower_bound(n, solver="highs", time_limit=10, lp_only=False):
+    """
+    Uses the MIP model as a bound oracle on the max imbalance: either its root LP relaxation
+    or a MIP optimization truncated at time_limit, of which only the best bound is kept.
+    The bound is rounded up to the next odd integer (every team plays an odd number of games).
+
+    Params:
+        n: Number of teams (instances)
+        solver: The MIP backend (HiGHS by default, needing no license)
+        time_limit: Time limit of the truncated MIP, in seconds
+        lp_only: Whether to solve only the LP relaxation
+
+    Returns:
+        dict: {"bound", "value", "source", "time"}; bound is 1 (the trivial bound) if no
+              better one was proven
+    """
+    start_time = time.time()
+    ampl = AMPL()
+    ampl.setOption("solver_msg", 0)
+    backend = BACKENDS[solver]
+    backend.configure(ampl, {"time_limit": time_limit, "best_bound": True})
+
+    model_file, _ = FORMULATIONS["standard"]
+    ampl.read(model_file)
+    ampl.getParameter('n').set(n)
+    ampl.getParameter('use_opt').set(1)
+
+    if lp_only:
+        value = lp_relaxation_bound(ampl, n)["value"]
+    else:
+        ampl.solve()
+        try:
+            value = float(ampl.get_value("MaxImbalanceObj.bestbound"))
+        except Exception:
+            value = None
+        if value is not None and (math.isnan(value) or math.isinf(value)):
+            value = None
+
+    bound = 1
+    if value is not None:
+        bound = math.ceil(value - 1e-6)
+        if (n - 1) % 2 and bound % 2 == 0:
+            bound += 1
+        bound = max(1, bound)
+    ampl.close()
+
+    return {"bound": bound, "value": value, "source": "lp" if lp_only else f"{solver}_{time_limit}s",
+            "time": round(time.time() - start_time, 3)}
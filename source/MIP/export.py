@@ -0,0 +1,24 @@
+import os
+
+DEFAULT_EXPORT_DIR = os.path.join(os.getcwd(), "artifacts/MIP")
+
+
+def export_stub(n, formulation, use_sb, use_optimization):
+    """
+    Returns the path, without extension, of the exported model of a configuration under
+    artifacts/MIP: <n>_<formulation>_<sb>_<opt>.
+    """
+    os.makedirs(DEFAULT_EXPORT_DIR, exist_ok=True)
+    name = f"{n}_{formulation}_{'sb' if use_sb else 'nosb'}_{'opt' if use_optimization else 'noopt'}"
+    return os.path.join(DEFAULT_EXPORT_DIR, name)
+
+
+def export_mps(ampl, stub):
+    """
+    Writes the instantiated model in MPS format (AMPL "write m<stub>" command).
+
+    Returns:
+        str: The path of the written file
+    """
+    ampl.eval(f'write "m{stub}";')
+    return f"{stub}.mps"
@@ -0,0 +1,25 @@
+def iis_constraints(ampl):
+    """
+    Returns the names of the constraints in the irreducible infeasible subsystem found by the
+    solver (iis suffix of the constraints, set with the iisfind option).
+    """
+    names = ampl.getData("{i in 1.._ncons: _con[i].iis <> 'non'} _conname[i]").toList()
+    return sorted(str(name[-1]) if isinstance(name, tuple) else str(name) for name in names)
+
+
+def explain_infeasibility(ampl, backend, params):
+    """
+    Solves an infeasible model again with the IIS computation of the solver and prints the
+    constraints of the subsystem; the constraint names carry their indices (e.g.
+    MaxTwoPerPeriod[3,2]), which point to the modeling error.
+
+    Returns:
+        list: The names of the IIS constraints, stored in the solver parameters
+    """
+    backend.configure(ampl, {**params, "iis": True})
+    ampl.solve()
+    names = iis_constraints(ampl)
+    print(f"\nInfeasible MIP model, irreducible infeasible subsystem ({len(names)} constraints):")
+    for name in names:
+        print(f"  {name}")
+    return names
@@ -0,0 +1,62 @@
+import math
+import time
+
+
+def active_periods(ampl, period_var='A'):
+    """
+    Returns the (i, k, w, p) indices of the match / period assignments of the last solve
+    (A in the standard formulation, X in the slot one).
+    """
+    df = ampl.getVariable(period_var).getValues().to_pandas()
+    active = []
+    if not df.empty:
+        val_col = df.columns[-1]
+        idx_cols = df.columns[:-1]
+        for _, row in df.iterrows():
+            if float(row[val_col]) > 0.5:
+                active.append(tuple(int(row[c]) for c in idx_cols))
+    return active
+
+
+def period_violations(ampl, period_var='A'):
+    """
+    Returns the (team, period) pairs of the last solution where the team plays more than
+    twice in the period.
+    """
+    counts = {}
+    for i, k, w, p in active_periods(ampl, period_var):
+        for t in (i, k):
+            counts[t, p] = counts.get((t, p), 0) + 1
+    return sorted(pair for pair, count in counts.items() if count > 2)
+
+
+def solve_lazy(ampl, backend, params, time_limit, period_var='A'):
+    """
+    Cutting loop on the period limit (MaxTwoPerPeriod), left out of the model with lazy_period:
+    after every solve the solution is checked in Python, the violated (team, period) pairs are
+    added to PERIOD_CUTS and the model is solved again in the remaining time. AMPL does not
+    expose the lazy-constraint callbacks of the solvers, so the loop is the same for every backend.
+
+    Returns:
+        dict: {"refinements", "cuts", "violated"}, stored in the solver parameters; "violated"
+              counts the pairs still violated by the last solution when the time runs out
+    """
+    deadline = time.time() + time_limit
+    cuts = ampl.getSet('PERIOD_CUTS')
+    added = []
+    refinements = 0
+
+    while True:
+        ampl.solve()
+
+        violated = period_violations(ampl, period_var)
+        remaining = deadline - time.time()
+        if not violated or remaining <= 0:
+            break
+
+        added.extend(violated)
+        cuts.setValues(added)
+        refinements += 1
+        backend.configure(ampl, {**params, "time_limit": max(1, math.floor(remaining))})
+
+    return {"refinements": refinements, "cuts": len(added), "violated": len(violated)}
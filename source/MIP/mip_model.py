@@ -1,7 +1,19 @@
 from amplpy import AMPL, modules
+import math
 import os
 import time
 from source.MIP import mip_utils as utils
+from source.MIP.backends import BACKENDS, default_threads
+from source.MIP.lazy import solve_lazy, active_periods
+from source.MIP.warm_start import set_mip_start
+from source.MIP.relaxation import lp_relaxation_bound
+from source.MIP.export import export_stub, export_mps
+from source.MIP.relax_fix import relax_and_fix, fix_and_optimize
+from source.MIP.pool import pool_stub, read_pool
+from source.MIP.iis import explain_infeasibility
+from source.common.metadata import package_version, run_metadata
+from source.results.logs import open_log, close_log
+from source.common.trace import record_improvement, pop_trace
 import traceback
 
 modules.activate(os.getenv("AMPL_LICENSE_UUID"))
@@ -10,8 +22,36 @@ current_dir = os.getcwd()
 DEFAULT_MIP_OUTPUT_DIR = os.path.join(current_dir, 'res/MIP')
 DEFAULT_MIP_MODEL_FILE = os.path.join(current_dir, 'source/MIP/model/mip_model.mod')
 
+# Weeks re-optimized at every fix-and-optimize step
+FIX_OPTIMIZE_WEEKS = 2
 
-def mip_solver(n, solver, use_sb=False, use_optimization=False):
+# MIP formulations (--model mip_<formulation>), with their period assignment variable
+FORMULATIONS = {
+    "standard": (DEFAULT_MIP_MODEL_FILE, 'A'),
+    "slot": (os.path.join(current_dir, 'source/MIP/model/mip_slot_model.mod'), 'X'),
+}
+
+
+# Hand-tuned rules of the automatic configuration (--model mip_auto), first match on the number
+# of teams: the slot formulation closes the small instances faster, the larger ones need the
+# standard formulation started from the heuristic with a feasibility-oriented profile
+AUTO_RULES = [
+    (8, {"formulation": "slot"}),
+    (12, {"formulation": "standard"}),
+    (None, {"formulation": "standard", "warm_start": True, "profile": "feasibility"}),
+]
+
+
+def auto_configuration(n):
+    """
+    Returns the options selected by AUTO_RULES for an instance size.
+    """
+    for max_teams, configuration in AUTO_RULES:
+        if max_teams is None or n <= max_teams:
+            return dict(configuration)
+
+
+def mip_solver(n, solver, use_sb=False, use_optimization=False, options=None):
     """
     Solves the MIP model using the specified parameters.
     Params:
@@ -19,34 +59,259 @@ def mip_solver(n, solver, use_sb=False, use_optimization=False):
         solver: The solver to use (e.g., "gurobi", "cplex")
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
+        options: Solver parameters of the run ("threads", "mip_gap", "profile"), "lazy_period" to add
+                 the period limit through a cutting loop, "warm_start" to start from the heuristic,
+                 "lp_bound" to record the root LP relaxation bound first, "export_lp" to write the
+                 model under artifacts/MIP, "relax_fix" (weeks per block) for the relax-and-fix
+                 heuristic, "fix_optimize" (seconds) for the fix-and-optimize improvement phase,
+                 "disaggregated" for the period limit on per-team indicators, "pool" (number of
+                 solutions) to read the solution pool and the MIP "formulation"
 
     Returns:
         ampl: The AMPL object after solving the model
+        solver_params: The parameters of the run, with the backend name
     """
+    options = options or {}
+    start_time = time.time()
     ampl = AMPL()
 
+    if solver not in BACKENDS:
+        raise ValueError(f"Unknown MIP solver '{solver}' (available: {', '.join(BACKENDS)})")
+    formulation = options.get("formulation") or "standard"
+    if formulation not in FORMULATIONS:
+        raise ValueError(f"Unknown MIP formulation '{formulation}' (available: {', '.join(FORMULATIONS)})")
+    model_file, period_var = FORMULATIONS[formulation]
+    if options.get("relax_fix") and options.get("lazy_period"):
+        raise ValueError("The relax-and-fix heuristic does not support the lazy period limit")
+
     ampl.setOption("solver_msg", 0)
-    ampl.setOption("solver", solver)
 
     time_limit = 300
-    if solver == "gurobi":
-        ampl.setOption("gurobi_options", f"TimeLimit={time_limit}")
-    elif solver == "cplex":
-        ampl.setOption("cplex_options", f"timelimit={time_limit}")
-
-    ampl.read(DEFAULT_MIP_MODEL_FILE)
+    fix_optimize = options.get("fix_optimize") if use_optimization else None
+    if fix_optimize:
+        time_limit -= fix_optimize
+    stub = export_stub(n, formulation, use_sb, use_optimization) if options.get("export_lp") else None
+    params = {"time_limit": time_limit, "threads": options.get("threads") or default_threads(),
+              "mip_gap": options.get("mip_gap"),
+              "best_bound": True if use_optimization else None, "profile": options.get("profile"),
+              "write_model": f"{stub}.lp" if stub else None}
+    pool = options.get("pool")
+    if pool:
+        if not BACKENDS[solver].supports("pool_stub"):
+            raise ValueError(f"The MIP solver '{solver}' has no solution pool")
+        params.update({"pool_stub": pool_stub(n, formulation, use_sb, use_optimization), "pool_limit": pool})
+    solver_params = BACKENDS[solver].configure(ampl, params)
+
+    ampl.read(model_file)
+    solver_params["formulation"] = formulation
 
     ampl.getParameter('n').set(n)
 
     ampl.getParameter('use_sb').set(1 if use_sb else 0)
     ampl.getParameter('use_opt').set(1 if use_optimization else 0)
+    ampl.getParameter('disaggregated').set(1 if options.get("disaggregated") else 0)
+
+    if stub:
+        solver_params["export"] = [export_mps(ampl, stub), params["write_model"]]
+
+    # The LP relaxation comes before the MIP start, which its solve would overwrite
+    if options.get("lp_bound"):
+        lp_relaxation = lp_relaxation_bound(ampl, n)
+        params["time_limit"] = max(1, math.floor(time_limit - lp_relaxation["time"]))
+        solver_params.update(BACKENDS[solver].configure(ampl, params))
+        solver_params["lp_relaxation"] = lp_relaxation
+
+    # The greedy heuristic of the MIP start (up to 10 s) is taken from the solver time limit
+    if options.get("warm_start"):
+        warm_start_begin = time.time()
+        solver_params["warm_start"] = set_mip_start(ampl, n, use_sb, formulation=formulation)
+        params["time_limit"] = max(1, math.floor(params["time_limit"] - (time.time() - warm_start_begin)))
+        solver_params.update(BACKENDS[solver].configure(ampl, params))
+        if use_optimization and solver_params["warm_start"]:
+            record_improvement(solver_params, start_time, solver_params["warm_start"]["imbalance"])
+
+    if options.get("relax_fix"):
+        solver_params["relax_fix"] = relax_and_fix(ampl, BACKENDS[solver], params, n, formulation,
+                                                   options["relax_fix"], params["time_limit"])
+    elif options.get("lazy_period"):
+        ampl.getParameter('lazy_period').set(1)
+        solver_params["lazy"] = solve_lazy(ampl, BACKENDS[solver], params, params["time_limit"],
+                                             period_var=period_var)
+    else:
+        ampl.solve()
+
+    # The incumbent of the main solve, if complete (AMPL reports no intermediate incumbents)
+    complete = len(active_periods(ampl, period_var)) == (n - 1) * (n // 2)
+    rejected = solver_params.get("lazy", {}).get("violated") or solver_params.get("relax_fix", {}).get("failed_block")
+    if use_optimization and complete and not rejected and \
+            str(ampl.get_value("solve_result")).lower() in ("solved", "limit"):
+        record_improvement(solver_params, start_time, round(ampl.getObjective("MaxImbalanceObj").value()))
+
+    # Improvement phase on a complete incumbent not proven optimal, in the budget kept for it
+    if fix_optimize and str(ampl.get_value("solve_result")).lower() != "solved" and complete:
+        solver_params["fix_optimize"] = fix_and_optimize(
+            ampl, BACKENDS[solver], params, n, formulation, FIX_OPTIMIZE_WEEKS, fix_optimize,
+            on_improvement=lambda obj: record_improvement(solver_params, start_time, obj))
+    if pool:
+        solver_params["pool"] = read_pool(ampl, params["pool_stub"], formulation)
+
+    # An infeasible model is a modeling error: the IIS points to the constraints involved
+    if str(ampl.get_value("solve_result")).lower() == "infeasible" and BACKENDS[solver].supports("iis"):
+        solver_params["iis"] = explain_infeasibility(ampl, BACKENDS[solver], params)
+    solver_params["size"] = utils.model_size(ampl)
+    solver_params["statistics"] = utils.solve_statistics(ampl)
+
+    return ampl, solver_params
+
+
+def result_key(solver, use_sb, use_optimization, options=None):
+    """
+    Returns the result key of a run: the formulation is appended unless it is the standard one,
+    then "disagg" for the disaggregated period limit and "rf" for the relax-and-fix heuristic.
+    """
+    formulation = (options or {}).get("formulation") or "standard"
+    key = utils.make_key(solver, use_sb, use_optimization)
+    if formulation != "standard":
+        key += f"_{formulation}"
+    if (options or {}).get("disaggregated"):
+        key += "_disagg"
+    if (options or {}).get("relax_fix"):
+        key += "_rf"
+    return key
 
-    ampl.solve()
 
-    return ampl
+def formulation_options(options=None):
+    """
+    Returns the options of every formulation to run: with formulation "compare" all of them
+    are run, each with its root LP bound, otherwise only the selected one.
+    """
+    if (options or {}).get("formulation") != "compare":
+        return [options]
+    return [{**options, "formulation": formulation, "lp_bound": True} for formulation in FORMULATIONS]
 
 
-def run_model(results_dict, n, solver, use_sb=False, use_optimization=False):
+def compare_formulations(n, results_dict, solver, use_sb, use_optimization, options):
+    """
+    Prints the LP bound, size and runtime of every formulation of a configuration.
+    """
+    print(f"\nn={n} {solver} sb={use_sb} opt={use_optimization}:")
+    for run_options in formulation_options(options):
+        entry = results_dict.get(result_key(solver, use_sb, use_optimization, run_options)) or {}
+        params = entry.get("params") or {}
+        size = params.get("size") or {}
+        lp_bound = (params.get("lp_relaxation") or {}).get("value")
+        print(f"  {run_options['formulation']:<10} LP bound = {lp_bound}, "
+              f"variables = {size.get('variables')}, constraints = {size.get('constraints')}, "
+              f"time = {entry.get('time')}, obj = {entry.get('obj')}, optimal = {entry.get('optimal')}")
+
+
+def read_assignments(ampl, n):
+    """
+    Reads the match (y), period (A) and orientation (H) assignments of the standard formulation.
+
+    Params:
+        ampl: The AMPL object after solving the model
+        n: Number of teams (instances)
+    Returns:
+        variables_dict: The active assignments, as expected by utils.parse_solution
+    """
+    y_var = ampl.getVariable('y')
+    A_var = ampl.getVariable('A')
+    H_var = ampl.getVariable('H')
+
+    y_dict = {}
+    A_dict = {}
+    H_dict = {}
+
+    try:
+        try:
+            y_values = y_var.getValues()
+            df_y = y_values.to_pandas()
+            if not df_y.empty:
+                val_col = df_y.columns[-1]
+                idx_cols = df_y.columns[:-1]
+                for _, row in df_y.iterrows():
+                    indices = tuple(int(row[c]) if str(row[c]).isdigit() else row[c] for c in idx_cols)
+                    val = float(row[val_col])
+                    if val > 0.5:
+                        y_dict[indices] = int(round(val))
+        except Exception:
+            TEAMS = range(1, n + 1)
+            WEEKS = range(1, n)
+            for i in TEAMS:
+                for k in TEAMS:
+                    if i < k:  # only non-oriented pairs
+                        for w in WEEKS:
+                            try:
+                                val = float(ampl.getValue(f"y[{i},{k},{w}]"))
+                                if val > 0.5:
+                                    y_dict[(i, k, w)] = int(round(val))
+                            except Exception:
+                                pass
+
+        try:
+            A_values = A_var.getValues()
+            df_A = A_values.to_pandas()
+            if not df_A.empty:
+                val_col = df_A.columns[-1]
+                idx_cols = df_A.columns[:-1]
+                for _, row in df_A.iterrows():
+                    indices = tuple(int(row[c]) if str(row[c]).isdigit() else row[c] for c in idx_cols)
+                    val = float(row[val_col])
+                    if val > 0.5:
+                        A_dict[indices] = int(round(val))
+        except Exception:
+            TEAMS = range(1, n + 1)
+            WEEKS = range(1, n)
+            PERIODS = range(1, n // 2 + 1)
+            for i in TEAMS:
+                for k in TEAMS:
+                    if i < k:  # only non-oriented pairs
+                        for w in WEEKS:
+                            for p in PERIODS:
+                                try:
+                                    val = float(ampl.getValue(f"A[{i},{k},{w},{p}]"))
+                                    if val > 0.5:
+                                        A_dict[(i, k, w, p)] = int(round(val))
+                                except Exception:
+                                    pass
+
+        try:
+            H_values = H_var.getValues()
+            df_H = H_values.to_pandas()
+            if not df_H.empty:
+                val_col = df_H.columns[-1]
+                idx_cols = df_H.columns[:-1]
+                for _, row in df_H.iterrows():
+                    indices = tuple(int(row[c]) if str(row[c]).isdigit() else row[c] for c in idx_cols)
+                    val = float(row[val_col])
+                    if val > 0.5:
+                        H_dict[indices] = int(round(val))
+        except Exception:
+            TEAMS = range(1, n + 1)
+            WEEKS = range(1, n)
+            for h in TEAMS:
+                for a in TEAMS:
+                    if h != a:
+                        for w in WEEKS:
+                            try:
+                                val = float(ampl.getValue(f"H[{h},{a},{w}]"))
+                                if val > 0.5:
+                                    H_dict[(h, a, w)] = int(round(val))
+                            except Exception:
+                                pass
+
+    except Exception:
+        print("Error processing variables, using fallback methods...")
+
+    return {
+        'A_dict': A_dict,
+        'H_dict': H_dict,
+        'y_dict': y_dict
+    }
+
+
+def run_model(results_dict, n, solver, use_sb=False, use_optimization=False, options=None):
     """
     Runs the MIP model with the given parameters and updates the results dictionary.
 
@@ -56,11 +321,19 @@ def run_model(results_dict, n, solver, use_sb=False, use_optimization=False):
         solver: The solver to use (e.g., "gurobi", "cplex")
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
+        options: Solver parameters of the run ("threads", "mip_gap") and the MIP "formulation"
+                 ("auto" selects it, with other options, from the instance size)
     Returns:
         results_dict: Updated dictionary with results for the given configuration
     """
 
-    key = utils.make_key(solver, use_sb, use_optimization)
+    key = result_key(solver, use_sb, use_optimization, options)
+    # The automatic configuration is stored under the "auto" key, with the selected options
+    selected = auto_configuration(n) if (options or {}).get("formulation") == "auto" else None
+    if selected:
+        options = {**options, **selected}
+    formulation = (options or {}).get("formulation") or "standard"
+    log = open_log("MIP", n, key)
     try:
         print(
             f"\nRunning MIP instance with"
@@ -68,121 +341,65 @@ def run_model(results_dict, n, solver, use_sb=False, use_optimization=False):
             f"\n  - solver = {solver}"
             f"\n  - symmetry breaking = {use_sb}"
             f"\n  - optimization = {use_optimization}"
+            f"\n  - formulation = {formulation}"
         )
 
         start = time.time()
-        ampl = mip_solver(n, solver, use_sb, use_optimization)
+        ampl, solver_params = mip_solver(n, solver, use_sb, use_optimization, options)
         elapsed_time = time.time() - start
+        if selected:
+            solver_params["auto"] = selected
 
-        y_var = ampl.getVariable('y')
-        A_var = ampl.getVariable('A')
-        H_var = ampl.getVariable('H')
-
-        y_dict = {}
-        A_dict = {}
-        H_dict = {}
-
-        try:
-            try:
-                y_values = y_var.getValues()
-                df_y = y_values.to_pandas()
-                if not df_y.empty:
-                    val_col = df_y.columns[-1]
-                    idx_cols = df_y.columns[:-1]
-                    for _, row in df_y.iterrows():
-                        indices = tuple(int(row[c]) if str(row[c]).isdigit() else row[c] for c in idx_cols)
-                        val = float(row[val_col])
-                        if val > 0.5:
-                            y_dict[indices] = int(round(val))
-            except Exception:
-                TEAMS = range(1, n + 1)
-                WEEKS = range(1, n)
-                for i in TEAMS:
-                    for k in TEAMS:
-                        if i < k:  # only non-oriented pairs
-                            for w in WEEKS:
-                                try:
-                                    val = float(ampl.getValue(f"y[{i},{k},{w}]"))
-                                    if val > 0.5:
-                                        y_dict[(i, k, w)] = int(round(val))
-                                except Exception:
-                                    pass
-
-            try:
-                A_values = A_var.getValues()
-                df_A = A_values.to_pandas()
-                if not df_A.empty:
-                    val_col = df_A.columns[-1]
-                    idx_cols = df_A.columns[:-1]
-                    for _, row in df_A.iterrows():
-                        indices = tuple(int(row[c]) if str(row[c]).isdigit() else row[c] for c in idx_cols)
-                        val = float(row[val_col])
-                        if val > 0.5:
-                            A_dict[indices] = int(round(val))
-            except Exception:
-                TEAMS = range(1, n + 1)
-                WEEKS = range(1, n)
-                PERIODS = range(1, n // 2 + 1)
-                for i in TEAMS:
-                    for k in TEAMS:
-                        if i < k:  # only non-oriented pairs
-                            for w in WEEKS:
-                                for p in PERIODS:
-                                    try:
-                                        val = float(ampl.getValue(f"A[{i},{k},{w},{p}]"))
-                                        if val > 0.5:
-                                            A_dict[(i, k, w, p)] = int(round(val))
-                                    except Exception:
-                                        pass
-
-            try:
-                H_values = H_var.getValues()
-                df_H = H_values.to_pandas()
-                if not df_H.empty:
-                    val_col = df_H.columns[-1]
-                    idx_cols = df_H.columns[:-1]
-                    for _, row in df_H.iterrows():
-                        indices = tuple(int(row[c]) if str(row[c]).isdigit() else row[c] for c in idx_cols)
-                        val = float(row[val_col])
-                        if val > 0.5:
-                            H_dict[indices] = int(round(val))
-            except Exception:
-                TEAMS = range(1, n + 1)
-                WEEKS = range(1, n)
-                for h in TEAMS:
-                    for a in TEAMS:
-                        if h != a:
-                            for w in WEEKS:
-                                try:
-                                    val = float(ampl.getValue(f"H[{h},{a},{w}]"))
-                                    if val > 0.5:
-                                        H_dict[(h, a, w)] = int(round(val))
-                                except Exception:
-                                    pass
-
-        except Exception:
-            print("Error processing variables, using fallback methods...")
 
         W, P = n - 1, n // 2
 
-        variables_dict = {
-            'A_dict': A_dict,
-            'H_dict': H_dict,
-            'y_dict': y_dict
-        }
-        solution = utils.parse_solution(ampl, variables_dict, W, P, n)
+        if formulation == "slot":
+            solution = utils.parse_solution(ampl, "X", W, P, n)
+        else:
+            solution = utils.parse_solution(ampl, read_assignments(ampl, n), W, P, n)
+
+        # A lazy run out of time may end on a schedule breaking the period limit, a relax-and-fix run
+        # stopped before its last block on a partly fractional one
+        if solver_params.get("lazy", {}).get("violated") or solver_params.get("relax_fix", {}).get("failed_block"):
+            solution = None
 
         time_val, optimal, solution, obj = utils.process_result(
             ampl, solution, elapsed_time, use_optimization
         )
 
+        # The objective is checked against the schedule, since the solver only satisfies the
+        # max imbalance linearization within its integrality and feasibility tolerances
+        if use_optimization and obj is not None and solution:
+            recomputed = utils.solution_imbalance(solution, n)
+            if recomputed != obj:
+                print(f"Warning: MIP objective {obj} differs from the schedule imbalance {recomputed}")
+                solver_params["objective_check"] = {"reported": obj, "recomputed": recomputed}
+                obj, optimal = recomputed, False
+
+        # The solver stops as "solved" at the relative gap of --mip-gap: only a closed gap is optimal
+        if use_optimization and obj is not None:
+            solver_params["bounds"] = utils.mip_bounds(ampl, obj)
+            solver_params["statistics"]["best_bound"] = solver_params["bounds"]["best_bound"]
+            optimal = optimal and solver_params["bounds"]["proven"]
+        # The relax-and-fix heuristic only proves its last block optimal for the fixed ones
+        if solver_params.get("relax_fix"):
+            optimal = optimal and not use_optimization
+        # After fix-and-optimize the last solve is on a fixed schedule: only the lower bound 1 proves it
+        if solver_params.get("fix_optimize"):
+            optimal = solver_params["fix_optimize"]["end"] == 1
+        # The time follows the final optimality claim: the elapsed time of a run proven within the
+        # limit, the limit for any other one
+        time_val, optimal = utils.final_time(elapsed_time, optimal)
+
         utils.print_solution(time_val, optimal, solution, obj)
 
         results_dict[key] = {
             "sol": solution,
             "time": time_val,
             "optimal": optimal,
-            "obj": obj
+            "obj": obj,
+            "params": solver_params,
+            "trace": pop_trace(solver_params)
         }
 
     except Exception:
@@ -193,11 +410,15 @@ def run_model(results_dict, n, solver, use_sb=False, use_optimization=False):
             "optimal": False,
             "obj": None
         }
+    close_log()
+    results_dict[key]["metadata"] = run_metadata(
+        "MIP", solver, package_version(f"ampl_module_{solver}"), variant=formulation,
+        threads=(results_dict[key].get("params") or {}).get("threads"), log=log)
 
     return results_dict
 
 
-def run_single_instance(n, solver, use_sb=False, use_optimization=False):
+def run_single_instance(n, solver, use_sb=False, use_optimization=False, options=None):
     """
     Runs a single instance of the MIP model with the given parameters.
 
@@ -206,6 +427,8 @@ def run_single_instance(n, solver, use_sb=False, use_optimization=False):
         solver: The solver to use (e.g., "gurobi", "cplex")
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
+        options: Solver parameters of the run ("threads", "mip_gap") and the MIP "formulation"
+                 ("compare" runs every formulation)
     """
 
     if solver is None:
@@ -213,16 +436,25 @@ def run_single_instance(n, solver, use_sb=False, use_optimization=False):
 
     os.makedirs(DEFAULT_MIP_OUTPUT_DIR, exist_ok=True)
     results_dict = {}
-    results_dict = run_model(results_dict, n, solver, use_sb, use_optimization)
+    for run_options in formulation_options(options):
+        results_dict = run_model(results_dict, n, solver, use_sb, use_optimization, run_options)
+
+    if (options or {}).get("formulation") == "compare":
+        compare_formulations(n, results_dict, solver, use_sb, use_optimization, options)
 
     utils.write_solution(DEFAULT_MIP_OUTPUT_DIR, n, results_dict)
 
 
-def run_all():
+def run_all(options=None):
     """
     Runs all configurations for the MIP model.
+
+    Params:
+        options: Solver parameters of the runs ("threads", "mip_gap") and the MIP "formulation"
+                 ("compare" runs every formulation)
     """
 
+    # The backends of the submitted results, HiGHS runs with --single --solver highs
     solvers = ["gurobi", "cplex"]
     instances = [6, 8, 10, 12, 14, 16]
     output_dir = DEFAULT_MIP_OUTPUT_DIR
@@ -234,6 +466,9 @@ def run_all():
         for solver in solvers:
             for sb in [False, True]:
                 for opt in [False, True]:
-                    results_dict = run_model(results_dict, n, solver, sb, opt)
+                    for run_options in formulation_options(options):
+                        results_dict = run_model(results_dict, n, solver, sb, opt, run_options)
+                    if (options or {}).get("formulation") == "compare":
+                        compare_formulations(n, results_dict, solver, sb, opt, options)
 
         utils.write_solution(output_dir, n, results_dict)
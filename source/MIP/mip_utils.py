@@ -1,5 +1,6 @@
-import os
-import json
+import math
+import re
+from source.results.writer import write_solution
 
 
 def parse_solution(ampl, variables_dict, W, P, n):
@@ -165,10 +166,6 @@ def process_result(ampl, solution, elapsed_time, use_optimization):
 
     obj = None
     is_optimal = False
-    time_val = int(elapsed_time)
-
-    if time_val > 300:
-        time_val = 300
 
     solve_status = ampl.get_value("solve_result_num")
     solve_result = ampl.get_value("solve_result")
@@ -181,36 +178,107 @@ def process_result(ampl, solution, elapsed_time, use_optimization):
             objectives = list(ampl.get_objectives())
             if objectives:
                 obj_name = objectives[0][0] if isinstance(objectives[0], tuple) else str(objectives[0])
-                obj = int(ampl.get_objective(obj_name).value())
+                # The solvers return the integer objective as a float within their tolerances
+                obj = int(round(ampl.get_objective(obj_name).value()))
     else:
         if has_solution and str(solve_result).lower() in ("solved", "feasible"):
             is_optimal = True
 
+    time_val, is_optimal = final_time(elapsed_time, is_optimal)
     return time_val, is_optimal, solution, obj
 
 
-def write_solution(output_dir, n, results_dict):
+def final_time(elapsed_time, optimal):
+    """
+    Derives the reported time and optimality of a run together: the elapsed time (floored)
+    of a run proven within the limit, the limit 300 and not optimal otherwise.
+
+    Returns:
+        tuple: (time_val, is_optimal)
     """
-    Writes the results to a JSON file.
+    time_val = int(elapsed_time)
+    if time_val >= 300 or not optimal:
+        return 300, False
+    return time_val, True
+
+
+def model_size(ampl):
+    """
+    Returns the size of the generated model: variables and constraints as instantiated by AMPL
+    and as sent to the solver after the AMPL presolve.
+    """
+    return {
+        "variables": int(ampl.get_value("_nvars")),
+        "constraints": int(ampl.get_value("_ncons")),
+        "solver_variables": int(ampl.get_value("_snvars")),
+        "solver_constraints": int(ampl.get_value("_sncons"))
+    }
+
+
+# Counters reported by the AMPL solver drivers in their solve message
+STATISTICS = {
+    "simplex_iterations": r"(\d+)\s+(?:MIP\s+|dual\s+)?simplex iterations",
+    "nodes": r"(\d+)\s+(?:branching|branch-and-bound|branch-and-cut)\s+nodes",
+    "cuts": r"(\d+)\s+cuts?\b",
+}
+
+
+def solve_statistics(ampl):
+    """
+    Extracts the explored nodes, simplex iterations and cuts of the last solve from the solve
+    message of the driver (None for the counters the driver does not report), with the solve
+    time measured by AMPL.
+    """
+    message = str(ampl.get_value("solve_message"))
+    statistics = {}
+    for name, pattern in STATISTICS.items():
+        match = re.search(pattern, message)
+        statistics[name] = int(match.group(1)) if match else None
+    statistics["solve_time"] = round(float(ampl.get_value("_solve_elapsed_time")), 3)
+    return statistics
+
+
+def mip_bounds(ampl, obj):
+    """
+    Reads the best bound of the objective (bestbound suffix) after an optimization run.
 
     Params:
-        output_dir: The directory where the results will be saved.
-        n: An identifier for the results (e.g., number of teams).
-        results_dict: A dictionary containing the results to be written.
+        ampl: The amplpy.AMPL object after solving.
+        obj: The objective value of the incumbent.
+
+    Returns:
+        A dictionary with the incumbent, the best bound (None if the solver did not return it),
+        the relative gap and whether the incumbent is proven optimal: the max imbalance is an
+        integer, so the gap is closed as soon as the bound is above obj - 1.
     """
+    try:
+        best_bound = float(ampl.get_value("MaxImbalanceObj.bestbound"))
+    except Exception:
+        best_bound = None
 
-    out_path = os.path.join(output_dir, f"{n}.json")
-    with open(out_path, 'w') as f:
-        f.write('{\n')
-        for i, (key, val) in enumerate(results_dict.items()):
-            f.write(f'  "{key}": {{\n')
+    if best_bound is None or math.isnan(best_bound) or math.isinf(best_bound):
+        return {"incumbent": obj, "best_bound": None, "gap": None, "proven": True}
 
-            sol_str = json.dumps(val["sol"], separators=(',', ':'))
-            f.write(f'    "time": {val["time"]},\n')
-            f.write(f'    "optimal": {"true" if val["optimal"] else "false"},\n')
-            f.write(f'    "obj": {json.dumps(val["obj"])},\n')
-            f.write(f'    "sol": {sol_str}\n')
+    gap = max(0.0, (obj - best_bound) / obj) if obj else 0.0
+    return {"incumbent": obj, "best_bound": round(best_bound, 6), "gap": round(gap, 6),
+            "proven": best_bound > obj - 1 + 1e-6}
 
-            f.write('  }' + (',' if i < len(results_dict) - 1 else '') + '\n')
-        f.write('}\n')
 
+def solution_imbalance(solution, n):
+    """
+    Recomputes the max home/away imbalance of a parsed schedule.
+
+    Params:
+        solution: The parsed solution as a list of lists of [home, away].
+        n: Number of teams.
+
+    Returns:
+        The max imbalance over the teams, None for an empty solution.
+    """
+    if not solution:
+        return None
+    home = [0] * (n + 1)
+    for row in solution:
+        for h, _ in row:
+            home[h] += 1
+    return max(abs(2 * home[t] - (n - 1)) for t in range(1, n + 1))
@@ -7,6 +7,8 @@ param P := n div 2;      # number of periods per week
 
 param use_sb default 0;  # symmetry breaking flag (0/1)
 param use_opt default 0; # optimization flag (0/1)
+param lazy_period default 0; # period limit only on PERIOD_CUTS (0/1)
+param disaggregated default 0; # period limit on per-team period indicators B (0/1)
 
 set TEAMS := 1..n;
 set WEEKS := 1..W;
@@ -15,6 +17,9 @@ set PERIODS := 1..P;
 # Set of unordered team pairs (i < k)
 set MATCHES := {i in TEAMS, k in TEAMS: i < k};
 
+# (team, period) pairs whose period limit is enforced when lazy_period = 1
+set PERIOD_CUTS within {TEAMS, PERIODS} default {};
+
 
 # =========================
 # DECISION VARIABLES
@@ -28,6 +33,10 @@ var A {i in TEAMS, k in TEAMS, w in WEEKS, p in PERIODS: i < k} binary;
 # 3) Home/away orientation for scheduled matches
 var H {h in TEAMS, a in TEAMS, w in WEEKS: h <> a} binary;
 
+# Per-team period indicators of the disaggregated period limit
+# (not in any constraint, hence dropped by the AMPL presolve, unless disaggregated = 1)
+var B {t in TEAMS, w in WEEKS, p in PERIODS} binary;
+
 # Variables for optimization (home/away balance)
 var home_games {t in TEAMS} integer >= 0 <= W;
 var away_games {t in TEAMS} integer >= 0 <= W;
@@ -49,13 +58,26 @@ s.t. OnePerWeek {t in TEAMS, w in WEEKS}:
     sum {k in TEAMS: k > t} y[t,k,w] = 1;
 
 # (3) Every team plays at most twice in the same period over the tournament
-s.t. MaxTwoPerPeriod {t in TEAMS, p in PERIODS}:
+s.t. MaxTwoPerPeriod {t in TEAMS, p in PERIODS: disaggregated = 0 and (lazy_period = 0 or (t,p) in PERIOD_CUTS)}:
     sum {w in WEEKS}
         (
           sum {k in TEAMS: k < t} A[k,t,w,p] +
           sum {k in TEAMS: k > t} A[t,k,w,p]
         ) <= 2;
 
+# (3') Disaggregated period limit: B[t,w,p] = 1 iff team t plays in period p in week w
+s.t. PeriodIndicator {t in TEAMS, w in WEEKS, p in PERIODS: disaggregated = 1}:
+    B[t,w,p] =
+        sum {k in TEAMS: k < t} A[k,t,w,p] +
+        sum {k in TEAMS: k > t} A[t,k,w,p];
+
+s.t. OnePeriodPerWeek {t in TEAMS, w in WEEKS: disaggregated = 1}:
+    sum {p in PERIODS} B[t,w,p] = 1;
+
+s.t. MaxTwoPerPeriodDisaggregated {t in TEAMS, p in PERIODS:
+        disaggregated = 1 and (lazy_period = 0 or (t,p) in PERIOD_CUTS)}:
+    sum {w in WEEKS} B[t,w,p] <= 2;
+
 
 # =========================
 # IMPLIED CONSTRAINTS
@@ -0,0 +1,105 @@
+# =========================
+# PARAMETERS
+# =========================
+param n;                 # number of teams
+param W := n - 1;        # number of weeks
+param P := n div 2;      # number of periods per week
+
+param use_sb default 0;  # symmetry breaking flag (0/1)
+param use_opt default 0; # optimization flag (0/1)
+param lazy_period default 0; # period limit only on PERIOD_CUTS (0/1)
+param disaggregated default 0; # period limit on per-team period indicators B (0/1)
+
+set TEAMS := 1..n;
+set WEEKS := 1..W;
+set PERIODS := 1..P;
+
+# (team, period) pairs whose period limit is enforced when lazy_period = 1
+set PERIOD_CUTS within {TEAMS, PERIODS} default {};
+
+
+# =========================
+# DECISION VARIABLES
+# =========================
+# Slot-indexed formulation: one variable per oriented match and slot (week, period),
+# instead of the aggregated match / period / orientation variables of mip_model.mod
+var X {h in TEAMS, a in TEAMS, w in WEEKS, p in PERIODS: h <> a} binary;
+
+# Per-team period indicators of the disaggregated period limit
+# (not in any constraint, hence dropped by the AMPL presolve, unless disaggregated = 1)
+var B {t in TEAMS, w in WEEKS, p in PERIODS} binary;
+
+# Variables for optimization (home/away balance)
+var home_games {t in TEAMS} integer >= 0 <= W;
+var away_games {t in TEAMS} integer >= 0 <= W;
+var imbalance  {t in TEAMS} integer >= 1 <= W;
+var max_imbalance integer >= 1 <= W;
+
+
+# =========================
+# HARD CONSTRAINTS
+# =========================
+
+# (1) Every team plays with every other team only once
+s.t. PairOnce {i in TEAMS, k in TEAMS: i < k}:
+    sum {w in WEEKS, p in PERIODS} (X[i,k,w,p] + X[k,i,w,p]) = 1;
+
+# (2) Every team plays once a week
+s.t. OnePerWeek {t in TEAMS, w in WEEKS}:
+    sum {k in TEAMS, p in PERIODS: k <> t} (X[t,k,w,p] + X[k,t,w,p]) = 1;
+
+# (3) Every team plays at most twice in the same period over the tournament
+s.t. MaxTwoPerPeriod {t in TEAMS, p in PERIODS: disaggregated = 0 and (lazy_period = 0 or (t,p) in PERIOD_CUTS)}:
+    sum {w in WEEKS, k in TEAMS: k <> t} (X[t,k,w,p] + X[k,t,w,p]) <= 2;
+
+# (3') Disaggregated period limit: B[t,w,p] = 1 iff team t plays in period p in week w
+s.t. PeriodIndicator {t in TEAMS, w in WEEKS, p in PERIODS: disaggregated = 1}:
+    B[t,w,p] =
+        sum {k in TEAMS: k <> t} (X[t,k,w,p] + X[k,t,w,p]);
+
+s.t. OnePeriodPerWeek {t in TEAMS, w in WEEKS: disaggregated = 1}:
+    sum {p in PERIODS} B[t,w,p] = 1;
+
+s.t. MaxTwoPerPeriodDisaggregated {t in TEAMS, p in PERIODS:
+        disaggregated = 1 and (lazy_period = 0 or (t,p) in PERIOD_CUTS)}:
+    sum {w in WEEKS} B[t,w,p] <= 2;
+
+
+# =========================
+# IMPLIED CONSTRAINTS
+# =========================
+
+# (IC1) One match per slot
+s.t. OneMatchPerSlot {w in WEEKS, p in PERIODS}:
+    sum {h in TEAMS, a in TEAMS: h <> a} X[h,a,w,p] = 1;
+
+
+# =========================
+# SYMMETRY BREAKING (if use_sb = 1)
+# =========================
+
+# (SB1): Fix match (1,2) in week 1
+s.t. SB1:
+    sum {p in PERIODS} (X[1,2,1,p] + X[2,1,1,p]) = use_sb;
+
+# (SB2): Team 1 plays team (w+1) in week w
+s.t. SB2 {w in WEEKS: w+1 <= n}:
+    sum {p in PERIODS} (X[1,w+1,w,p] + X[w+1,1,w,p]) >= use_sb;
+
+
+# =========================
+# HOME/AWAY BALANCE & OBJECTIVE
+# =========================
+
+s.t. HomeGamesDef {t in TEAMS}:
+    home_games[t] = sum {a in TEAMS, w in WEEKS, p in PERIODS: a <> t} X[t,a,w,p];
+
+s.t. AwayGamesDef {t in TEAMS}:
+    away_games[t] = sum {h in TEAMS, w in WEEKS, p in PERIODS: h <> t} X[h,t,w,p];
+
+s.t. Imbalance1 {t in TEAMS}: imbalance[t] >= home_games[t] - away_games[t];
+s.t. Imbalance2 {t in TEAMS}: imbalance[t] >= away_games[t] - home_games[t];
+
+s.t. MaxImbalanceDef {t in TEAMS}: max_imbalance >= imbalance[t];
+
+minimize MaxImbalanceObj: use_opt * max_imbalance;
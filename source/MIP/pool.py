@@ -0,0 +1,45 @@
+import os
+from source.MIP.export import DEFAULT_EXPORT_DIR
+from source.MIP.relax_fix import WEEK_VARIABLES
+
+# Variables restored after reading the pool, so that the result is read from the incumbent
+OBJECTIVE_VARIABLES = ["home_games", "away_games", "imbalance", "max_imbalance"]
+
+
+def pool_stub(n, formulation, use_sb, use_optimization):
+    """
+    Returns the stub of the pool solution files of a configuration under artifacts/MIP/pool
+    (the solver writes <stub>1.sol, <stub>2.sol, ...).
+    """
+    directory = os.path.join(DEFAULT_EXPORT_DIR, "pool")
+    os.makedirs(directory, exist_ok=True)
+    name = f"{n}_{formulation}_{'sb' if use_sb else 'nosb'}_{'opt' if use_optimization else 'noopt'}_"
+    return os.path.join(directory, name)
+
+
+def read_pool(ampl, stub, formulation):
+    """
+    Reads the solutions of the pool written by the solver (problem suffix nsol) and restores
+    the incumbent afterwards.
+
+    Returns:
+        dict: {"count", "distinct", "objectives"}: the number of pool solutions, the number of
+              distinct schedules among them and the max imbalance of each one
+    """
+    count = int(ampl.get_value("Current.nsol"))
+    names = [name for name, _, _ in WEEK_VARIABLES[formulation]] + OBJECTIVE_VARIABLES
+    incumbent = {name: ampl.getVariable(name).getValues().toDict() for name in names}
+
+    schedules, objectives = set(), []
+    for k in range(1, count + 1):
+        ampl.eval(f'solution "{stub}{k}.sol";')
+        active = frozenset((name, index) for name, _, _ in WEEK_VARIABLES[formulation]
+                           for index, value in ampl.getVariable(name).getValues().toDict().items()
+                           if value > 0.5)
+        schedules.add(active)
+        objectives.append(int(round(ampl.getVariable("max_imbalance").value())))
+
+    for name in names:
+        ampl.getVariable(name).setValues(incumbent[name])
+
+    return {"count": count, "distinct": len(schedules), "objectives": objectives}
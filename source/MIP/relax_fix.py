@@ -0,0 +1,138 @@
+import math
+import time
+
+# Week-indexed assignment variables of each formulation: (name, AMPL indexing, subscript);
+# the week is always the third index
+WEEK_VARIABLES = {
+    "standard": [
+        ("y", "i in TEAMS, k in TEAMS, w in WEEKS: i < k", "i,k,w"),
+        ("A", "i in TEAMS, k in TEAMS, w in WEEKS, p in PERIODS: i < k", "i,k,w,p"),
+        ("H", "h in TEAMS, a in TEAMS, w in WEEKS: h <> a", "h,a,w"),
+    ],
+    "slot": [
+        ("X", "h in TEAMS, a in TEAMS, w in WEEKS, p in PERIODS: h <> a", "h,a,w,p"),
+    ],
+}
+
+
+def set_relaxed(ampl, formulation, first_week, relaxed):
+    """
+    Relaxes (relax suffix 1) or restores the integrality of the variables from first_week on.
+    """
+    for name, indexing, subscript in WEEK_VARIABLES[formulation]:
+        ampl.eval(f"let {{{indexing} and w >= {first_week}}} {name}[{subscript}].relax := {int(relaxed)};")
+
+
+def fix_weeks(ampl, formulation, first_week, last_week):
+    """
+    Fixes the variables of the weeks first_week..last_week at their (rounded) current values.
+    """
+    for name, indexing, subscript in WEEK_VARIABLES[formulation]:
+        ampl.eval(f"fix {{{indexing} and w >= {first_week} and w <= {last_week}}} "
+                  f"{name}[{subscript}] := round({name}[{subscript}]);")
+
+
+def unfix_weeks(ampl, formulation, first_week, last_week):
+    """
+    Frees the variables of the weeks first_week..last_week.
+    """
+    for name, indexing, subscript in WEEK_VARIABLES[formulation]:
+        ampl.eval(f"unfix {{{indexing} and w >= {first_week} and w <= {last_week}}} {name}[{subscript}];")
+
+
+def relax_and_fix(ampl, backend, params, n, formulation, block, time_limit):
+    """
+    Relax-and-fix heuristic over blocks of weeks: the variables of the current block are
+    integer and the ones of the following weeks relaxed; once the block is solved its variables
+    are fixed and the next block is made integer, until the whole schedule is fixed. A fixed
+    block that leaves the next ones infeasible ends the heuristic without a schedule.
+
+    Params:
+        block: Number of weeks made integer at every step
+        time_limit: Total budget of the steps, shared equally
+
+    Returns:
+        dict: {"blocks", "solved", "failed_block"}, stored in the solver parameters
+    """
+    weeks = n - 1
+    steps = math.ceil(weeks / block)
+    deadline = time.time() + time_limit
+
+    set_relaxed(ampl, formulation, 1, True)
+    solved = 0
+    for step in range(steps):
+        first_week, last_week = step * block + 1, min(weeks, (step + 1) * block)
+        remaining = deadline - time.time()
+        if remaining <= 0:
+            break
+        backend.configure(ampl, {**params, "time_limit": max(1, math.floor(remaining / (steps - step)))})
+
+        set_relaxed(ampl, formulation, first_week, False)
+        set_relaxed(ampl, formulation, last_week + 1, True)
+        ampl.solve()
+        if str(ampl.get_value("solve_result")).lower() != "solved":
+            return {"blocks": steps, "solved": solved, "failed_block": step + 1}
+
+        fix_weeks(ampl, formulation, first_week, last_week)
+        solved += 1
+
+    return {"blocks": steps, "solved": solved, "failed_block": None if solved == steps else solved + 1}
+
+
+def fix_and_optimize(ampl, backend, params, n, formulation, free_weeks, time_limit, on_improvement=None):
+    """
+    Fix-and-optimize improvement of the (complete) incumbent of an optimization run that did not
+    prove it optimal: every step fixes the
+    variables of all the weeks but a window of free_weeks consecutive ones at the incumbent and
+    re-optimizes the window. The windows slide over the weeks until a full pass brings no
+    improvement or the budget is spent; a step that does not improve restores the incumbent.
+    on_improvement, if given, is called with the objective of every improving step.
+
+    Returns:
+        dict: {"steps", "improvements", "start", "end"} (objective before and after), stored in
+              the solver parameters
+    """
+    weeks = n - 1
+    free_weeks = min(free_weeks, weeks)
+    deadline = time.time() + time_limit
+    objective = ampl.getObjective("MaxImbalanceObj")
+    best = round(objective.value())
+    names = [name for name, _, _ in WEEK_VARIABLES[formulation]]
+    incumbent = {name: ampl.getVariable(name).getValues().toDict() for name in names}
+    start, steps, improvements = best, 0, 0
+
+    improved = True
+    while improved and best > 1:
+        improved = False
+        for first_week in range(1, weeks - free_weeks + 2):
+            remaining = deadline - time.time()
+            if remaining <= 0 or best == 1:
+                break
+            last_week = first_week + free_weeks - 1
+            fix_weeks(ampl, formulation, 1, weeks)
+            unfix_weeks(ampl, formulation, first_week, last_week)
+            backend.configure(ampl, {**params, "time_limit": max(1, math.floor(remaining))})
+            ampl.solve()
+            steps += 1
+
+            value = objective.value() if str(ampl.get_value("solve_result")).lower() in ("solved", "limit") else None
+            if value is not None and round(value) < best:
+                best, improved = round(value), True
+                improvements += 1
+                if on_improvement is not None:
+                    on_improvement(best)
+                incumbent = {name: ampl.getVariable(name).getValues().toDict() for name in names}
+            else:
+                for name in names:
+                    ampl.getVariable(name).setValues(incumbent[name])
+
+        if deadline - time.time() <= 0:
+            break
+
+    # The week variables stay fixed at the incumbent, which the result is read from
+    for name in names:
+        ampl.getVariable(name).setValues(incumbent[name])
+    fix_weeks(ampl, formulation, 1, weeks)
+    ampl.solve()
+
+    return {"steps": steps, "improvements": improvements, "start": start, "end": best}
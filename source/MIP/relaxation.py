@@ -0,0 +1,34 @@
+import math
+import time
+
+
+def lp_relaxation_bound(ampl, n):
+    """
+    Solves the root LP relaxation of the MIP (relax_integrality) with the max imbalance as
+    objective, whatever the use_opt flag of the run, then restores the integer model.
+
+    Its optimum rounded up is a valid lower bound of the max imbalance; since every team
+    plays an odd number of games, the imbalance is odd and the bound is rounded up to odd.
+
+    Returns:
+        dict: {"value", "bound", "time"}; value and bound are None if the LP was not solved
+    """
+    start_time = time.time()
+    use_opt = ampl.getParameter('use_opt').value()
+
+    ampl.setOption("relax_integrality", 1)
+    ampl.getParameter('use_opt').set(1)
+    ampl.solve()
+
+    value, bound = None, None
+    if str(ampl.get_value("solve_result")).lower() == "solved":
+        value = ampl.getVariable('max_imbalance').value()
+        bound = math.ceil(value - 1e-9)
+        if (n - 1) % 2 and bound % 2 == 0:
+            bound += 1
+        bound = max(1, bound)
+
+    ampl.setOption("relax_integrality", 0)
+    ampl.getParameter('use_opt').set(use_opt)
+
+    return {"value": value, "bound": bound, "time": round(time.time() - start_time, 3)}
@@ -0,0 +1,46 @@
+from source.common.heuristic import greedy_schedule, relabel_for_symmetry_breaking, max_imbalance
+
+
+def set_mip_start(ampl, n, use_sb=False, time_limit=10, formulation="standard"):
+    """
+    Runs the greedy heuristic and sets its schedule as the initial values of the MIP variables
+    (relabeled for SB1 / SB2 when symmetry breaking is enabled), so that the solvers, which
+    receive the AMPL initial values as their MIP start, have an incumbent at the root node.
+
+    Returns:
+        dict: {"imbalance"} of the heuristic schedule, stored in the solver parameters, or None
+              if the heuristic did not repair all the period conflicts (no start is set)
+    """
+    matches, valid = greedy_schedule(n, time_limit=time_limit)
+    if not valid:
+        return None
+    if use_sb:
+        matches = relabel_for_symmetry_breaking(matches, n)
+
+    # 1-based indices of the model
+    played = {(min(h, a) + 1, max(h, a) + 1, w + 1): p + 1 for h, a, w, p in matches}
+    hosts = {(h + 1, a + 1, w + 1) for h, a, w, _ in matches}
+    TEAMS = range(1, n + 1)
+    WEEKS = range(1, n)
+    PERIODS = range(1, n // 2 + 1)
+
+    if formulation == "slot":
+        slots = {(h + 1, a + 1, w + 1, p + 1) for h, a, w, p in matches}
+        ampl.getVariable('X').setValues({(h, a, w, p): int((h, a, w, p) in slots)
+                                         for h in TEAMS for a in TEAMS if h != a for w in WEEKS for p in PERIODS})
+    else:
+        ampl.getVariable('y').setValues({(i, k, w): int((i, k, w) in played)
+                                         for i in TEAMS for k in TEAMS if i < k for w in WEEKS})
+        ampl.getVariable('A').setValues({(i, k, w, p): int(played.get((i, k, w)) == p)
+                                         for i in TEAMS for k in TEAMS if i < k for w in WEEKS for p in PERIODS})
+        ampl.getVariable('H').setValues({(h, a, w): int((h, a, w) in hosts)
+                                         for h in TEAMS for a in TEAMS if h != a for w in WEEKS})
+
+    home = {t: sum(1 for h, _, _ in hosts if h == t) for t in TEAMS}
+    ampl.getVariable('home_games').setValues({t: home[t] for t in TEAMS})
+    ampl.getVariable('away_games').setValues({t: (n - 1) - home[t] for t in TEAMS})
+    ampl.getVariable('imbalance').setValues({t: abs(2 * home[t] - (n - 1)) for t in TEAMS})
+    imbalance = max_imbalance(matches, n)
+    ampl.getVariable('max_imbalance').setValues([imbalance])
+
+    return {"imbalance": imbalance}
@@ -1,21 +1,153 @@
 from source.SAT.model import sat_model
 from z3 import *
 
+DEFAULT_SEED = 42
 
-def build_model(n_teams, use_sb=False, use_optimization=False, max_diff_constraint=None):
+# Z3 defaults, restored on every build so that options of a previous run
+# (set through the global sat.* parameters) do not leak into the next one
+Z3_SAT_DEFAULTS = {
+    "sat.restart": "ema",
+    "sat.variable_decay": 110,
+}
+
+# Restart strategies accepted by each SAT solver (--restart)
+RESTARTS = {
+    "z3": ["luby", "geometric", "ema", "static"],
+    "glucose": ["glucose"],
+}
+
+# Constraint families that can be switched off for debugging (options["disable"])
+CONSTRAINT_GROUPS = ["pairs", "weekly", "period_limit", "channeling", "implied", "symmetry"]
+
+
+class EncodingTooLarge(Exception):
+    """
+    Raised when a formula exceeds the clause budget (options["clause_budget"]) before
+    it is sent to the solver.
+    """
+
+    def __init__(self, clauses, budget):
+        super().__init__(f"encoding too large: ~{clauses} clauses, budget {budget}")
+        self.clauses = clauses
+        self.budget = budget
+
+
+def estimate_clauses(expr):
+    """
+    Estimates the number of CNF clauses of a Z3 Boolean expression: one per clause,
+    with Tseitin definitions for nested connectives and the size of a sequential counter
+    (about 2 * m * k clauses for at most k of m literals) for pseudo-Boolean constraints.
+    """
+    if is_and(expr):
+        return sum(estimate_clauses(c) for c in expr.children())
+    if is_or(expr):
+        return 1 + sum(estimate_clauses(c) for c in expr.children() if not _is_literal(c))
+    if is_implies(expr):
+        a, b = expr.children()
+        return estimate_clauses(b) + (0 if _is_literal(a) else estimate_clauses(a))
+    if is_app_of(expr, Z3_OP_PB_LE) or is_app_of(expr, Z3_OP_PB_GE) or is_app_of(expr, Z3_OP_PB_EQ) \
+            or is_app_of(expr, Z3_OP_PB_AT_MOST) or is_app_of(expr, Z3_OP_PB_AT_LEAST):
+        m = expr.num_args()
+        k = expr.params()[0] if expr.params() else 1
+        # at least k of m is at most m - k of the negations, an equality needs both directions
+        counter = 2 * m * max(min(k, m - k), 1)
+        return 2 * counter if is_app_of(expr, Z3_OP_PB_EQ) else counter
+    if is_not(expr):
+        child = expr.arg(0)
+        return 1 if _is_literal(child) else 1 + estimate_clauses(child)
+    return 1
+
+
+def _is_literal(expr):
+    return is_const(expr) or (is_not(expr) and is_const(expr.arg(0)))
+
+
+def check_clause_budget(clauses, options=None):
+    """
+    Raises EncodingTooLarge if clauses exceeds options["clause_budget"] (no budget by default).
+    """
+    budget = (options or {}).get("clause_budget")
+    if budget is not None and clauses > budget:
+        raise EncodingTooLarge(clauses, budget)
+
+
+class GuardedSolver:
+    """
+    Solver proxy adding every constraint as literal -> constraint, so that a whole
+    constraint family is enabled or disabled by assuming its activation literal.
+    """
+
+    def __init__(self, solver, literal):
+        self.solver = solver
+        self.literal = literal
+
+    def add(self, *constraints):
+        for c in constraints:
+            for item in (c if isinstance(c, list) else [c]):
+                self.solver.add(Implies(self.literal, item))
+
+
+def apply_solver_options(solver, options=None):
+    """
+    Applies the seed, restart strategy and variable decay options to a Z3 solver.
+
+    Params:
+        solver: The Z3 solver
+        options: Dictionary with the optional keys "seed", "restart" and "var_decay".
+                 var_decay uses the MiniSat/Glucose convention (activity decay factor
+                 in (0, 1)) and is converted to Z3's inverse percentage.
+    Returns:
+        dict: The effective parameters, to be recorded in the results
+    """
+    options = options or {}
+    seed = options.get("seed")
+    seed = DEFAULT_SEED if seed is None else seed
+
+    for name, value in Z3_SAT_DEFAULTS.items():
+        set_param(name, value)
+
+    solver.set("random_seed", seed)
+    set_param("sat.random_seed", seed)
+
+    restart = options.get("restart")
+    if restart and restart not in RESTARTS["z3"]:
+        # --all runs every solver with the same options: Z3 rejects the other strategies
+        print(f"Warning: restart strategy '{restart}' is not supported by Z3, using its default")
+        restart = None
+    if restart:
+        set_param("sat.restart", restart)
+
+    if options.get("var_decay"):
+        set_param("sat.variable_decay", round(100 / options["var_decay"]))
+
+    return {
+        "seed": seed,
+        "restart": restart or Z3_SAT_DEFAULTS["sat.restart"],
+        "var_decay": options.get("var_decay"),
+    }
+
+
+def build_model(n_teams, use_sb=False, use_optimization=False, max_diff_constraint=None, options=None,
+                track_groups=False):
     """
     Builds the SAT model with specified parameters.
+
+    When options["disable"] is a list (possibly empty), each constraint family of
+    CONSTRAINT_GROUPS is guarded by an activation literal and the listed ones are left off.
     
     Params:
         n_teams: Number of teams
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
         max_diff_constraint: maximum allowed home-away imbalance (optional)
+        options: Dictionary of solver options (seed, restart, var_decay, disable)
+        track_groups: Return the activation literals of the enabled groups in extra_params["groups"],
+                      to be passed as assumptions, instead of asserting them
     Returns:
         tuple: (solver, home, per, weeks, periods, extra_params)
     """
     solver = Solver()
-    solver.set("random_seed", 42)
+    solver_params = apply_solver_options(solver, options)
     solver.set("timeout", 300_000)  # 5 minutes timeout
     
     
@@ -28,13 +160,37 @@ def build_model(n_teams, use_sb=False, use_optimization=False, max_diff_constrai
     # Create variables
     home, per = sat_model.create_variables(Teams, Weeks, Periods)
     
+    disabled = (options or {}).get("disable")
+    groups = {}
+
+    def target(group):
+        if disabled is None:
+            return solver
+        groups[group] = Bool(f"group_{group}")
+        return GuardedSolver(solver, groups[group])
+
     # Add constraints
-    sat_model.add_hard_constraints(home, per, Teams, Weeks, Periods, solver)
-    sat_model.add_channeling_constraint(home, per, Teams, Weeks, Periods, solver)
-    sat_model.add_implied_constraints(home, per, Teams, Weeks, Periods, solver)  
+    sat_model.constraint_each_pair_once(home, Teams, Weeks, target("pairs"))
+    sat_model.constraint_one_match_per_week(home, Teams, Weeks, target("weekly"))
+    sat_model.constraint_max_two_per_period(per, Teams, Weeks, Periods, target("period_limit"))
+    sat_model.add_channeling_constraint(home, per, Teams, Weeks, Periods, target("channeling"))
+    sat_model.add_implied_constraints(home, per, Teams, Weeks, Periods, target("implied"))
 
     if use_sb:
-        sat_model.add_symmetry_breaking_constraints(home, per, Teams, Weeks, Periods, solver, use_optimization)
+        sat_model.add_symmetry_breaking_constraints(home, per, Teams, Weeks, Periods, target("symmetry"),
+                                                    use_optimization)
+
+    # Refuse oversized encodings before the solver thrashes memory for the whole time limit
+    if (options or {}).get("clause_budget") is not None:
+        clauses = sum(estimate_clauses(a) for a in solver.assertions())
+        solver_params["estimated_clauses"] = clauses
+        check_clause_budget(clauses, options)
+
+    enabled = {group: literal for group, literal in groups.items() if group not in (disabled or [])}
+    if disabled is not None:
+        solver_params["disabled_groups"] = sorted(disabled)
+        if not track_groups:
+            solver.add(list(enabled.values()))
 
     
     extra_params = {
@@ -42,7 +198,10 @@ def build_model(n_teams, use_sb=False, use_optimization=False, max_diff_constrai
         "opt": use_optimization,
         "teams_list": Teams,  
         "teams": n_teams,
-        "max_diff_constraint": max_diff_constraint
+        "max_diff_constraint": max_diff_constraint,
+        "solver_params": solver_params
     }
+    if track_groups and disabled is not None:
+        extra_params["groups"] = enabled
     
     return solver, home, per, Weeks, Periods, extra_params
\ No newline at end of file
@@ -0,0 +1,158 @@
+from source.SAT.model.sat_model import add_max_diff_constraint, add_max_diff_totalizer
+from source.common.brute_force import enumerate_schedules, satisfies_symmetry_breaking
+from source.common.heuristic import greedy_schedule, relabel_for_symmetry_breaking, max_imbalance
+from source.SAT.build_model import build_model
+from z3 import *
+import random
+
+# Weeks left free in the random instances: the brute force enumeration grows by
+# orders of magnitude with every free week (128 schedules with 2, ~6000 with 3)
+FREE_WEEKS = 2
+
+
+def project(matches):
+    """
+    Projects a schedule onto the names of the true home/per variables of the SAT model.
+    """
+    literals = set()
+    for h, a, w, p in matches:
+        literals.update({f"h_{h}_{a}_{w}", f"p_{h}_{w}_{p}", f"p_{a}_{w}_{p}"})
+    return frozenset(literals)
+
+
+def random_instance(n_teams, rng, use_sb=False, use_optimization=False):
+    """
+    Builds a tiny random instance by fixing all but FREE_WEEKS weeks of a random
+    valid schedule (the greedy one with randomly permuted teams and periods, and a random
+    global orientation: all of them preserve validity).
+
+    Returns:
+        dict: {week: [(home, away, period), ...]} of the fixed weeks
+    """
+    num_weeks, num_periods = n_teams - 1, n_teams // 2
+    if num_weeks <= FREE_WEEKS:
+        return {}
+
+    matches, _ = greedy_schedule(n_teams, time_limit=5, seed=rng.randrange(10 ** 6))
+    teams = rng.sample(range(n_teams), n_teams)
+    periods = rng.sample(range(num_periods), num_periods)
+    flip = rng.random() < 0.5
+    matches = [(teams[a], teams[h], w, periods[p]) if flip else (teams[h], teams[a], w, periods[p])
+               for h, a, w, p in matches]
+
+    if use_sb:
+        matches = relabel_for_symmetry_breaking(matches, n_teams, team_order=not use_optimization)
+
+    fixed = {}
+    for h, a, w, p in matches:
+        if w < num_weeks - FREE_WEEKS:
+            fixed.setdefault(w, []).append((h, a, p))
+    return fixed
+
+
+def brute_force_models(n_teams, fixed, use_sb, use_optimization, max_diff):
+    """
+    Returns the projected set of all schedules satisfying the definition of the problem,
+    the symmetry breaking and the imbalance bound of the configuration.
+    """
+    models = set()
+    for matches in enumerate_schedules(n_teams, fixed):
+        if use_sb and not satisfies_symmetry_breaking(matches, n_teams, use_optimization):
+            continue
+        if max_diff is not None and max_imbalance(matches, n_teams) > max_diff:
+            continue
+        models.add(project(matches))
+    return models
+
+
+def sat_models(n_teams, fixed, use_sb, use_optimization, max_diff, options=None, limit=None):
+    """
+    Enumerates the models of the SAT encoding projected onto the home/per variables,
+    blocking each projected assignment once found.
+
+    Params:
+        limit: Stops after this many models (an encoding with more models than the
+               brute force reference is already known to be unsound)
+    """
+    solver, home, per, Weeks, Periods, _ = build_model(n_teams, use_sb, use_optimization, options=options)
+    Teams = list(range(n_teams))
+
+    if max_diff is not None:
+        if ((options or {}).get("objective_encoding") or "pb") == "totalizer":
+            solver.add(add_max_diff_totalizer(home, Teams, Weeks, solver)[max_diff])
+        else:
+            add_max_diff_constraint(home, Teams, Weeks, max_diff, solver)
+
+    for w, week in fixed.items():
+        for h, a, p in week:
+            solver.add(home[h][a][w], per[h][w][p], per[a][w][p])
+
+    variables = [v for i in Teams for j in Teams if i != j for v in home[i][j]]
+    variables += [v for i in Teams for w in Weeks for v in per[i][w]]
+
+    models = set()
+    while solver.check() == sat:
+        model = solver.model()
+        true_vars = frozenset(str(v) for v in variables if is_true(model.eval(v, model_completion=True)))
+        models.add(true_vars)
+        if limit is not None and len(models) >= limit:
+            break
+        solver.add(Or([Not(v) if str(v) in true_vars else v for v in variables]))
+
+    return models
+
+
+def crosscheck(n_teams, fixed, use_sb, use_optimization, max_diff, options=None):
+    """
+    Compares the projected models of the SAT encoding with the brute force reference.
+
+    Returns:
+        tuple: (reference models, missing, extra) - missing schedules are excluded by the
+               encoding (over-constrained), extra ones are accepted by it but invalid (unsound)
+    """
+    expected = brute_force_models(n_teams, fixed, use_sb, use_optimization, max_diff)
+    found = sat_models(n_teams, fixed, use_sb, use_optimization, max_diff, options, limit=len(expected) + 10)
+    return expected, expected - found, found - expected
+
+
+def run_crosscheck(trials=5, seed=None, options=None):
+    """
+    Runs the brute force cross-check of the SAT encoding: the infeasible n=4 instance and
+    random n=6 instances, with and without symmetry breaking and imbalance bounds.
+
+    Params:
+        trials: Number of random n=6 instances
+        seed: Seed of the instance generator
+        options: Dictionary of solver options (objective_encoding selects the bound encoding)
+    Returns:
+        bool: True if the encoding matched the reference on every check
+    """
+    rng = random.Random(seed or 0)
+    encoding = (options or {}).get("objective_encoding") or "pb"
+    checks = [(4, False, False, None), (4, True, False, None)]
+    for _ in range(trials):
+        for use_sb in (False, True):
+            checks.append((6, use_sb, False, None))
+            checks.append((6, use_sb, True, rng.randint(1, 5)))
+
+    failures = 0
+    for n_teams, use_sb, use_optimization, max_diff in checks:
+        fixed = random_instance(n_teams, rng, use_sb, use_optimization)
+        expected, missing, extra = crosscheck(n_teams, fixed, use_sb, use_optimization, max_diff, options)
+
+        config = (f"n={n_teams} sb={use_sb} opt={use_optimization} max_diff={max_diff}"
+                  f"{f' ({encoding})' if max_diff is not None else ''}")
+        if not missing and not extra:
+            print(f"OK    {config}: {len(expected)} models")
+            continue
+
+        failures += 1
+        print(f"FAIL  {config}: {len(expected)} reference models, "
+              f"{len(missing)} missing from the encoding, {len(extra)} invalid models accepted")
+        for label, models in (("missing", missing), ("invalid", extra)):
+            if models:
+                example = sorted(v for v in next(iter(models)) if v.startswith("h_"))
+                print(f"        e.g. {label}: {' '.join(example)}")
+
+    print(f"\nCross-check: {len(checks) - failures}/{len(checks)} configurations match the brute force reference")
+    return failures == 0
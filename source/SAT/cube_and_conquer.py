@@ -0,0 +1,195 @@
+from source.SAT.model.sat_model import add_max_diff_constraint
+from source.SAT.build_model import build_model
+from source.SAT import sat_utils as utils
+from source.common.bounds import initial_lower_bound
+from source.common.trace import record_improvement
+from itertools import product
+import multiprocessing as mp
+from z3 import *
+import time
+import os
+
+# Best objective found so far, shared between the worker processes
+_best_obj = None
+
+
+def generate_cubes(n_teams, depth, use_sb=False):
+    """
+    Splits the search space by fixing the period of team 0 in the first `depth` weeks.
+    Every team plays exactly one period per week, so the cubes are a partition;
+    cubes using a period more than twice are discarded since they are trivially UNSAT.
+
+    Params:
+        n_teams: Number of teams
+        depth: Number of weeks whose period is fixed by each cube
+        use_sb: Whether symmetry breaking is used (week 0 is then already fixed to period 0)
+    Returns:
+        list: Cubes, each a list of (week, period) pairs for team 0
+    """
+    num_weeks, num_periods = n_teams - 1, n_teams // 2
+    first_week = 1 if use_sb else 0
+    weeks = list(range(first_week, min(first_week + depth, num_weeks)))
+
+    cubes = []
+    for periods in product(range(num_periods), repeat=len(weeks)):
+        counts = [periods.count(p) + (1 if use_sb and p == 0 else 0) for p in range(num_periods)]
+        if max(counts) <= 2:
+            cubes.append(list(zip(weeks, periods)))
+
+    return cubes
+
+
+def _init_worker(best_obj):
+    global _best_obj
+    _best_obj = best_obj
+
+
+def _parse_schedule(model, home, per, Teams, Weeks, Periods):
+    return utils.parse_solution({
+        "status": sat,
+        "model": model,
+        "variables": {"home": home, "per": per},
+        "weeks": Weeks,
+        "periods": Periods,
+        "extra_params": {"teams_list": Teams},
+    })
+
+
+def _solve_cube(task):
+    """
+    Solves a single cube in a worker process. In optimization mode, a binary search
+    is run on the max imbalance, bounded from above by the best objective of all workers
+    and from below by the lower bound of the instance.
+    """
+    n_teams, use_sb, use_optimization, cube, deadline, options, lower_bound = task
+
+    solver, home, per, Weeks, Periods, _ = build_model(n_teams, use_sb, use_optimization, options=options)
+    Teams = list(range(n_teams))
+
+    for w, p in cube:
+        solver.add(per[0][w][p])
+
+    result = {"cube": cube, "obj": None, "schedule": None, "complete": True}
+
+    if not use_optimization:
+        solver.set("timeout", max(1, int((deadline - time.time()) * 1000)))
+        status = solver.check()
+        if status == sat:
+            result["schedule"] = _parse_schedule(solver.model(), home, per, Teams, Weeks, Periods)
+        result["complete"] = status != unknown
+        return result
+
+    lower, upper = lower_bound, n_teams - 1
+    while True:
+        upper = min(upper, _best_obj.value - 1)
+        remaining = deadline - time.time()
+        if lower > upper:
+            break
+        if remaining <= 0:
+            result["complete"] = False
+            break
+
+        mid = (lower + upper) // 2
+        solver.push()
+        add_max_diff_constraint(home, Teams, Weeks, mid, solver)
+        solver.set("timeout", max(1, int(remaining * 1000)))
+        status = solver.check()
+
+        if status == sat:
+            result["obj"] = mid
+            result["schedule"] = _parse_schedule(solver.model(), home, per, Teams, Weeks, Periods)
+            with _best_obj.get_lock():
+                _best_obj.value = min(_best_obj.value, mid)
+            upper = mid - 1
+        elif status == unsat:
+            lower = mid + 1
+        else:
+            result["complete"] = False
+            solver.pop()
+            break
+
+        solver.pop()
+
+    return result
+
+
+def solve_cubes(n_teams, use_sb=False, use_optimization=False, timeout=300, options=None):
+    """
+    Cube-and-conquer: solves the cubes produced by generate_cubes in parallel workers
+    and merges the best objective found.
+
+    Params:
+        n_teams: Number of teams
+        use_sb: Whether to use symmetry breaking
+        use_optimization: Whether to minimize the max imbalance
+        timeout: Time limit in seconds
+        options: Dictionary of solver options; "cube_depth" and "workers" control the split
+    Returns:
+        dict: Structured result, with the merged schedule under "schedule"
+    """
+    options = options or {}
+    start_time = time.time()
+    deadline = start_time + timeout
+
+    depth = options.get("cube_depth") or 2
+    workers = options.get("workers") or os.cpu_count()
+    cubes = generate_cubes(n_teams, depth, use_sb)
+    solver_params = {}
+    # Computed once here: the workers only search above it
+    lower_bound = initial_lower_bound(n_teams, options, solver_params) if use_optimization else 1
+    tasks = [(n_teams, use_sb, use_optimization, cube, deadline, options, lower_bound) for cube in cubes]
+
+    print(f"Solving {len(cubes)} cubes (depth {depth}) with {workers} workers")
+
+    # spawn: Z3 contexts must not be shared with forked processes
+    ctx = mp.get_context("spawn")
+    best_obj = ctx.Value("i", n_teams)
+    best_result = None
+    finished = []
+
+    with ctx.Pool(workers, initializer=_init_worker, initargs=(best_obj,)) as pool:
+        try:
+            for res in pool.imap_unordered(_solve_cube, tasks):
+                finished.append(res)
+                if res["schedule"] is not None:
+                    if best_result is None or (use_optimization and res["obj"] < best_result["obj"]):
+                        best_result = res
+                        if use_optimization:
+                            record_improvement(solver_params, start_time, res["obj"])
+                # a feasible schedule (satisfaction) or a max imbalance at the lower bound ends the search
+                if best_result is not None and (not use_optimization or best_result["obj"] <= lower_bound):
+                    break
+                if time.time() >= deadline:
+                    break
+        except KeyboardInterrupt:
+            pass
+        pool.terminate()
+
+    elapsed = time.time() - start_time
+    complete = len(finished) == len(tasks) and all(r["complete"] for r in finished)
+
+    return {
+        "status": sat if best_result else (unsat if complete else unknown),
+        "time": elapsed,
+        "schedule": best_result["schedule"] if best_result else None,
+        "weeks": list(range(n_teams - 1)),
+        "periods": list(range(n_teams // 2)),
+        "extra_params": {
+            "sb": use_sb,
+            "opt": use_optimization,
+            "teams_list": list(range(n_teams)),
+            "teams": n_teams,
+            "max_diff": best_result["obj"] if best_result else None,
+            # every cube must be closed to prove anything beyond the initial bound
+            "lower_bound": best_result["obj"] if (complete and best_result) else lower_bound,
+            "is_optimal": complete or (best_result is not None and best_result["obj"] <= lower_bound),
+            "solver_params": {
+                **solver_params,
+                "mode": "cube",
+                "cube_depth": depth,
+                "cubes": len(cubes),
+                "cubes_solved": len(finished),
+                "workers": workers,
+            },
+        },
+    }
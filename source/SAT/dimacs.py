@@ -1,5 +1,16 @@
 from itertools import product
 from z3 import *
+from source.results.logs import log_subprocess
+import subprocess
+import tempfile
+import time
+import os
+
+# BreakID executable, used for automatic symmetry breaking on the exported CNF
+BREAKID_PATH = "/usr/local/bin/breakid"
+
+# UNSAT proofs (options["proof"]) are stored here with the CNF they refer to
+DEFAULT_PROOF_DIR = os.path.join(os.getcwd(), "artifacts/SAT")
 
 
 def solver_to_dimacs(solver):
@@ -121,3 +132,174 @@ def get_all_variables_for_dimacs_from_variables_only(home, per, Teams, Weeks, Pe
     except Exception as e:
         print(f"[ERROR] in get_all_variables_for_dimacs_from_variables_only: {e}")
         return None
+
+
+def glucose_arguments(options=None):
+    """
+    Translates the seed and variable decay options into Glucose command line flags.
+    Glucose only implements its own LBD-based restart policy, so a requested
+    restart strategy other than "glucose" is reported and ignored.
+
+    Returns:
+        tuple: (list of flags, dict of effective parameters)
+    """
+    options = options or {}
+    args = []
+
+    seed = options.get("seed")
+    if seed is not None:
+        if seed < 1:
+            # Glucose only accepts positive seeds: the one it runs with is recorded
+            print(f"Warning: Glucose needs a positive seed, using 1 instead of {seed}")
+            seed = 1
+        # rnd-init randomizes the initial activities, otherwise the seed is never used
+        args += [f"-rnd-seed={seed}", "-rnd-init"]
+
+    if options.get("var_decay"):
+        args.append(f"-var-decay={options['var_decay']}")
+
+    restart = options.get("restart")
+    if restart and restart != "glucose":
+        print(f"Warning: restart strategy '{restart}' is not supported by Glucose, using its default")
+
+    params = {
+        "seed": seed,
+        "restart": "glucose",
+        "var_decay": options.get("var_decay"),
+    }
+
+    return args, params
+
+
+def read_learnt_clauses(proof_file, var_map, max_length=8):
+    """
+    Reads the clauses learned by Glucose from its DRUP proof (-certified output)
+    and translates them back to Z3 clauses.
+
+    Only clauses still alive at the end of the run, no longer than max_length and
+    built exclusively on the problem variables (home "h_*" and period "p_*") are kept:
+    auxiliary variables introduced by the PB encoding change between bound queries.
+
+    Params:
+        proof_file: Path of the DRUP proof written by Glucose
+        var_map: { z3.BoolRef : int } mapping produced by solver_to_dimacs
+        max_length: Maximum number of literals of a reused clause
+    Returns:
+        list: Z3 clauses (Or of literals)
+    """
+    id2var = {vid: v for v, vid in var_map.items()
+              if v.decl().name().startswith(("h_", "p_"))}
+
+    alive = set()
+    with open(proof_file, "r") as f:
+        for line in f:
+            tokens = line.split()
+            if not tokens:
+                continue
+            deleted = tokens[0] == "d"
+            lits = tokens[1:-1] if deleted else tokens[:-1]
+            if len(lits) == 0 or len(lits) > max_length:
+                continue
+            try:
+                clause = tuple(sorted(int(l) for l in lits))
+            except ValueError:
+                continue
+            if any(abs(l) not in id2var for l in clause):
+                continue
+            if deleted:
+                alive.discard(clause)
+            else:
+                alive.add(clause)
+
+    clauses = []
+    for clause in alive:
+        lits = [id2var[l] if l > 0 else Not(id2var[-l]) for l in clause]
+        clauses.append(Or(lits) if len(lits) > 1 else lits[0])
+
+    return clauses
+
+
+def apply_breakid(dimacs_str, breakid_path=BREAKID_PATH, timeout=60):
+    """
+    Runs BreakID on a DIMACS formula to detect its symmetries automatically and
+    returns the formula extended with the symmetry breaking clauses it generates.
+
+    Params:
+        dimacs_str: The DIMACS CNF string
+        breakid_path: Path of the BreakID executable
+        timeout: Time limit for the detection, in seconds
+    Returns:
+        tuple: (dimacs string, stats) - the input string is returned unchanged if BreakID fails
+    """
+    stats = {"breakid_clauses": 0, "breakid_time": 0.0}
+    if not breakid_path or not os.path.exists(breakid_path):
+        print(f"Warning: BreakID not found at {breakid_path}, skipping automatic symmetry breaking")
+        return dimacs_str, stats
+
+    cnf_file = None
+    start = time.time()
+    try:
+        with tempfile.NamedTemporaryFile(mode="w", suffix=".cnf", delete=False) as tmp_file:
+            cnf_file = tmp_file.name
+            tmp_file.write(dimacs_str)
+
+        result = subprocess.run([breakid_path, cnf_file, "-t", str(int(timeout))],
+                                capture_output=True, text=True, timeout=timeout + 10)
+        log_subprocess("breakid", result)
+
+        lines = [l for l in result.stdout.splitlines() if l.strip() and not l.startswith("c")]
+        if not lines or not lines[0].startswith("p cnf"):
+            print("Warning: BreakID produced no CNF output, skipping automatic symmetry breaking")
+            return dimacs_str, stats
+
+        stats["breakid_clauses"] = dimacs_clauses(lines[0]) - dimacs_clauses(dimacs_str)
+        return "\n".join(lines) + "\n", stats
+
+    except subprocess.TimeoutExpired:
+        print("Warning: BreakID timed out, skipping automatic symmetry breaking")
+        return dimacs_str, stats
+    finally:
+        stats["breakid_time"] = round(time.time() - start, 3)
+        if cnf_file and os.path.exists(cnf_file):
+            os.unlink(cnf_file)
+
+
+def proof_paths(n_teams, use_sb, use_optimization, bound=None):
+    """
+    Returns the paths of the CNF and of the DRUP proof of a Glucose query under
+    artifacts/SAT, named after the instance, the configuration and the bound.
+
+    Returns:
+        tuple: (cnf path, proof path)
+    """
+    os.makedirs(DEFAULT_PROOF_DIR, exist_ok=True)
+    name = f"{n_teams}_glucose_{'sb' if use_sb else 'nosb'}_{'opt' if use_optimization else 'noopt'}"
+    if bound is not None:
+        name += f"_le{bound}"
+    base = os.path.join(DEFAULT_PROOF_DIR, name)
+    return f"{base}.cnf", f"{base}.drat"
+
+
+def keep_proof(dimacs_str, cnf_path, proof_path, status):
+    """
+    Keeps the proof of an UNSAT query, writing next to it the CNF it refers to
+    (both are needed by drat-trim), and removes the proof of any other answer.
+
+    Returns:
+        dict: {"cnf": path, "proof": path} for UNSAT queries, None otherwise
+    """
+    if status != "unsat":
+        if os.path.exists(proof_path):
+            os.unlink(proof_path)
+        return None
+
+    with open(cnf_path, "w") as cnf_file:
+        cnf_file.write(dimacs_str)
+    return {"cnf": cnf_path, "proof": proof_path}
+
+
+def dimacs_clauses(dimacs_str):
+    """
+    Returns the number of clauses declared in the header of a DIMACS string.
+    """
+    return int(dimacs_str.split("\n", 1)[0].split()[3])
@@ -0,0 +1,141 @@
+from source.SAT.model.sat_model import add_max_diff_constraint
+from source.common.heuristic import greedy_schedule, relabel_for_symmetry_breaking
+from source.common.bounds import initial_lower_bound
+from source.common.trace import record_improvement
+from source.SAT.build_model import build_model
+from z3 import *
+import time
+
+
+def fixed_literals(matches, home, per, fixed_teams):
+    """
+    Returns the literals fixing the heuristic matches (week, period and orientation)
+    of the teams 0..fixed_teams-1, with the team each literal belongs to.
+    """
+    literals = {}
+    for h, a, w, p in matches:
+        owner = min(h, a)
+        if owner < fixed_teams:
+            for lit in (home[h][a][w], per[h][w][p], per[a][w][p]):
+                literals.setdefault(lit.decl().name(), (lit, owner))
+    return list(literals.values())
+
+
+def check_with_unfixing(solver, matches, home, per, state, deadline):
+    """
+    Checks the solver assuming the heuristic assignment of the first state["fixed"] teams.
+    On UNSAT the core is inspected: if it does not involve the fixed literals the answer is
+    a proof, otherwise the teams involved are unfixed and the check is repeated.
+    """
+    while True:
+        remaining = deadline - time.time()
+        if remaining <= 0:
+            return unknown
+
+        literals = fixed_literals(matches, home, per, state["fixed"])
+        owner = {lit.decl().name(): team for lit, team in literals}
+
+        solver.set("timeout", max(1, int(remaining * 1000)))
+        status = solver.check([lit for lit, _ in literals])
+
+        if status != unsat or state["fixed"] == 0:
+            return status
+
+        core = [owner[c.decl().name()] for c in solver.unsat_core() if c.decl().name() in owner]
+        if not core:
+            return unsat
+
+        state["fixed"] = min(core)
+        print(f"  UNSAT with the heuristic assignment, unfixing down to {state['fixed']} teams")
+
+
+def solve_hybrid(n_teams, use_sb=False, use_optimization=False, timeout=300, options=None):
+    """
+    Partial-assignment hybrid: the greedy heuristic schedule of the first teams is fixed
+    through assumptions and SAT only completes (and optimizes) the rest. When a query is
+    UNSAT because of the fixed part, the fixed teams are progressively released.
+
+    Params:
+        n_teams: Number of teams
+        use_sb: Whether to use symmetry breaking
+        use_optimization: Whether to minimize the max imbalance
+        timeout: Time limit in seconds
+        options: Dictionary of solver options; "fixed_teams" sets how many teams are fixed
+                 initially (default: half of them)
+    Returns:
+        dict: Structured result (same format as the Z3 branches of solve_instance)
+    """
+    options = options or {}
+    start_time = time.time()
+    deadline = start_time + timeout
+
+    solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization,
+                                                                  options=options)
+    Teams = list(range(n_teams))
+
+    matches, _ = greedy_schedule(n_teams, time_limit=min(10, timeout / 10))
+    if use_sb:
+        matches = relabel_for_symmetry_breaking(matches, n_teams, team_order=not use_optimization)
+
+    fixed = options.get("fixed_teams")
+    state = {"fixed": n_teams // 2 if fixed is None else fixed}
+    initially_fixed = state["fixed"]
+
+    best_model, best_max_diff, is_optimal, infeasible = None, None, False, False
+    lower_bound = initial_lower_bound(n_teams, options, extra_params["solver_params"]) if use_optimization else 1
+
+    try:
+        if not use_optimization:
+            status = check_with_unfixing(solver, matches, home, per, state, deadline)
+            if status == sat:
+                best_model = solver.model()
+            is_optimal = status == sat
+            infeasible = status == unsat
+        else:
+            upper_bound = n_teams - 1
+            while lower_bound <= upper_bound and time.time() < deadline:
+                mid = (lower_bound + upper_bound) // 2
+                print(f"Testing max_imbalance = {mid} ({state['fixed']} teams fixed)")
+
+                solver.push()
+                add_max_diff_constraint(home, Teams, Weeks, mid, solver)
+                status = check_with_unfixing(solver, matches, home, per, state, deadline)
+
+                if status == sat:
+                    best_model, best_max_diff = solver.model(), mid
+                    record_improvement(extra_params["solver_params"], start_time, mid)
+                    upper_bound = mid - 1
+                elif status == unsat:
+                    lower_bound = mid + 1
+                solver.pop()
+
+                if status == unknown:
+                    break
+
+            is_optimal = best_model is not None and lower_bound > upper_bound
+            # Every bound up to the number of weeks refuted: no schedule at all
+            infeasible = best_model is None and lower_bound > upper_bound
+
+    except KeyboardInterrupt:
+        pass
+
+    return {
+        "status": sat if best_model is not None else (unsat if infeasible else unknown),
+        "time": time.time() - start_time,
+        "model": best_model,
+        "variables": {"home": home, "per": per},
+        "weeks": Weeks,
+        "periods": Periods,
+        "extra_params": {
+            **extra_params,
+            "max_diff": best_max_diff,
+            "lower_bound": lower_bound,
+            "is_optimal": is_optimal,
+            "solver_params": {
+                **extra_params["solver_params"],
+                "mode": "hybrid",
+                "fixed_teams_initial": initially_fixed,
+                "fixed_teams_final": state["fixed"],
+            },
+        },
+    }
@@ -1,6 +1,9 @@
-from source.SAT.build_model import build_model
+from source.SAT.cube_and_conquer import solve_cubes
+from source.SAT.hybrid import solve_hybrid
+from source.SAT.build_model import build_model, check_clause_budget
 from source.SAT.optimization import *
 from source.SAT.dimacs import *
+from source.results.logs import log_subprocess
 import subprocess
 from z3 import *
 import tempfile
@@ -13,20 +16,47 @@ SOLVERS = {
 }
 
 
-def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, path=None):
+def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, path=None, options=None):
     """
     Solves a SAT instance with optional home-away optimization.
+    options carries the solver parameters (seed, restart, var_decay) and the
+    solving mode (cube_depth, workers for cube-and-conquer; hybrid, fixed_teams for
+    the heuristic-fixed hybrid; breakid for automatic symmetry breaking on the CNF).
     Returns a structured result.
     """
     start_time = time.time()
     Teams = list(range(n_teams))
 
+    if (options or {}).get("breakid") and solver_name.lower() != "glucose":
+        raise ValueError("BreakID symmetry breaking is only available with the glucose solver")
+    if (options or {}).get("lexicographic") and solver_name.lower() != "z3":
+        raise ValueError("Lexicographic optimization is only available with the z3 solver")
+    if (options or {}).get("proof") and solver_name.lower() != "glucose":
+        # Z3 proofs refer to its internal variables, not to a CNF that drat-trim could check
+        raise ValueError("DRAT proof logging is only available with the glucose solver")
+
+    # -----------------------------
+    # Cube-and-conquer (parallel Z3)
+    # -----------------------------
+    if (options or {}).get("cube_depth"):
+        if solver_name.lower() != "z3":
+            raise ValueError("Cube-and-conquer mode is only available with the z3 solver")
+        return solve_cubes(n_teams, use_sb, use_optimization, timeout=300, options=options)
+
+    # -----------------------------
+    # Heuristic-fixed hybrid (Z3)
+    # -----------------------------
+    if (options or {}).get("hybrid"):
+        if solver_name.lower() != "z3":
+            raise ValueError("Hybrid mode is only available with the z3 solver")
+        return solve_hybrid(n_teams, use_sb, use_optimization, timeout=300, options=options)
+
     # -----------------------------
     # Optimization + Z3 branch
     # -----------------------------
     if use_optimization and solver_name.lower() == "z3":
-        model, home, per, max_diff, elapsed = optimize_home_away_difference(
-            n_teams, use_sb, timeout=300
+        model, home, per, max_diff, elapsed, solver_params, lower_bound = optimize_home_away_difference(
+            n_teams, use_sb, timeout=300, options=options
         )
         num_weeks, num_periods = n_teams - 1, n_teams // 2
 
@@ -42,7 +72,9 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, p
                 "opt": use_optimization,
                 "teams_list": Teams,
                 "teams": n_teams,
-                "max_diff": max_diff
+                "max_diff": max_diff,
+                "lower_bound": lower_bound,
+                "solver_params": solver_params
             },
         }
 
@@ -53,7 +85,7 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, p
         if path is None:
             raise ValueError("For optimization with Glucose you must provide the executable path")
 
-        result = optimize_home_away_difference_glucose(n_teams, path, use_sb, timeout=300)
+        result = optimize_home_away_difference_glucose(n_teams, path, use_sb, timeout=300, options=options)
 
         return {
             "status": sat if result["dimacs_output"] else unsat,
@@ -66,7 +98,9 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, p
                 "opt": use_optimization,
                 "teams_list": Teams,
                 "teams": n_teams,
-                "max_diff": result["best_max_diff"]
+                "max_diff": result["best_max_diff"],
+                "lower_bound": result["lower_bound"],
+                "solver_params": result["solver_params"]
             },
             "dimacs_output": result["dimacs_output"],
             "variable_mapping": result["variable_mapping"],
@@ -77,22 +111,35 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, p
     # -----------------------------
     else:
         solver, home, per, Weeks, Periods, extra_params = build_model(
-            n_teams, use_sb, use_optimization
+            n_teams, use_sb, use_optimization, options=options, track_groups=solver_name.lower() == "z3"
         )
 
         if solver_name.lower() == "z3":
             return solve_with_z3(solver, home, per, Weeks, Periods, extra_params, start_time)
         else:
-            return solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_params, start_time, solvers_config=SOLVERS,)
+            return solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_params, start_time,
+                                     solvers_config=SOLVERS, options=options)
 
 
 def solve_with_z3(solver, home, per, Weeks, Periods, extra_params, start_time):
     """
     Solve the SAT instance using Z3 solver and return a structured result.
+    The enabled constraint groups (extra_params["groups"]) are checked as assumptions:
+    on UNSAT, the groups in the core are reported.
     """
+    groups = extra_params.pop("groups", {})
     try:
-        status = solver.check()
+        if groups:
+            solver.set("core.minimize", True)
+        status = solver.check(list(groups.values()))
         elapsed_time = time.time() - start_time
+
+        if status == unsat and groups:
+            core = {str(c) for c in solver.unsat_core()}
+            core_groups = [group for group, literal in groups.items() if str(literal) in core]
+            extra_params["solver_params"]["unsat_core"] = core_groups
+            print(f"UNSAT core: {', '.join(core_groups) or 'none (UNSAT without any group)'}")
+
         is_optimal = status == sat
 
         return {
@@ -115,7 +162,8 @@ def solve_with_z3(solver, home, per, Weeks, Periods, extra_params, start_time):
         }
 
 
-def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_params, start_time, solvers_config=None, instance_name=None):
+def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_params, start_time, solvers_config=None,
+                      instance_name=None, options=None):
     """
     Solve using an external DIMACS solver (Glucose) with proper file handling and unique temporary files,
     and return a structured result.
@@ -125,6 +173,7 @@ def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_para
         solvers_config = {}
 
     cnf_file = None
+    proof_file = None
     try:
         # Get solver path
         dimacs_solver_path = solvers_config.get(solver_name)
@@ -132,6 +181,7 @@ def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_para
             raise ValueError(f"DIMACS solver path not provided for: {solver_name}")
 
         Teams = list(range(len(home)))
+        solver_args, extra_params["solver_params"] = glucose_arguments(options)
 
         # 1. Build DIMACS string and mapping
         dimacs_str, var_map = solver_to_dimacs(solver)
@@ -144,19 +194,33 @@ def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_para
                 home, per, Teams, Weeks, Periods, solver
             )
 
+        # Optional automatic symmetry breaking: BreakID extends the CNF with its own clauses
+        if (options or {}).get("breakid"):
+            dimacs_str, breakid_stats = apply_breakid(dimacs_str)
+            extra_params["solver_params"].update(breakid_stats)
+
+        extra_params["solver_params"]["clauses"] = dimacs_clauses(dimacs_str)
+        check_clause_budget(extra_params["solver_params"]["clauses"], options)
+
         # 3. Write DIMACS to temporary file
         with tempfile.NamedTemporaryFile(mode="w", suffix=".cnf", delete=False) as tmp_file:
             cnf_file = tmp_file.name
             tmp_file.write(dimacs_str)
 
+        proof_args = []
+        if (options or {}).get("proof"):
+            proof_cnf, proof_file = proof_paths(len(home), extra_params["sb"], extra_params["opt"])
+            proof_args = ["-certified", f"-certified-output={proof_file}"]
+
         # 4. Execute external solver
         result = subprocess.run(
-            [dimacs_solver_path, "-model", cnf_file],
+            [dimacs_solver_path, "-model", *solver_args, *proof_args, cnf_file],
             capture_output=True,
             text=True,
             timeout=max(1, 300 - (time.time() - start_time)),
         )
         elapsed_time = time.time() - start_time
+        log_subprocess("glucose", result)
 
         # 5. Determine status
         if result.returncode == 10:
@@ -169,6 +233,9 @@ def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_para
             if result.stderr:
                 print(f"Solver stderr: {result.stderr[:200]}...")
 
+        if proof_args:
+            extra_params["solver_params"]["proof"] = keep_proof(dimacs_str, proof_cnf, proof_file, str(status))
+
         # 6. Build result dictionary
         result_dict = {
             "status": status,
@@ -196,6 +263,9 @@ def solve_with_dimacs(solver, home, per, solver_name, Weeks, Periods, extra_para
         return result_dict
 
     except (subprocess.TimeoutExpired, KeyboardInterrupt):
+        # an interrupted proof is incomplete
+        if proof_file and os.path.exists(proof_file):
+            os.unlink(proof_file)
         return {
             "status": unsat,
             "time": 300,
@@ -194,8 +194,114 @@ def add_max_diff_constraint(home, Teams, Weeks, max_diff, s):
             for w in Weeks:
                 home_games.append(home[i][j][w])
         
-        min_home = (total_games - max_diff) // 2
+        # |2 * home - total_games| <= max_diff, rounding min_home up
+        min_home = (total_games - max_diff + 1) // 2
         max_home = (total_games + max_diff) // 2
         
         s.add(at_least_k(home_games, min_home))
-        s.add(at_most_k(home_games, max_home))
\ No newline at end of file
+        s.add(at_most_k(home_games, max_home))
+
+# ----------------------------------
+# TOTALIZER ENCODING OF THE OBJECTIVE
+# ----------------------------------
+
+def totalizer(bool_vars, name, s):
+    """
+    Totalizer (Bailleux & Boufkhad) over bool_vars: returns the sorted unary outputs
+    out[k] (k = 1..len(bool_vars), out[0] unused) with out[k] <-> at least k inputs are true.
+    """
+    if len(bool_vars) == 1:
+        return [None, bool_vars[0]]
+
+    left = totalizer(bool_vars[:len(bool_vars) // 2], f"{name}l", s)
+    right = totalizer(bool_vars[len(bool_vars) // 2:], f"{name}r", s)
+    p, q = len(left) - 1, len(right) - 1
+    out = [None] + [Bool(f"{name}_{k}") for k in range(1, p + q + 1)]
+
+    for i in range(p + 1):
+        for j in range(q + 1):
+            # left >= i and right >= j -> out >= i + j
+            if i + j >= 1:
+                clause = [out[i + j]]
+                if i > 0:
+                    clause.append(Not(left[i]))
+                if j > 0:
+                    clause.append(Not(right[j]))
+                s.add(Or(clause))
+            # left <= i and right <= j -> out <= i + j
+            if i + j < p + q:
+                clause = [Not(out[i + j + 1])]
+                if i < p:
+                    clause.append(left[i + 1])
+                if j < q:
+                    clause.append(right[j + 1])
+                s.add(Or(clause))
+
+    return out
+
+
+def home_count_totalizers(home, Teams, Weeks, s):
+    """
+    Encodes each team's number of home games with a totalizer over its weekly home indicators.
+
+    Returns:
+        dict: {team: out} with out[k] <-> the team plays at least k home games
+    """
+    counts = {}
+
+    for i in Teams:
+        # home_week[w] <-> team i plays at home in week w (at most one match per week)
+        home_week = []
+        for w in Weeks:
+            hw = Bool(f"hw_{i}_{w}")
+            matches = [home[i][j][w] for j in Teams if j != i]
+            s.add(Or([Not(hw)] + matches))
+            for m in matches:
+                s.add(Or(Not(m), hw))
+            home_week.append(hw)
+        counts[i] = totalizer(home_week, f"tot_{i}", s)
+
+    return counts
+
+
+def add_max_diff_totalizer(home, Teams, Weeks, s, counts=None):
+    """
+    Encodes the max imbalance with comparators over the sorted outputs of the
+    home count totalizers (built here unless already given).
+
+    Returns:
+        dict: {max_diff: literal} where each literal implies max imbalance <= max_diff,
+              to be used as an assumption (or unit clause) in the bound queries
+    """
+    total_games = len(Weeks)
+    if counts is None:
+        counts = home_count_totalizers(home, Teams, Weeks, s)
+
+    bounds = {}
+    for max_diff in range(1, total_games + 1):
+        min_home = (total_games - max_diff + 1) // 2
+        max_home = (total_games + max_diff) // 2
+        bound = Bool(f"obj_le_{max_diff}")
+        for i in Teams:
+            if min_home >= 1:
+                s.add(Or(Not(bound), counts[i][min_home]))
+            if max_home < total_games:
+                s.add(Or(Not(bound), Not(counts[i][max_home + 1])))
+        bounds[max_diff] = bound
+
+    return bounds
+
+
+def total_imbalance_deviations(counts, total_games):
+    """
+    Returns the literals over the home count totalizer outputs whose number S gives the
+    total imbalance sum_i |2 * home_i - total_games| = len(counts) + 2 * S: a team with
+    h home games contributes the missing outputs below the balanced count and the true
+    outputs above it.
+    """
+    half = total_games // 2
+    deviations = []
+    for out in counts.values():
+        deviations += [Not(out[k]) for k in range(1, half + 1)]
+        deviations += [out[k] for k in range(half + 2, total_games + 1)]
+    return deviations
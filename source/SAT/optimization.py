@@ -1,6 +1,10 @@
-from source.SAT.model.sat_model import add_max_diff_constraint
+from source.SAT.model.sat_model import add_max_diff_constraint, add_max_diff_totalizer, home_count_totalizers, \
+    total_imbalance_deviations, at_most_k
 from source.SAT.dimacs import solver_to_dimacs
-from .build_model import build_model
+from .build_model import build_model, check_clause_budget
+from source.common.bounds import initial_lower_bound
+from source.common.trace import record_improvement
+from source.results.logs import log_subprocess
 from source.SAT.dimacs import *
 import subprocess
 from z3 import *
@@ -9,62 +13,183 @@ import time
 import os
 
 
-def optimize_home_away_difference(n_teams, use_sb=False, timeout=300):
+def proof_time(queries, best_max_diff):
+    """
+    Returns the time of the UNSAT query proving the optimum (the highest UNSAT bound
+    below it), or None if the optimum was not proven by a query.
+    """
+    proofs = [q for q in queries if q["status"] == "unsat" and best_max_diff is not None
+              and q["bound"] < best_max_diff]
+    return max(proofs, key=lambda q: q["bound"])["time"] if proofs else None
+
+
+def total_imbalance(model, home, Teams, Weeks):
+    """
+    Computes the total imbalance sum_i |2 * home_i - weeks| of a model.
+    """
+    total = 0
+    for i in Teams:
+        home_games = sum(1 for j in Teams if j != i for w in Weeks
+                         if is_true(model.eval(home[i][j][w], model_completion=True)))
+        total += abs(2 * home_games - len(Weeks))
+    return total
+
+
+def optimize_total_imbalance(solver, deviations, fixed_primary, best_model, home, Teams, Weeks, deadline,
+                             queries):
+    """
+    Second phase of the lexicographic optimization: with the max imbalance fixed at its optimum
+    (fixed_primary, a list of assumptions, or already asserted), binary search on the number
+    of deviation literals, i.e. on the total imbalance len(Teams) + 2 * deviations.
+
+    Returns:
+        tuple: (best model, total imbalance, proven lower bound on the total imbalance)
+    """
+    n_teams = len(Teams)
+    best_total = total_imbalance(best_model, home, Teams, Weeks)
+    lower, upper = 0, (best_total - n_teams) // 2 - 1
+
+    while lower <= upper and time.time() < deadline:
+        mid = (lower + upper) // 2
+        print(f"Testing total_imbalance = {n_teams + 2 * mid}")
+
+        solver.set("timeout", max(1, int((deadline - time.time()) * 1000)))
+        solver.push()
+        solver.add(at_most_k(deviations, mid))
+        query_start = time.time()
+        status = solver.check(fixed_primary)
+        queries.append({"objective": "total_imbalance", "bound": n_teams + 2 * mid, "status": str(status),
+                        "time": round(time.time() - query_start, 3)})
+        if status == sat:
+            best_model = solver.model()
+            best_total = total_imbalance(best_model, home, Teams, Weeks)
+        solver.pop()
+
+        if status == sat:
+            upper = (best_total - n_teams) // 2 - 1
+        elif status == unsat:
+            lower = mid + 1
+        else:
+            break
+
+    return best_model, best_total, n_teams + 2 * lower
+
+
+def optimize_home_away_difference(n_teams, use_sb=False, timeout=300, options=None):
     """
     Optimize home-away difference using binary search on max imbalance (Z3).
+
+    options["objective_encoding"] selects how the bound is expressed: "pb" (default) adds
+    the PB cardinality constraints of add_max_diff_constraint in a push/pop scope,
+    "totalizer" builds the sorted-output encoding once and checks each bound as an assumption.
+
+    The search is anytime: on timeout the best model found so far is returned together
+    with the tightest proven lower bound (1 is always valid, the number of weeks being odd).
+
+    With options["lexicographic"], once the max imbalance is proven optimal it is fixed and the
+    total imbalance (sum over the teams) is minimized in the remaining time; the result is
+    stored in solver_params ("total_imbalance", "total_imbalance_bound").
     """
     start_time = time.time()
 
+    home, per, solver_params = None, None, {}
+    best_model, best_max = None, None
+    lower_bound = 1
+
     try:
         # Base model
-        solver, home, per, Weeks, Periods, _ = build_model(n_teams, use_sb, use_optimization=True)
+        solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization=True,
+                                                                      options=options)
+        solver_params = extra_params["solver_params"]
         Teams = list(range(n_teams))
         total_weeks = n_teams - 1
+        lower_bound = initial_lower_bound(n_teams, options, solver_params)
+
+        encoding = (options or {}).get("objective_encoding") or "pb"
+        lexicographic = bool((options or {}).get("lexicographic"))
+        counts = home_count_totalizers(home, Teams, Weeks, solver) if (encoding == "totalizer" or lexicographic) \
+            else None
+        bounds = add_max_diff_totalizer(home, Teams, Weeks, solver, counts) if encoding == "totalizer" else None
+        queries = []
+        solver_params.update({"objective_encoding": encoding, "queries": queries})
 
         # Binary search bounds
-        lower_bound, upper_bound = 1, total_weeks
-        best_model, best_max_diff = None, upper_bound
+        upper_bound = total_weeks
 
         # Binary search loop
         while lower_bound <= upper_bound and (time.time() - start_time) < timeout:
             mid = (lower_bound + upper_bound) // 2
             print(f"Testing max_imbalance = {mid}")
 
-            solver.push()
-            # Add max imbalance constraint
-            add_max_diff_constraint(home, Teams, Weeks, mid, solver)
+            solver.set("timeout", max(1, int((timeout - (time.time() - start_time)) * 1000)))
 
-            status = solver.check()
+            query_start = time.time()
+            if bounds is not None:
+                status = solver.check(bounds[mid])
+            else:
+                solver.push()
+                # Add max imbalance constraint
+                add_max_diff_constraint(home, Teams, Weeks, mid, solver)
+                status = solver.check()
+            queries.append({"bound": mid, "status": str(status), "time": round(time.time() - query_start, 3)})
 
             if status == sat:
                 best_model = solver.model()
                 best_max = mid
+                record_improvement(solver_params, start_time, mid)
+            if bounds is None:
                 solver.pop()
+
+            if status == sat:
                 upper_bound = mid - 1
-                if best_max == 1:
-                    break
-            else:
-                solver.pop()
+            elif status == unsat:
                 lower_bound = mid + 1
+            else:
+                # Timeout inside the query: nothing is proven about this bound
+                break
 
+        solver_params["proof_time"] = proof_time(queries, best_max)
+
+        # Lexicographic second phase, only once the primary optimum is proven
+        if lexicographic and best_model is not None and lower_bound >= best_max:
+            if bounds is not None:
+                fixed_primary = [bounds[best_max]]
+            else:
+                fixed_primary = []
+                add_max_diff_constraint(home, Teams, Weeks, best_max, solver)
+            best_model, total, total_bound = optimize_total_imbalance(
+                solver, total_imbalance_deviations(counts, total_weeks), fixed_primary, best_model,
+                home, Teams, Weeks, start_time + timeout, queries
+            )
+            solver_params.update({"total_imbalance": total, "total_imbalance_bound": min(total_bound, total)})
 
         elapsed = time.time() - start_time
 
         # Timeout with no solution
         if (elapsed >= timeout and best_model is None):
-            return None, None, None, None, timeout
+            return None, None, None, None, timeout, solver_params, lower_bound
 
         # Solution found or timeout with partial solution
-        return best_model, home, per, best_max, elapsed
+        return best_model, home, per, best_max, elapsed, solver_params, lower_bound
 
 
     except KeyboardInterrupt:
-        return best_model, home, per, best_max, timeout
+        return best_model, home, per, best_max, timeout, solver_params, lower_bound
 
 
-def optimize_home_away_difference_glucose(n_teams, glucose_path, use_sb=False, timeout=300):
+def optimize_home_away_difference_glucose(n_teams, glucose_path, use_sb=False, timeout=300, options=None):
     """
     Optimize home-away difference using binary search on max imbalance (Glucose).
+
+    With options["reuse_learnts"], the clauses learned in each bound query are read back
+    from the Glucose proof and added to every following query with a tighter (or equal)
+    bound, where they are still implied by the formula.
+
+    With options["breakid"], every query CNF is extended with the symmetry breaking
+    clauses detected by BreakID before being passed to Glucose.
+
+    With options["proof"], the DRUP proof of every UNSAT query is stored under artifacts/SAT
+    with its CNF, so that the lower bound can be verified independently (drat-trim).
     """
     start_time = time.time()
     Teams = list(range(n_teams))
@@ -72,13 +197,29 @@ def optimize_home_away_difference_glucose(n_teams, glucose_path, use_sb=False, t
     num_weeks, num_periods = total_weeks, n_teams // 2
     Weeks, Periods = list(range(num_weeks)), list(range(num_periods))
 
-    lower, upper = 1, total_weeks
-    best_max_diff = upper
+    best_max_diff = None
     best_dimacs_output = None
     best_variable_mapping = None
+    solver_args, solver_params = glucose_arguments(options)
+    lower, upper = initial_lower_bound(n_teams, options, solver_params), total_weeks
+    reuse_learnts = bool((options or {}).get("reuse_learnts"))
+    use_breakid = bool((options or {}).get("breakid"))
+    keep_proofs = bool((options or {}).get("proof"))
+    if reuse_learnts and use_breakid:
+        # clauses learned under one set of symmetry breaking clauses are not implied by the next query
+        raise ValueError("Learnt clause reuse cannot be combined with BreakID symmetry breaking")
+    breakid_clauses, breakid_time = 0, 0.0
+
+    # learnt_pool[bound] = clauses learned while testing max_imbalance = bound
+    learnt_pool = {}
+    reused_clauses = 0
 
     # 1. Build base model without max_diff constraint
-    base_solver, home, per, _, _, _ = build_model(n_teams, use_sb, use_optimization=True)
+    base_solver, home, per, _, _, _ = build_model(n_teams, use_sb, use_optimization=True, options=options)
+
+    encoding = (options or {}).get("objective_encoding") or "pb"
+    bounds = add_max_diff_totalizer(home, Teams, Weeks, base_solver) if encoding == "totalizer" else None
+    queries = []
 
     try:
         while lower <= upper and (time.time() - start_time) < timeout:
@@ -92,7 +233,16 @@ def optimize_home_away_difference_glucose(n_teams, glucose_path, use_sb=False, t
                 temp_solver.add(assertion)
 
             # 3. Add max_diff constraint
-            add_max_diff_constraint(home, Teams, Weeks, mid, temp_solver)
+            if bounds is not None:
+                temp_solver.add(bounds[mid])
+            else:
+                add_max_diff_constraint(home, Teams, Weeks, mid, temp_solver)
+
+            # Clauses learned at a looser bound b >= mid are implied by the current query
+            if reuse_learnts:
+                valid_learnts = [c for b, clauses in learnt_pool.items() if b >= mid for c in clauses]
+                temp_solver.add(valid_learnts)
+                reused_clauses += len(valid_learnts)
 
             # 4. Convert to DIMACS
             temp_dimacs, var_map = solver_to_dimacs(temp_solver)
@@ -100,37 +250,69 @@ def optimize_home_away_difference_glucose(n_teams, glucose_path, use_sb=False, t
             # 5. Build mapping from DIMACS
             current_mapping = build_variable_mapping(home, per, var_map, Teams, Weeks, Periods)
 
+            if use_breakid:
+                temp_dimacs, breakid_stats = apply_breakid(temp_dimacs)
+                breakid_clauses += breakid_stats["breakid_clauses"]
+                breakid_time += breakid_stats["breakid_time"]
+
+            check_clause_budget(dimacs_clauses(temp_dimacs), options)
+
             # 6. Write CNF to temp file
             cnf_file = None
+            proof_file = None
             try:
                 with tempfile.NamedTemporaryFile(mode="w+", suffix=".cnf", delete=False) as tmpfile:
                     cnf_file = tmpfile.name
                     tmpfile.write(temp_dimacs)
 
+                proof_args = []
+                if keep_proofs:
+                    proof_cnf, proof_file = proof_paths(n_teams, use_sb, True, mid)
+                elif reuse_learnts:
+                    with tempfile.NamedTemporaryFile(suffix=".drup", delete=False) as tmpproof:
+                        proof_file = tmpproof.name
+                if proof_file:
+                    proof_args = ["-certified", f"-certified-output={proof_file}"]
+
                 # 7. Run Glucose
+                query_start = time.time()
                 result = subprocess.run(
-                    [glucose_path, "-model", cnf_file],
+                    [glucose_path, "-model", *solver_args, *proof_args, cnf_file],
                     capture_output=True,
                     text=True,
                     timeout=max(1, timeout - (time.time() - start_time)),
                 )
+                log_subprocess(f"glucose (max_imbalance <= {mid})", result)
+
+                status = {10: "sat", 20: "unsat"}.get(result.returncode, "unknown")
+                queries.append({"bound": mid, "status": status, "time": round(time.time() - query_start, 3)})
+
+                if reuse_learnts:
+                    learnt_pool[mid] = read_learnt_clauses(proof_file, var_map)
+
+                if keep_proofs:
+                    queries[-1]["proof"] = keep_proof(temp_dimacs, proof_cnf, proof_file, status)
+                    proof_file = None
 
                 if result.returncode == 10:  # SAT
                     best_max_diff = mid
+                    record_improvement(solver_params, start_time, mid)
                     best_dimacs_output = result.stdout
                     best_variable_mapping = current_mapping
                     upper = mid - 1
                 elif result.returncode == 20:  # UNSAT
                     lower = mid + 1
-                else:  # Unknown return code
-                    lower = mid + 1
+                else:  # Unknown return code: nothing is proven about this bound
+                    print(f"Unexpected return code: {result.returncode}")
+                    break
 
             finally:
-                if cnf_file and os.path.exists(cnf_file):
-                    try:
-                        os.unlink(cnf_file)
-                    except Exception as cleanup_error:
-                        print(f"Warning: Could not remove temp file {cnf_file}: {cleanup_error}")
+                for tmp in (cnf_file, proof_file):
+                    if tmp and os.path.exists(tmp):
+                        try:
+                            os.unlink(tmp)
+                        except Exception as cleanup_error:
+                            print(f"Warning: Could not remove temp file {tmp}: {cleanup_error}")
 
     except (subprocess.TimeoutExpired, KeyboardInterrupt):
         # always return the best model found
@@ -141,9 +323,15 @@ def optimize_home_away_difference_glucose(n_teams, glucose_path, use_sb=False, t
     return {
         "dimacs_output": best_dimacs_output,
         "best_max_diff": best_max_diff,
+        "lower_bound": lower,
         "time": elapsed_time,
         "Weeks": Weeks,
         "Periods": Periods,
         "Teams": Teams,
         "variable_mapping": best_variable_mapping,
+        "solver_params": {**solver_params, "reuse_learnts": reuse_learnts, "reused_clauses": reused_clauses,
+                          "objective_encoding": encoding, "queries": queries,
+                          "proof_time": proof_time(queries, best_max_diff),
+                          **({"breakid_clauses": breakid_clauses, "breakid_time": round(breakid_time, 3)}
+                             if use_breakid else {})},
     }
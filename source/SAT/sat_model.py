@@ -1,5 +1,9 @@
 from source.SAT.instance_solver import solve_instance
+from source.SAT.build_model import EncodingTooLarge
 from source.SAT import sat_utils as utils
+from source.common.metadata import run_metadata
+from source.results.logs import open_log, close_log
+from source.common.trace import pop_trace
 import os.path as pt
 from z3 import *
 import os, time
@@ -16,7 +20,7 @@ SOLVERS = {
 }
 
 
-def sat_solver(n_teams, solver_name, use_sb=False, use_optimization=False):
+def sat_solver(n_teams, solver_name, use_sb=False, use_optimization=False, options=None):
     """
     Solves the SAT model using Z3.
     
@@ -25,6 +29,7 @@ def sat_solver(n_teams, solver_name, use_sb=False, use_optimization=False):
         solver_name: The solver name
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization
+        options: Dictionary of solver options (seed, restart, var_decay)
     
     Returns:
         dict: Result object containing solution and statistics
@@ -32,12 +37,12 @@ def sat_solver(n_teams, solver_name, use_sb=False, use_optimization=False):
     path = SOLVERS[solver_name] if solver_name.lower() == "glucose" else None
 
     # Solve the instance
-    result = solve_instance(n_teams, solver_name, use_sb, use_optimization, path)
+    result = solve_instance(n_teams, solver_name, use_sb, use_optimization, path, options)
 
     return result
 
 
-def run_model(results_dict, n, solver, sb=False, opt=False):
+def run_model(results_dict, n, solver, sb=False, opt=False, options=None):
     """
     Runs the SAT model with the given parameters and updates the results dictionary.
     Params:
@@ -46,10 +51,12 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
         solver: Solver to use
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization
+        options: Dictionary of solver options (seed, restart, var_decay)
     Returns:
         results_dict: Updated dictionary with results for the given configuration
     """
-    key = utils.make_key(solver, sb, opt)
+    key = utils.make_key(solver, sb, opt, "breakid" if (options or {}).get("breakid") else None)
+    log = open_log("SAT", n, key)
 
     try:
         print(
@@ -60,7 +67,7 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
             f"\n  - optimization = {opt}"
         )
 
-        result = sat_solver(n, solver, sb, opt)
+        result = sat_solver(n, solver, sb, opt, options)
 
         elapsed_time, optimal, solution, obj = utils.process_result(result, opt)
 
@@ -70,7 +77,26 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
             "sol": solution,
             "time": elapsed_time,
             "optimal": optimal,
-            "obj": obj
+            "obj": obj,
+            # Tightest proven lower bound on the max imbalance (anytime result on timeout)
+            "bound": result.get("extra_params", {}).get("lower_bound") if opt else None,
+            "params": result.get("extra_params", {}).get("solver_params")
+        }
+        results_dict[key]["trace"] = pop_trace(results_dict[key]["params"])
+        # Secondary objective of the lexicographic optimization
+        total = (results_dict[key]["params"] or {}).get("total_imbalance")
+        if total is not None:
+            results_dict[key]["total_imbalance"] = total
+
+    except EncodingTooLarge as e:
+        print(f"Skipping {key} for n={n}: {e}")
+        results_dict[key] = {
+            "sol": [],
+            "time": 300,
+            "optimal": False,
+            "obj": None,
+            "outcome": "encoding too large",
+            "clauses": e.clauses
         }
 
     except Exception as e:
@@ -82,10 +108,18 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
             "obj": None
         }
 
+    close_log()
+    options = options or {}
+    results_dict[key]["metadata"] = run_metadata(
+        "SAT", solver, get_version_string() if solver.lower() == "z3" else None,
+        variant=options.get("objective_encoding"),
+        seed=(results_dict[key].get("params") or {}).get("seed", options.get("seed")),
+        threads=options.get("workers") if options.get("cube_depth") else None, log=log)
+
     return results_dict
 
 
-def run_single_instance(n, solver, use_sb=False, use_optimization=False):
+def run_single_instance(n, solver, use_sb=False, use_optimization=False, options=None):
     """
     Runs a single instance of the SAT model with the given parameters.
 
@@ -94,6 +128,7 @@ def run_single_instance(n, solver, use_sb=False, use_optimization=False):
         solver: The solver to use
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
+        options: Dictionary of solver options (seed, restart, var_decay)
     """
 
     if solver is None:
@@ -104,17 +139,22 @@ def run_single_instance(n, solver, use_sb=False, use_optimization=False):
 
     results_dict = {}
 
-    results_dict = run_model(results_dict, n, solver, use_sb, use_optimization)
+    results_dict = run_model(results_dict, n, solver, use_sb, use_optimization, options)
 
     utils.write_solution(output_dir, n, results_dict)
 
 
-def run_all():
+def run_all(options=None):
     """
     Runs all configurations for the SAT model.
+
+    Params:
+        options: Dictionary of solver options (seed, restart, var_decay)
     """
 
-    solvers = ["z3", "glucose"]
+    # BreakID and proof logging work on the exported CNF, only the DIMACS pipeline supports them
+    dimacs_only = (options or {}).get("breakid") or (options or {}).get("proof")
+    solvers = ["glucose"] if dimacs_only else ["z3", "glucose"]
     instances = [6, 8, 10, 12, 14, 16, 18]
     output_dir = DEFAULT_SAT_OUTPUT_DIR
     os.makedirs(output_dir, exist_ok=True)
@@ -125,7 +165,7 @@ def run_all():
         for solver in solvers:
             for sb in [False, True]:
                 for opt in [False, True]:
-                    results_dict = run_model(results_dict, n, solver, sb, opt)
+                    results_dict = run_model(results_dict, n, solver, sb, opt, options)
 
                     gc.collect()
 
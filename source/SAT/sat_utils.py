@@ -1,8 +1,7 @@
 from itertools import combinations
 from z3 import *
-import os
-import json
 import math
+from source.results.writer import write_solution
 
 
 def print_solution(time, optimal, solution, obj):
@@ -51,6 +50,10 @@ def parse_solution(result):
     if result["status"] != sat:
         return []
 
+    # ----- Already decoded schedule (e.g. cube-and-conquer workers) -----
+    if result.get("schedule") is not None:
+        return result["schedule"]
+
     Teams = result["extra_params"]["teams_list"]
     Weeks = result["weeks"]
     Periods = result["periods"]
@@ -135,7 +138,7 @@ def parse_solution(result):
 
 
 
-def make_key(solver_name, sb, opt):
+def make_key(solver_name, sb, opt, variant=None):
     """
     Creates a unique key for the solver configuration.
 
@@ -143,6 +146,7 @@ def make_key(solver_name, sb, opt):
         solver_name: String name of the solver.
         sb: Boolean indicating if symmetry breaking is used.
         opt: Boolean indicating if optimization is used.
+        variant: Optional suffix distinguishing a non-default pipeline (e.g. "breakid").
 
     Returns:
         A string key representing the solver configuration.
@@ -154,35 +158,12 @@ def make_key(solver_name, sb, opt):
         "sb" if sb else "nosb",
         "opt" if opt else "noopt"
     ]
+    if variant:
+        parts.append(variant)
 
     return "_".join(parts)
 
 
-def write_solution(output_dir, n, results_dict):
-    """
-    Writes the results to a JSON file.
-    Params:
-        output_dir: The directory where the results will be saved.
-        n: An identifier for the results (number of teams).
-        results_dict: A dictionary containing the results to be written.
-    """
-
-    out_path = os.path.join(output_dir, f"{n}.json")
-    with open(out_path, 'w') as f:
-        f.write('{\n')
-        for i, (key, val) in enumerate(results_dict.items()):
-            f.write(f'  "{key}": {{\n')
-
-            sol_str = json.dumps(val["sol"], separators=(',', ':'))
-            f.write(f'    "time": {val["time"]},\n')
-            f.write(f'    "optimal": {"true" if val["optimal"] else "false"},\n')
-            f.write(f'    "obj": {json.dumps(val["obj"])},\n')
-            f.write(f'    "sol": {sol_str}\n')
-
-            f.write('  }' + (',' if i < len(results_dict) - 1 else '') + '\n')
-        f.write('}\n')
-
-
 def process_result(result, use_optimization):
     """
     Processes the result from a SAT solver.
@@ -199,13 +180,16 @@ def process_result(result, use_optimization):
     if use_optimization and has_solution:
         max_diff = result["extra_params"].get("max_diff")
         obj = max_diff
-        is_optimal = (obj == 1)
+        # Optimal when the best solution meets the proven lower bound
+        lower_bound = result["extra_params"].get("lower_bound") or 1
+        is_optimal = obj is not None and obj <= lower_bound
     else:
         is_optimal = has_solution
         obj = None
 
-    if time_val >= 300:
+    if time_val >= 300 or not is_optimal:
         is_optimal = False
+        time_val = 300
 
     
     return time_val, is_optimal, solution, obj
\ No newline at end of file
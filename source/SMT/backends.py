@@ -0,0 +1,70 @@
+from source.results.logs import log_subprocess
+import subprocess
+import tempfile
+import os
+
+
+class SmtLibBackend:
+    """
+    External SMT solver run as a subprocess on an SMT-LIB2 script.
+
+    Params:
+        name: Solver name, as given with --solver
+        path: Path of the executable
+        args: Command line flags passed before the script
+        version_flag: Flag printing the solver version
+        omt: Whether the solver supports the OMT (minimize ...) command
+        timeout_flag: Optional flag template for the solver's own time limit in milliseconds
+    """
+
+    def __init__(self, name, path, args=(), version_flag="--version", omt=False, timeout_flag=None):
+        self.name = name
+        self.path = path
+        self.args = list(args)
+        self.version_flag = version_flag
+        self.omt = omt
+        self.timeout_flag = timeout_flag
+
+    def available(self):
+        return os.path.exists(self.path)
+
+    def version(self):
+        """
+        Returns the first line printed by the solver with its version flag, or None.
+        """
+        try:
+            result = subprocess.run([self.path, self.version_flag], capture_output=True, text=True, timeout=10)
+            return (result.stdout or result.stderr).strip().splitlines()[0]
+        except (OSError, IndexError, subprocess.TimeoutExpired):
+            return None
+
+    def run(self, script, timeout):
+        """
+        Runs the solver on a script.
+
+        Returns:
+            str: The solver output, or None if the time limit was reached
+        """
+        script_file = None
+        try:
+            with tempfile.NamedTemporaryFile(mode="w", suffix=".smt2", delete=False) as tmp_file:
+                script_file = tmp_file.name
+                tmp_file.write(script)
+
+            limit = [self.timeout_flag.format(ms=int(timeout * 1000))] if self.timeout_flag else []
+            result = subprocess.run([self.path, *self.args, *limit, script_file], capture_output=True, text=True,
+                                    timeout=max(1, timeout) + 5)
+            log_subprocess(self.name, result)
+            return result.stdout
+
+        except subprocess.TimeoutExpired:
+            return None
+        finally:
+            if script_file and os.path.exists(script_file):
+                os.unlink(script_file)
+
+
+BACKENDS = {
+    "optimathsat": SmtLibBackend("optimathsat", "/usr/local/bin/optimathsat", version_flag="-version", omt=True),
+    "cvc5": SmtLibBackend("cvc5", "/usr/local/bin/cvc5", args=["--lang=smt2"], timeout_flag="--tlimit={ms}"),
+}
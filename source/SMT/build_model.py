@@ -1,8 +1,330 @@
-from source.SMT.model import smt_model
+from source.SMT.model import smt_model, smt_idl_model, smt_bv_model, smt_array_model, smt_pb_model, smt_uf_model
+from source.SMT.model.smt_model import tagged
+from source.SMT.z3_compat import set_global_param
 from z3 import *
+import time
+import os
 
+# Encoding variants, selected with --model smt_<variant>: every module exposes the
+# same create_variables / add_*_constraint(s) interface as the LIA model
+VARIANTS = {
+    "lia": smt_model,
+    "idl": smt_idl_model,
+    "bv": smt_bv_model,
+    "array": smt_array_model,
+    "pb": smt_pb_model,
+    "uf": smt_uf_model,
+}
 
-def build_model(n_teams, use_sb=False, use_optimization=False):
+
+# Default seed of the Z3 SMT solver, overridden by the random_seed parameter
+DEFAULT_SEED = 42
+
+# Readable descriptions of the constraint functions of the variants, used for UNSAT cores
+DESCRIPTIONS = {
+    "constraint_each_pair_once": "every pair of teams meets exactly once",
+    "constraint_one_match_per_week": "every team plays once a week",
+    "constraint_max_two_per_period": "every team plays at most twice in the same period",
+    "add_domain_constrain": "periods (and slots) range over their domain",
+    "constraint_period_consistency": "opponents play in the same period",
+    "constraint_array_channeling": "home/period variables follow the week arrays",
+    "constraint_two_teams_per_period": "every period hosts exactly one match per week",
+    "constraint_period_indicators": "every team is in exactly one period per week",
+    "constraint_match_consistency": "opponents share the period, exactly one of them at home",
+    "constrain_home_symmetry": "a pair cannot host each other in the same week",
+    "constraint_one_match_per_team": "every team is in exactly one match per week",
+    "add_sb1": "symmetry breaking: team 0 hosts team 1 in period 0 of week 0",
+    "add_sb2": "symmetry breaking: team 0 plays team w+1 in week w",
+    "add_team_order_constraint": "symmetry breaking: the lower index plays at home",
+    "add_period_order_constraint": "symmetry breaking: periods ordered by their lowest team in week 0",
+    "add_max_diff_constraint": "every team's home/away imbalance is within the bound",
+    "totalizer": "home game counter (totalizer) of the imbalance bound",
+}
+
+
+class TrackingSolver:
+    """
+    Solver wrapper naming every added assertion with its own tracker literal, so that an
+    UNSAT core can be mapped back to the constraint (group, function and bound) it comes from.
+
+    Params:
+        solver: The Z3 solver the assertions are added to
+        trackers: Dictionary tracker name -> description, shared by all groups
+        group: Name of the constraint group ("hard", "channeling", "implied", "symmetry", "bound")
+        bound: Optional objective bound the assertions belong to
+        constraint: Constraint function the assertions come from (a key of DESCRIPTIONS), set by
+                    the model through tagged()
+    """
+
+    def __init__(self, solver, trackers, group, bound=None, constraint=None):
+        self.solver = solver
+        self.trackers = trackers
+        self.group = group
+        self.bound = bound
+        self.constraint = constraint
+
+    def tagged(self, constraint):
+        return TrackingSolver(self.solver, self.trackers, self.group, self.bound, constraint)
+
+    def add(self, *constraints):
+        for constraint in constraints:
+            for c in (constraint if isinstance(constraint, (list, tuple)) else [constraint]):
+                name = f"track_{len(self.trackers)}"
+                self.trackers[name] = {"group": self.group, "constraint": self.constraint, "bound": self.bound}
+                self.solver.assert_and_track(c, Bool(name))
+
+
+class FilteringSolver:
+    """
+    Solver wrapper dropping the assertions added by the given constraint functions (as tagged
+    by the model, see tagged()), used to leave a constraint out of the initial model when it is
+    added lazily.
+    """
+
+    def __init__(self, solver, skip, constraint=None):
+        self.solver = solver
+        self.skip = set(skip)
+        self.constraint = constraint
+
+    def tagged(self, constraint):
+        return FilteringSolver(tagged(self.solver, constraint), self.skip, constraint)
+
+    def add(self, *constraints):
+        if self.constraint not in self.skip:
+            self.solver.add(*constraints)
+
+
+def describe_core(core, trackers):
+    """
+    Maps an UNSAT core of tracker literals to readable constraint descriptions.
+
+    Returns:
+        list: One entry per constraint function in the core, with the number of its assertions
+    """
+    described = {}
+    for literal in core:
+        info = trackers.get(str(literal))
+        if info is None:
+            continue
+        key = (info["group"], info["constraint"], info["bound"])
+        if key not in described:
+            description = DESCRIPTIONS.get(info["constraint"], info["constraint"])
+            if info["bound"] is not None:
+                description += f" (max imbalance <= {info['bound']})"
+            described[key] = {"group": info["group"], "constraint": info["constraint"],
+                              "description": description, "assertions": 0}
+        described[key]["assertions"] += 1
+    return list(described.values())
+
+
+ARITH_OPS = (Z3_OP_LE, Z3_OP_GE, Z3_OP_LT, Z3_OP_GT)
+BV_OPS = (Z3_OP_ULEQ, Z3_OP_UGEQ, Z3_OP_ULT, Z3_OP_UGT, Z3_OP_SLEQ, Z3_OP_SGEQ, Z3_OP_SLT, Z3_OP_SGT)
+PB_OPS = (Z3_OP_PB_LE, Z3_OP_PB_GE, Z3_OP_PB_EQ, Z3_OP_PB_AT_MOST, Z3_OP_PB_AT_LEAST)
+
+
+def encoding_stats(assertions):
+    """
+    Counts the size of an SMT problem: assertions, declared variables per sort, distinct
+    arithmetic / bitvector / pseudo-Boolean atoms, and the theories it mixes.
+
+    Returns:
+        dict: The statistics, stored in the solver parameters of the result
+    """
+    assertions = list(assertions)
+    variables = {"bool": 0, "int": 0, "bv": 0, "array": 0}
+    atoms = {"arith": 0, "bv": 0, "pb": 0}
+    theories = set()
+    seen, stack = set(), list(assertions)
+
+    while stack:
+        e = stack.pop()
+        if e.get_id() in seen:
+            continue
+        seen.add(e.get_id())
+        if not is_app(e):
+            continue
+
+        if is_const(e) and e.decl().kind() == Z3_OP_UNINTERPRETED:
+            for sort, check in (("bool", is_bool), ("int", is_int), ("bv", is_bv), ("array", is_array)):
+                if check(e):
+                    variables[sort] += 1
+        elif is_app_of(e, Z3_OP_EQ) and (is_arith(e.arg(0)) or is_bv(e.arg(0))):
+            atoms["arith" if is_arith(e.arg(0)) else "bv"] += 1
+        elif any(is_app_of(e, op) for op in ARITH_OPS):
+            atoms["arith"] += 1
+        elif any(is_app_of(e, op) for op in BV_OPS):
+            atoms["bv"] += 1
+        elif any(is_app_of(e, op) for op in PB_OPS):
+            atoms["pb"] += 1
+
+        if is_arith(e):
+            theories.add("arith")
+        elif is_bv(e):
+            theories.add("bv")
+        elif is_array(e):
+            theories.add("arrays")
+        if any(is_app_of(e, op) for op in PB_OPS):
+            theories.add("pb")
+        stack.extend(e.children())
+
+    return {
+        "assertions": len(assertions),
+        "variables": variables,
+        "atoms": atoms,
+        "theories": sorted(theories),
+    }
+
+
+def get_variant(options=None):
+    """
+    Returns the model module of the variant selected by options["variant"] (default "lia").
+    """
+    name = (options or {}).get("variant") or "lia"
+    if name not in VARIANTS:
+        raise ValueError(f"Unknown SMT variant: {name}")
+    return VARIANTS[name]
+
+
+def parse_param(value):
+    """
+    Converts a parameter value given on the command line to bool, int or float when possible.
+    """
+    if value.lower() in ("true", "false"):
+        return value.lower() == "true"
+    for cast in (int, float):
+        try:
+            return cast(value)
+        except ValueError:
+            pass
+    return value
+
+
+def parse_params(pairs):
+    """
+    Parses a list of "key=value" strings into a dictionary of Z3 parameters.
+    """
+    params = {}
+    for pair in pairs or []:
+        key, sep, value = pair.partition("=")
+        if not sep or not key.strip():
+            raise ValueError(f"Invalid Z3 parameter (expected key=value): {pair}")
+        params[key.strip()] = parse_param(value.strip())
+    return params
+
+
+def parse_tactic(spec):
+    """
+    Builds a Z3 tactic from a pipeline such as "simplify; solve-eqs; smt": the tactics are
+    applied in sequence, and each one can take its own parameters, e.g. "smt(arith.solver=2)".
+    """
+    tactics = []
+    for step in [s.strip() for s in spec.split(";") if s.strip()]:
+        name, _, args = step.partition("(")
+        tactic = Tactic(name.strip())
+        if args:
+            params = parse_params([a for a in args.rstrip(")").split(",") if a.strip()])
+            tactic = With(tactic, **params)
+        tactics.append(tactic)
+    if not tactics:
+        raise ValueError(f"Empty Z3 tactic pipeline: {spec}")
+    return tactics[0] if len(tactics) == 1 else Then(*tactics)
+
+
+def solver_config(options=None):
+    """
+    Returns the tactic pipeline and Z3 parameters selected by options["tactic"],
+    options["z3_config"] (a dictionary, e.g. loaded from a JSON file) and options["z3_params"]
+    (key=value strings, overriding the dictionary), as stored in the solver parameters of the result.
+    """
+    config = {}
+    if (options or {}).get("tactic"):
+        config["tactic"] = options["tactic"]
+    params = {**((options or {}).get("z3_config") or {}), **parse_params((options or {}).get("z3_params"))}
+    if params:
+        config["z3_params"] = params
+    return config
+
+
+def apply_params(solver, params):
+    """
+    Sets Z3 parameters (random_seed, restart.*, smt.arith.*, relevancy, ...) on a solver or
+    optimizer; the ones it does not accept are set as global parameters.
+
+    Returns:
+        list: The names of the parameters set globally (they also hold for the following runs)
+    """
+    global_params = []
+    for key, value in params.items():
+        try:
+            solver.set(key, value)
+        except Z3Exception:
+            if set_global_param(key, value):
+                global_params.append(key)
+    return global_params
+
+
+def thread_count(options=None):
+    """
+    Number of Z3 threads of a run: options["threads"] capped by the available cores.
+    """
+    return min((options or {}).get("threads") or 1, os.cpu_count() or 1)
+
+
+def apply_threads(options=None):
+    """
+    Enables Z3's parallel mode with options["threads"] threads (capped by the available cores),
+    or disables it, since the global parameters would otherwise leak into the following runs.
+
+    Returns:
+        int: The number of threads of the run
+    """
+    threads = thread_count(options)
+    set_global_param("parallel.enable", threads > 1)
+    if threads > 1:
+        set_global_param("parallel.threads.max", threads)
+    return threads
+
+
+def make_solver(model, options=None):
+    """
+    Creates the Z3 solver of a variant: from the tactic pipeline of options["tactic"] if given,
+    otherwise the dedicated solver of the variant logic (if any) or the default one.
+    """
+    config = solver_config(options)
+    if "tactic" in config:
+        solver = parse_tactic(config["tactic"]).solver()
+    elif hasattr(model, "LOGIC"):
+        # Variants restricted to a logic fragment declare it, so that Z3 picks the dedicated solver
+        solver = SolverFor(model.LOGIC)
+    else:
+        solver = Solver()
+
+    apply_threads(options)
+    solver.set("random_seed", DEFAULT_SEED)
+    solver.set("timeout", 300_000)  # 5 minutes timeout
+    apply_params(solver, config.get("z3_params", {}))
+    return solver
+
+
+# Formulas built with options["reuse_formula"], per (n, variant, configuration)
+FORMULA_CACHE = {}
+
+
+def formula_key(n_teams, use_sb, use_optimization, options=None):
+    """
+    Shape of a formula: an STS instance is fully described by n, so two runs share their
+    formula when they agree on the encoding and on the options changing the assertions.
+    """
+    options = options or {}
+    return (n_teams, options.get("variant") or "lia", use_sb, use_sb and use_optimization,
+            bool(use_sb and options.get("period_order")), bool(options.get("lazy_period")))
+
+
+def clear_formula_cache():
+    FORMULA_CACHE.clear()
+
+
+def build_model(n_teams, use_sb=False, use_optimization=False, options=None):
     """
     Builds the SMT model with specified parameters.
     
@@ -10,32 +332,60 @@ def build_model(n_teams, use_sb=False, use_optimization=False):
         n_teams: Number of teams
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
-        max_diff_constraint: Maximum home-away difference constraint
+        options: Dictionary of solver options (variant, tactic, z3_params, track, period_order).
+                 period_order adds the period ordering to the symmetry breaking, lazy_period leaves
+                 the period limit out (to be refined by the CEGAR loop). With track,
+                 every assertion is named by a tracker literal, described in extra_params["trackers"].
+                 With reuse_formula, the assertions of a formula already built for the same shape
+                 (formula_key) are added to the new solver instead of being rebuilt
     
     Returns:
         tuple: (solver, variables, weeks, periods, extra_params)
     """
-    
-    solver = Solver()
-    solver.set("random_seed", 42)    
-    solver.set("timeout", 300_000)  # 5 minutes timeout
+    model = get_variant(options)
+    build_start = time.time()
+
+    solver = make_solver(model, options)
+
+    reuse = (options or {}).get("reuse_formula") and not (options or {}).get("track")
+    key = formula_key(n_teams, use_sb, use_optimization, options)
+    if reuse and key in FORMULA_CACHE:
+        assertions, home, per, Weeks, Periods, extra_params = FORMULA_CACHE[key]
+        solver.add(assertions)
+        extra_params = {**extra_params, "opt": use_optimization, "encoding": {**extra_params["encoding"], "cached": True,
+                                                     "build_time": round(time.time() - build_start, 3)}}
+        return solver, home, per, Weeks, Periods, extra_params
     
     # Get parameters
-    num_teams, num_weeks, num_periods = smt_model.get_params(n_teams)
+    num_teams, num_weeks, num_periods = model.get_params(n_teams)
     Teams = list(range(num_teams))
     Weeks = list(range(num_weeks))
     Periods = list(range(num_periods))
     
     # Create SMT variables 
-    home, per = smt_model.create_variables(Teams, Weeks, Periods)  
+    home, per = model.create_variables(Teams, Weeks, Periods)  
     
+    trackers = {}
+
+    def target(group):
+        return TrackingSolver(solver, trackers, group) if (options or {}).get("track") else solver
+
+    if (options or {}).get("lazy_period") and (options or {}).get("track"):
+        raise ValueError("The lazy period limit cannot be combined with UNSAT core tracking")
+    hard = target("hard")
+    if (options or {}).get("lazy_period"):
+        hard = FilteringSolver(hard, ["constraint_max_two_per_period"])
+
     # Add constraints
-    smt_model.add_hard_constraints(home, per, Teams, Weeks, Periods, solver)
-    smt_model.add_channeling_constraint(home, per, Teams, Weeks, Periods, solver)
-    smt_model.add_implied_constraints(home, per, Teams, Weeks, Periods, solver)
+    model.add_hard_constraints(home, per, Teams, Weeks, Periods, hard)
+    model.add_channeling_constraint(home, per, Teams, Weeks, Periods, target("channeling"))
+    model.add_implied_constraints(home, per, Teams, Weeks, Periods, target("implied"))
 
     if use_sb:
-        smt_model.add_symmetry_breaking_constraints(home, per, Teams, Weeks, solver, use_optimization)
+        model.add_symmetry_breaking_constraints(home, per, Teams, Weeks, target("symmetry"), use_optimization)
+        if (options or {}).get("period_order"):
+            model.add_period_order_constraint(per, Teams, Periods,
+                                              tagged(target("symmetry"), "add_period_order_constraint"))
   
     
     extra_params = {
@@ -43,6 +393,14 @@ def build_model(n_teams, use_sb=False, use_optimization=False):
         "opt": use_optimization,
         "teams_list": Teams,  
         "teams": n_teams,
-    }   
+        "variant": (options or {}).get("variant") or "lia",
+        "period_order": bool(use_sb and (options or {}).get("period_order")),
+        "encoding": {**encoding_stats(solver.assertions()), "cached": False,
+                     "build_time": round(time.time() - build_start, 3)},
+    }
+    if (options or {}).get("track"):
+        extra_params["trackers"] = trackers
+    if reuse:
+        FORMULA_CACHE[key] = (list(solver.assertions()), home, per, Weeks, Periods, extra_params)   
     
     return solver, home, per, Weeks, Periods, extra_params  
\ No newline at end of file
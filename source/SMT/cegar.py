@@ -0,0 +1,68 @@
+from source.SMT.z3_compat import evaluate
+from z3 import *
+import time
+
+
+def period_violations(model, per, Teams, Weeks, Periods):
+    """
+    Returns the (team, period) pairs of a candidate model where the team plays more than
+    twice in the period.
+    """
+    violated = []
+    for i in Teams:
+        for p in Periods:
+            count = sum(1 for w in Weeks if evaluate(model, per[i][w] == p))
+            if count > 2:
+                violated.append((i, p))
+    return violated
+
+
+def add_period_lemmas(variant, per, pairs, Weeks, s):
+    """
+    Adds the period limit of the variant for the given (team, period) pairs only.
+    """
+    for i, p in pairs:
+        variant.constraint_max_two_per_period(per, [i], Weeks, [p], s)
+
+
+def check_lazy(solver, variant, per, Teams, Weeks, Periods, state, deadline):
+    """
+    Counterexample-guided refinement of the period limit, left out of the model built with
+    options["lazy_period"]: every candidate model is checked against the limit in Python and
+    the constraints of the violated (team, period) pairs are added before checking again.
+
+    Params:
+        state: Dictionary shared across calls; its "lemmas" list collects the refined pairs (in
+               order, so that the ones lost in a popped scope can be re-added) and "refinements"
+               counts the rejected candidates
+    Returns:
+        The status of the last check: sat only for a model satisfying the full period limit
+    """
+    while True:
+        remaining = deadline - time.time()
+        if remaining <= 0:
+            return unknown
+
+        solver.set("timeout", max(1, int(remaining * 1000)))
+        status = solver.check()
+        if status != sat:
+            return status
+
+        violated = period_violations(solver.model(), per, Teams, Weeks, Periods)
+        if not violated:
+            return sat
+
+        add_period_lemmas(variant, per, violated, Weeks, solver)
+        state["lemmas"].extend(violated)
+        state["refinements"] += 1
+
+
+def new_state():
+    return {"lemmas": [], "refinements": 0}
+
+
+def summary(state):
+    """
+    Returns the refinement statistics stored in the solver parameters.
+    """
+    return {"refinements": state["refinements"], "lemmas": len(state["lemmas"])}
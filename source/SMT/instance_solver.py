@@ -1,14 +1,96 @@
-from source.SMT.build_model import build_model
-from source.SMT.model import smt_model
+from source.SMT.build_model import build_model, get_variant, solver_config, apply_params, thread_count, TrackingSolver, \
+    describe_core, DEFAULT_SEED
+from source.SMT.model.smt_model import max_imbalance_objective
+from source.SMT.smtlib import to_smtlib, parse_status, parse_values, schedule_from_values, export_smt2, \
+    export_logic
+from source.SMT.backends import BACKENDS
+from source.SAT.build_model import GuardedSolver
+from source.SMT import cegar
+from source.SMT.interrupt import SoftTimeout, ModelMonitor
+from source.SMT.z3_compat import evaluate, objective_lower
+from source.SMT.relaxation import relaxation_lower_bound
+from source.common.bounds import initial_lower_bound
+from source.common.trace import record_improvement
+from source.SMT.warm_start import heuristic_assignment, add_phase_hints, add_soft_hints
 from z3 import *
 import time
+import re
 
-def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, max_diff_constraint=None):
+# Solvers (external ones are run on an SMT-LIB2 export of the model)
+SOLVERS = {
+    "z3": None,
+    **{name: backend.path for name, backend in BACKENDS.items()}
+}
+
+# Optimization strategies of the Z3 backend (options["strategy"])
+STRATEGIES = ["binary", "omt", "assumptions", "linear"]
+
+# Options supported by each strategy, among the ones restricted to some of them
+STRATEGY_OPTIONS = {
+    "binary": {"tactic", "track", "warm_start", "chunk", "lazy_period"},
+    "omt": {"warm_start", "chunk"},
+    "assumptions": {"tactic"},
+    "linear": {"tactic"},
+}
+
+def z3_metadata(options=None):
+    """
+    Returns the solver parameters recorded for every Z3 run: version, effective seed, threads,
+    tactic and tuning parameters.
+    """
+    config = solver_config(options)
+    seed = config.get("z3_params", {}).get("random_seed", DEFAULT_SEED)
+    return {"backend": "z3", "version": get_version_string(), "random_seed": seed,
+            "threads": thread_count(options), **config}
+
+# Statistics summarized in the results (Z3 names, which vary with the solver core in use)
+STATISTICS = ["conflicts", "decisions", "propagations", "restarts", "arith-pivots", "arith-conflicts",
+              "final checks", "added eqs", "memory", "max memory", "time"]
+
+
+def collect_statistics(solver):
+    """
+    Returns the Z3 statistics of a solver or optimizer after a run: the STATISTICS entries
+    it reports, with all the others under "all".
+    """
+    try:
+        stats = solver.statistics()
+        values = {key: stats.get_key_value(key) for key in stats.keys()}
+    except Z3Exception:
+        return None
+    return {**{key: values[key] for key in STATISTICS if key in values}, "all": values}
+
+
+def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, max_diff_constraint=None,
+                   options=None):
     
     try:
+        if solver_name.lower() in BACKENDS:
+            if solver_config(options) or any((options or {}).get(name) for name in ("warm_start", "track",
+                                                                                     "lazy_period", "threads",
+                                                                                     "relaxation")):
+                raise ValueError("Z3 tactics, parameters, threads, warm start, UNSAT cores, the lazy "
+                                 "period limit and the relaxation probe only apply to the z3 solver")
+            return solve_with_smtlib(n_teams, BACKENDS[solver_name.lower()], use_sb, use_optimization,
+                                     options=options)
+
         if use_optimization:
 
-            model, home, per, max_diff, elapsed = optimize_home_away_difference(n_teams, use_sb, 300)
+            strategy = (options or {}).get("strategy") or "binary"
+            if strategy not in STRATEGIES:
+                raise ValueError(f"Unknown SMT optimization strategy: {strategy}")
+            unsupported = [name for name in ("tactic", "track", "warm_start", "chunk", "lazy_period")
+                           if (options or {}).get(name) and name not in STRATEGY_OPTIONS[strategy]]
+            if unsupported:
+                raise ValueError(f"Options not supported by the {strategy} strategy: {', '.join(unsupported)}")
+            optimize = {
+                "binary": optimize_home_away_difference,
+                "omt": optimize_with_omt,
+                "assumptions": optimize_with_assumptions,
+                "linear": optimize_linear,
+            }[strategy]
+            model, home, per, max_diff, elapsed, solver_params = optimize(n_teams, use_sb, 300, options)
+            solver_params = {**z3_metadata(options), **solver_params}
 
             return {
                 "status": sat if model else unsat,
@@ -23,7 +105,10 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, m
                     "opt": True,
                     "teams_list": list(range(n_teams)),
                     "teams": n_teams,
-                    "max_diff": max_diff
+                    "max_diff": max_diff,
+                    "variant": (options or {}).get("variant") or "lia",
+                    "proven_optimal": solver_params.get("proven_optimal", False),
+                    "solver_params": solver_params
                 }
             }
     
@@ -31,12 +116,34 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, m
             # Regular solving path
             start_time = time.time()
 
-            solver, home, per, weeks, periods, extra_params = build_model(n_teams, use_sb, False)
+            solver, home, per, weeks, periods, extra_params = build_model(n_teams, use_sb, False, options)
+            solver_params = {**z3_metadata(options), "encoding": extra_params["encoding"]}
+            if (options or {}).get("warm_start"):
+                assignment, _ = heuristic_assignment(n_teams, home, per, use_sb, False,
+                                                     period_order=options.get("period_order"))
+                solver_params["warm_start"] = {"phase_hints": add_phase_hints(solver, assignment)}
+            if (options or {}).get("export_smt2"):
+                export_smt2(solver.assertions(), n_teams, extra_params, logic=export_logic(get_variant(options)))
         
             # Solve the model
-            status = solver.check()
+            with SoftTimeout(300 - (time.time() - start_time)) as soft:
+                if (options or {}).get("lazy_period"):
+                    lazy_state = cegar.new_state()
+                    Teams = list(range(n_teams))
+                    status = cegar.check_lazy(solver, get_variant(options), per, Teams, weeks, periods, lazy_state,
+                                              start_time + 300)
+                    solver_params["cegar"] = cegar.summary(lazy_state)
+                else:
+                    status = solver.check()
+            if soft.reason:
+                solver_params["interrupted"] = soft.reason
+            solver_params["statistics"] = collect_statistics(solver)
             elapsed_time = time.time() - start_time
 
+            trackers = extra_params.pop("trackers", None)
+            if status == unsat and trackers is not None:
+                solver_params["unsat_core"] = report_core(solver, trackers)
+
             # Prepare result
             result = {
                 "status": status,
@@ -51,7 +158,8 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, m
                     **extra_params,
                     "opt": False,
                     "max_diff": None,
-                    "is_optimal": (status == sat) 
+                    "is_optimal": (status == sat),
+                    "solver_params": solver_params
                 }
             }
         
@@ -68,52 +176,489 @@ def solve_instance(n_teams, solver_name, use_sb=False, use_optimization=False, m
         
 
 
-def optimize_home_away_difference(n_teams, use_sb=False, timeout=300):
+def report_core(solver, trackers):
+    """
+    Maps the UNSAT core of the last check to constraint descriptions and prints them.
+    """
+    core = describe_core(solver.unsat_core(), trackers)
+    print("UNSAT core:")
+    for entry in core:
+        print(f"  [{entry['group']}] {entry['description']} ({entry['assertions']} assertions)")
+    return core
+
+
+def relaxed_lower_bound(n_teams, options, solver_params):
+    """
+    With options["relaxation"], runs the real relaxation probe before the integer search and
+    records it in solver_params; with options["mip_bound"], asks the MIP bound oracle. Returns
+    the best lower bound they prove (1 otherwise).
+    """
+    lower_bound = initial_lower_bound(n_teams, options, solver_params)
+    if not (options or {}).get("relaxation"):
+        return lower_bound
+    probe = relaxation_lower_bound(n_teams)
+    solver_params["relaxation"] = probe
+    print(f"Relaxation lower bound: {probe['bound']} (relaxed optimum {probe['value']})")
+    return max(lower_bound, probe["bound"])
+
+
+def optimize_home_away_difference(n_teams, use_sb=False, timeout=300, options=None):
     """
     SMT optimization using binary search with precomputed Z3 expressions.
+    The objective bound is encoded by the selected variant (options["variant"]).
+
+    A single solver is used incrementally: a satisfiable bound is implied by every following
+    (tighter) query, so its scope is kept open together with the lemmas learned in it, and only
+    the scopes of UNSAT bounds are popped. The search is anytime: on timeout the best model is
+    returned and the tightest proven lower bound is stored in solver_params.
+
+    With options["warm_start"] the greedy schedule sets the initial phases and its imbalance
+    is the first bound queried, so that the first SAT answer is already close to the optimum.
+    With options["track"] the core of every UNSAT bound is stored with its query.
+
+    With options["chunk"] (seconds) a query is interrupted when its chunk runs out: the bound
+    is skipped for the looser ones, keeping the best model, and the skipped bounds below it are
+    retried with the rest of the budget once the looser half of the search is closed.
+
+    With options["lazy_period"] every query runs the CEGAR loop on the period limit; the lemmas
+    refined inside a popped scope are re-added after the pop, since they hold for every bound.
     """
     start_time = time.time()
+    variant = get_variant(options)
+
+    home, per = None, None
+    best_model, best_max_diff = None, None
+    queries = []
+    chunk = (options or {}).get("chunk")
+    solver_params = {"strategy": "binary", "incremental": True, "queries": queries, "lower_bound": 1,
+                     "chunk": chunk}
 
     try:
         # Build base model
-        solver, home, per, Weeks, Periods, _ = build_model(n_teams, use_sb, use_optimization=True)
+        solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization=True,
+                                                                      options=options)
         Teams = list(range(n_teams))
         total_weeks = n_teams - 1
+        trackers = extra_params.pop("trackers", None)
+        solver_params["encoding"] = extra_params["encoding"]
+        lazy_state = cegar.new_state() if (options or {}).get("lazy_period") else None
+
+        warm_bound = None
+        if (options or {}).get("warm_start"):
+            assignment, warm_bound = heuristic_assignment(n_teams, home, per, use_sb, True,
+                                                          time_limit=min(10, timeout / 10),
+                                                          period_order=options.get("period_order"))
+            solver_params["warm_start"] = {"heuristic_obj": warm_bound,
+                                           "phase_hints": add_phase_hints(solver, assignment)}
 
         # Binary search bounds
-        lower_bound, upper_bound = 1, total_weeks
-        best_model, best_max_diff = None, upper_bound
+        lower_bound, upper_bound = relaxed_lower_bound(n_teams, options, solver_params), total_weeks
+        proven_lower = lower_bound
+        skipped = []
 
         # Binary search loop
-        while lower_bound <= upper_bound and (time.time() - start_time) < timeout:
-            mid = (lower_bound + upper_bound) // 2
+        while (time.time() - start_time) < timeout:
+            if lower_bound > upper_bound:
+                # Retry the bounds skipped on a chunk timeout, without chunk limit
+                if not skipped or (best_max_diff is not None and proven_lower >= best_max_diff):
+                    break
+                lower_bound = proven_lower
+                upper_bound = best_max_diff - 1 if best_max_diff is not None else total_weeks
+                chunk, skipped = None, []
+                continue
+
+            # The first query checks the heuristic bound, which the hinted phases satisfy
+            mid = warm_bound if (warm_bound is not None and not queries) else (lower_bound + upper_bound) // 2
             print(f"Testing max_imbalance = {mid}")
 
+            remaining = timeout - (time.time() - start_time)
+            limit = min(chunk, remaining) if chunk else remaining
+            solver.set("timeout", max(1, int(limit * 1000)))
+            lemma_mark = len(lazy_state["lemmas"]) if lazy_state is not None else 0
             solver.push()
             # Add max imbalance constraint
-            smt_model.add_max_diff_constraint(home, Teams, Weeks, mid, solver)
+            bound_target = TrackingSolver(solver, trackers, "bound", mid, "add_max_diff_constraint") \
+                if trackers is not None else solver
+            variant.add_max_diff_constraint(home, Teams, Weeks, mid, bound_target)
+            if (options or {}).get("export_smt2"):
+                export_smt2(solver.assertions(), n_teams, extra_params, bound=mid, logic=export_logic(variant))
+
+            query_start = time.time()
+            with SoftTimeout(limit) as soft:
+                if lazy_state is not None:
+                    status = cegar.check_lazy(solver, variant, per, Teams, Weeks, Periods, lazy_state,
+                                              query_start + limit)
+                else:
+                    status = solver.check()
+            queries.append({"bound": mid, "status": str(status), "time": round(time.time() - query_start, 3)})
+            if soft.cancelled_by_user:
+                solver_params["interrupted"] = "user"
 
-            status = solver.check()
+            # Lemmas refined in a scope popped below hold for every bound: they are re-added after the pop
+            refined = lazy_state["lemmas"][lemma_mark:] if lazy_state is not None else []
 
             if status == sat:
                 best_model = solver.model()
                 best_max_diff = mid
-                solver.pop()
+                record_improvement(solver_params, start_time, mid)
+                # keep the scope: the bound holds for all the following queries
                 upper_bound = mid - 1
-                if best_max_diff == 1:
-                    break
+            elif status == unsat:
+                if trackers is not None:
+                    queries[-1]["unsat_core"] = report_core(solver, trackers)
+                solver.pop()
+                cegar.add_period_lemmas(variant, per, refined, Weeks, solver)
+                lower_bound = mid + 1
+                proven_lower = max(proven_lower, mid + 1)
             else:
+                # Timeout inside the query: nothing is proven about this bound
                 solver.pop()
+                cegar.add_period_lemmas(variant, per, refined, Weeks, solver)
+                if not chunk or soft.cancelled_by_user:
+                    break
+                # End of the chunk: move on to the looser bounds
+                skipped.append(mid)
                 lower_bound = mid + 1
 
+        solver_params["lower_bound"] = proven_lower
+        solver_params["statistics"] = collect_statistics(solver)
+        solver_params["proven_optimal"] = best_model is not None and proven_lower >= best_max_diff
+        if lazy_state is not None:
+            solver_params["cegar"] = cegar.summary(lazy_state)
         elapsed = time.time() - start_time
 
         # Timeout with no solution
         if elapsed >= timeout and best_model is None:
-            return None, None, None, None, timeout
+            return None, None, None, None, timeout, solver_params
 
         # Solution found or timeout with partial solution
-        return best_model, home, per, best_max_diff, elapsed
+        return best_model, home, per, best_max_diff, elapsed, solver_params
 
     except KeyboardInterrupt:
-        return best_model, home, per, best_max_diff, timeout
\ No newline at end of file
+        return best_model, home, per, best_max_diff, timeout, solver_params
+
+
+def model_imbalance(model, home, Teams, Weeks):
+    """
+    Max home/away imbalance of the schedule of a model.
+    """
+    imbalances = []
+    for i in Teams:
+        home_games = sum(1 for j in Teams if j != i for w in Weeks
+                         if evaluate(model, home[i][j][w]))
+        imbalances.append(abs(2 * home_games - len(Weeks)))
+    return max(imbalances)
+
+
+def optimize_with_assumptions(n_teams, use_sb=False, timeout=300, options=None):
+    """
+    SMT binary search guarding every bound with an assumption literal: max imbalance <= b is
+    asserted once as guard_b => bound and enabled by checking under the assumption guard_b,
+    so the solver is never popped and keeps all its lemmas. A satisfied guard is then asserted
+    for good (every following bound is tighter) and a refuted one is negated.
+    """
+    start_time = time.time()
+    variant = get_variant(options)
+
+    home, per = None, None
+    best_model, best_max_diff = None, None
+    queries = []
+    solver_params = {"strategy": "assumptions", "incremental": True, "queries": queries, "lower_bound": 1}
+
+    try:
+        solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization=True,
+                                                                      options=options)
+        Teams = list(range(n_teams))
+        solver_params["encoding"] = extra_params["encoding"]
+
+        lower_bound, upper_bound = relaxed_lower_bound(n_teams, options, solver_params), n_teams - 1
+        while lower_bound <= upper_bound and (time.time() - start_time) < timeout:
+            mid = (lower_bound + upper_bound) // 2
+            print(f"Testing max_imbalance = {mid} (assumption)")
+
+            guard = Bool(f"bound_le_{mid}")
+            variant.add_max_diff_constraint(home, Teams, Weeks, mid, GuardedSolver(solver, guard))
+            if (options or {}).get("export_smt2"):
+                export_smt2(list(solver.assertions()) + [guard], n_teams, extra_params, bound=mid,
+                            logic=export_logic(variant))
+
+            remaining = timeout - (time.time() - start_time)
+            solver.set("timeout", max(1, int(remaining * 1000)))
+            query_start = time.time()
+            with SoftTimeout(remaining) as soft:
+                status = solver.check(guard)
+            if soft.cancelled_by_user:
+                solver_params["interrupted"] = "user"
+            queries.append({"bound": mid, "status": str(status), "time": round(time.time() - query_start, 3)})
+
+            if status == sat:
+                best_model, best_max_diff = solver.model(), mid
+                record_improvement(solver_params, start_time, mid)
+                solver.add(guard)
+                upper_bound = mid - 1
+            elif status == unsat:
+                solver.add(Not(guard))
+                lower_bound = mid + 1
+            else:
+                break
+
+        solver_params["lower_bound"] = lower_bound
+        solver_params["proven_optimal"] = best_model is not None and lower_bound > upper_bound
+        solver_params["statistics"] = collect_statistics(solver)
+        return best_model, home, per, best_max_diff, min(time.time() - start_time, timeout), solver_params
+
+    except KeyboardInterrupt:
+        return best_model, home, per, best_max_diff, timeout, solver_params
+
+
+def optimize_linear(n_teams, use_sb=False, timeout=300, options=None):
+    """
+    Linear (SAT-UNSAT) search on the max imbalance: the unbounded model is solved first and
+    every following query asks for a schedule strictly better than the last one found, until
+    UNSAT proves the last one optimal. Satisfied bounds stay asserted as in the binary search.
+    """
+    start_time = time.time()
+    variant = get_variant(options)
+
+    home, per = None, None
+    best_model, best_max_diff = None, None
+    queries = []
+    solver_params = {"strategy": "linear", "incremental": True, "queries": queries, "lower_bound": 1}
+
+    try:
+        solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization=True,
+                                                                      options=options)
+        Teams = list(range(n_teams))
+        solver_params["encoding"] = extra_params["encoding"]
+
+        lower = relaxed_lower_bound(n_teams, options, solver_params)
+        bound = None
+        while (time.time() - start_time) < timeout:
+            if bound is not None:
+                print(f"Testing max_imbalance = {bound}")
+                solver.push()
+                variant.add_max_diff_constraint(home, Teams, Weeks, bound, solver)
+            if (options or {}).get("export_smt2"):
+                export_smt2(solver.assertions(), n_teams, extra_params, bound=bound, logic=export_logic(variant))
+
+            remaining = timeout - (time.time() - start_time)
+            solver.set("timeout", max(1, int(remaining * 1000)))
+            query_start = time.time()
+            with SoftTimeout(remaining) as soft:
+                status = solver.check()
+            if soft.cancelled_by_user:
+                solver_params["interrupted"] = "user"
+            queries.append({"bound": bound, "status": str(status), "time": round(time.time() - query_start, 3)})
+
+            if status == sat:
+                best_model = solver.model()
+                best_max_diff = model_imbalance(best_model, home, Teams, Weeks)
+                record_improvement(solver_params, start_time, best_max_diff)
+                if best_max_diff <= lower:
+                    solver_params["lower_bound"] = lower
+                    solver_params["proven_optimal"] = True
+                    break
+                bound = best_max_diff - 1
+            else:
+                if status == unsat and best_model is not None:
+                    solver_params["lower_bound"] = best_max_diff
+                    solver_params["proven_optimal"] = True
+                break
+
+        solver_params["statistics"] = collect_statistics(solver)
+        return best_model, home, per, best_max_diff, min(time.time() - start_time, timeout), solver_params
+
+    except KeyboardInterrupt:
+        return best_model, home, per, best_max_diff, timeout, solver_params
+
+
+def optimize_with_omt(n_teams, use_sb=False, timeout=300, options=None):
+    """
+    SMT optimization handing the max imbalance to Z3's Optimize (OMT) instead of
+    iterating satisfiability checks over the bounds.
+
+    With options["warm_start"] the greedy schedule bounds the objective from above and its
+    values are added as soft constraints (and initial phases when supported).
+
+    With options["chunk"] (seconds) the optimizer is interrupted at the end of every chunk:
+    the best model found so far is kept, the objective is bounded strictly below it and the
+    search continues, so a timeout never loses the solutions of the previous chunks.
+    """
+    start_time = time.time()
+    best_model, home, per, best_max_diff = None, None, None, None
+    chunk = (options or {}).get("chunk")
+    solver_params = {"strategy": "omt", "proven_optimal": False, "chunk": chunk, "chunks": 0}
+
+    try:
+        solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization=True,
+                                                                      options=options)
+        Teams = list(range(n_teams))
+        solver_params["encoding"] = extra_params["encoding"]
+
+        optimizer = Optimize()
+        optimizer.set("timeout", int(timeout * 1000))
+        optimizer.set("random_seed", DEFAULT_SEED)
+        apply_params(optimizer, solver_config(options).get("z3_params", {}))
+        optimizer.add(solver.assertions())
+        objective, constraints = max_imbalance_objective(home, Teams, Weeks)
+        optimizer.add(constraints)
+        handle = optimizer.minimize(objective)
+        optimizer.add(objective >= relaxed_lower_bound(n_teams, options, solver_params))
+        monitor = ModelMonitor(optimizer, objective)
+        if (options or {}).get("warm_start"):
+            assignment, warm_bound = heuristic_assignment(n_teams, home, per, use_sb, True,
+                                                          time_limit=min(10, timeout / 10),
+                                                          period_order=options.get("period_order"))
+            if warm_bound is not None:
+                optimizer.add(objective <= warm_bound)
+            add_soft_hints(optimizer, assignment)
+            solver_params["warm_start"] = {"heuristic_obj": warm_bound, "soft_constraints": len(assignment),
+                                           "phase_hints": add_phase_hints(optimizer, assignment)}
+        if (options or {}).get("export_smt2"):
+            export_smt2(list(solver.assertions()) + constraints, n_teams, extra_params,
+                        logic=export_logic(get_variant(options), objective), objective=objective)
+
+        while (time.time() - start_time) < timeout:
+            remaining = timeout - (time.time() - start_time)
+            limit = min(chunk, remaining) if chunk else remaining
+            optimizer.set("timeout", max(1, int(limit * 1000)))
+            with SoftTimeout(limit) as soft:
+                status = optimizer.check()
+            solver_params["chunks"] += 1
+
+            if status == unsat:
+                # Only reachable after tightening: the previous best model is optimal
+                solver_params["proven_optimal"] = best_model is not None
+                break
+
+            # On timeout the optimizer still holds the best model found so far
+            try:
+                model = optimizer.model()
+                value = evaluate(model, objective)
+                if value is not None and (best_max_diff is None or value < best_max_diff):
+                    best_model, best_max_diff = model, value
+            except Z3Exception:
+                pass
+            # The optimizer may be cancelled before exposing a model: take the last one it reported
+            if monitor.model is not None and (best_max_diff is None or monitor.value < best_max_diff):
+                best_model, best_max_diff = monitor.model, monitor.value
+            record_improvement(solver_params, start_time, best_max_diff)
+
+            if status == sat:
+                solver_params["proven_optimal"] = True
+                break
+            if soft.cancelled_by_user:
+                solver_params["interrupted"] = "user"
+                break
+            if not chunk:
+                break
+            if best_max_diff is not None:
+                optimizer.add(objective <= best_max_diff - 1)
+
+        solver_params["statistics"] = collect_statistics(optimizer)
+        lower = objective_lower(handle)
+        if solver_params["proven_optimal"]:
+            solver_params["lower_bound"] = best_max_diff
+        elif lower is not None:
+            solver_params["lower_bound"] = lower
+
+        return best_model, home, per, best_max_diff, time.time() - start_time, solver_params
+
+    except KeyboardInterrupt:
+        return best_model, home, per, best_max_diff, timeout, solver_params
+
+
+def solve_with_smtlib(n_teams, backend, use_sb=False, use_optimization=False, timeout=300, options=None):
+    """
+    Solves the instance with an external SMT-LIB2 backend: the model of the selected variant
+    is exported in standard SMT-LIB2. With optimization, OMT backends minimize the max imbalance
+    with the (minimize ...) extension, the others run one script per bound in a binary search.
+
+    Returns a structured result, with the decoded schedule under "schedule".
+    """
+    start_time = time.time()
+    variant = get_variant(options)
+
+    solver, home, per, Weeks, Periods, extra_params = build_model(n_teams, use_sb, use_optimization, options)
+    Teams = list(range(n_teams))
+    base = list(solver.assertions())
+    values = [v for i in Teams for j in Teams if i != j for v in home[i][j]] + [v for row in per for v in row]
+    if not all(is_const(v) for v in values):
+        raise ValueError(f"The {extra_params['variant']} variant has no schedule variables to read back "
+                         f"from SMT-LIB2 answers")
+
+    queries = []
+    solver_params = {"backend": backend.name, "version": backend.version(), "queries": queries,
+                     "encoding": extra_params["encoding"]}
+
+    def query(assertions, objective=None, bound=None):
+        if (options or {}).get("export_smt2"):
+            export_smt2(assertions, n_teams, extra_params, bound=bound,
+                        logic=export_logic(variant, objective), objective=objective)
+        script = to_smtlib(assertions, objective=objective, values=values)
+        query_start = time.time()
+        output = backend.run(script, timeout - (time.time() - start_time))
+        answer = parse_status(output) if output is not None else "unknown"
+        queries.append({"bound": bound, "status": answer, "time": round(time.time() - query_start, 3)})
+        return answer, output
+
+    status, schedule, obj = unknown, None, None
+    proven_optimal = False
+
+    if use_optimization and backend.omt:
+        objective, constraints = max_imbalance_objective(home, Teams, Weeks)
+        answer, output = query(base + constraints, objective=objective)
+        if answer == "sat":
+            status = sat
+            schedule = schedule_from_values(parse_values(output), Teams, Weeks, Periods)
+            match = re.search(r"\(objectives\s*\(\s*\S+\s+(-?\d+)", output)
+            obj = int(match.group(1)) if match else None
+            record_improvement(solver_params, start_time, obj)
+            # the OMT search only answers sat once the minimum is proven
+            proven_optimal = True
+        elif answer == "unsat":
+            status = unsat
+
+    elif use_optimization:
+        lower_bound, upper_bound = relaxed_lower_bound(n_teams, options, solver_params), n_teams - 1
+        while lower_bound <= upper_bound and (time.time() - start_time) < timeout:
+            mid = (lower_bound + upper_bound) // 2
+            print(f"Testing max_imbalance = {mid}")
+
+            bound = Solver()
+            variant.add_max_diff_constraint(home, Teams, Weeks, mid, bound)
+            answer, output = query(base + list(bound.assertions()), bound=mid)
+
+            if answer == "sat":
+                status, obj = sat, mid
+                record_improvement(solver_params, start_time, mid)
+                schedule = schedule_from_values(parse_values(output), Teams, Weeks, Periods)
+                upper_bound = mid - 1
+            elif answer == "unsat":
+                lower_bound = mid + 1
+            else:
+                break
+
+        solver_params["lower_bound"] = lower_bound
+        proven_optimal = status == sat and lower_bound > upper_bound
+
+    else:
+        answer, output = query(base)
+        status = {"sat": sat, "unsat": unsat}.get(answer, unknown)
+        if status == sat:
+            schedule = schedule_from_values(parse_values(output), Teams, Weeks, Periods)
+
+    return {
+        "status": status,
+        "time": time.time() - start_time,
+        "model": None,
+        "schedule": schedule,
+        "weeks": Weeks,
+        "periods": Periods,
+        "extra_params": {
+            **extra_params,
+            "max_diff": obj,
+            "proven_optimal": proven_optimal,
+            "solver_params": solver_params,
+        },
+    }
@@ -0,0 +1,68 @@
+from source.SMT.z3_compat import on_model, evaluate
+from z3 import *
+import signal
+import threading
+
+
+class SoftTimeout:
+    """
+    Context manager around a Z3 check: the context is interrupted when the soft time limit
+    expires or on Ctrl-C, so that the check returns unknown instead of the process losing the
+    models found before it (Z3 only notices a KeyboardInterrupt once the check is over).
+
+    Params:
+        timeout: Soft time limit in seconds (None for no limit)
+        ctx: The Z3 context to interrupt (default: the main one)
+    """
+
+    def __init__(self, timeout=None, ctx=None):
+        self.timeout = timeout
+        self.ctx = ctx or main_ctx()
+        self.reason = None
+        self._timer = None
+        self._previous = None
+
+    def _interrupt(self, reason):
+        if self.reason is None:
+            self.reason = reason
+        self.ctx.interrupt()
+
+    def __enter__(self):
+        if self.timeout is not None:
+            self._timer = threading.Timer(max(0, self.timeout), self._interrupt, args=("timeout",))
+            self._timer.daemon = True
+            self._timer.start()
+        # Signal handlers can only be installed from the main thread
+        if threading.current_thread() is threading.main_thread():
+            self._previous = signal.signal(signal.SIGINT, lambda signum, frame: self._interrupt("user"))
+        return self
+
+    def __exit__(self, *exc):
+        if self._timer is not None:
+            self._timer.cancel()
+        if self._previous is not None:
+            signal.signal(signal.SIGINT, self._previous)
+        return False
+
+    @property
+    def cancelled_by_user(self):
+        return self.reason == "user"
+
+
+class ModelMonitor:
+    """
+    Keeps the last complete model reported by z3.Optimize while it improves the objective
+    (on_model callback, when the Z3 version offers it), so that it is still available if the
+    optimizer is cancelled before returning.
+    """
+
+    def __init__(self, optimizer, objective):
+        self.objective = objective
+        self.model = None
+        self.value = None
+        self.supported = on_model(optimizer, self._on_model)
+
+    def _on_model(self, model):
+        value = evaluate(model, self.objective)
+        if value is not None and (self.value is None or value < self.value):
+            self.model, self.value = model, value
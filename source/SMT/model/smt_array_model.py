@@ -0,0 +1,115 @@
+from source.SMT.model.smt_model import get_params, create_variables, exactly_one, constraint_each_pair_once, \
+    constraint_max_two_per_period, add_sb2, add_team_order_constraint, add_period_order_constraint, \
+    add_max_diff_constraint, tagged
+from z3 import *
+
+# Every week is an array slot -> team, read with select and inverted with store
+LOGIC = "QF_ALIA"
+
+
+# ------------------
+# DECISION VARIABLES
+# ------------------
+
+# The home / period variables of the LIA model are kept as views of the week arrays
+# (they are read by the objective and the decoding), the arrays are recreated by name
+
+def create_arrays(Teams, Weeks):
+    """
+    Returns the week arrays slots[w]: slot 2p holds the home team and slot 2p + 1 the
+    away team of period p, and their inverses position[w]: team -> slot, built by storing
+    every slot index at the team it holds.
+    """
+    slots = [Array(f"slots_{w}", IntSort(), IntSort()) for w in Weeks]
+    position = []
+    for w in Weeks:
+        inverse = K(IntSort(), -1)
+        for k in Teams:
+            inverse = Store(inverse, Select(slots[w], k), k)
+        position.append(inverse)
+    return slots, position
+
+
+# ----------------
+# HARD CONSTRAINTS
+# ----------------
+
+# (1) and (3) are stated on the home / period views as in the LIA model
+
+# (2) every team plays once a week: the slots of a week are a permutation of the teams
+def constraint_one_match_per_week(Teams, Weeks, s):
+    slots, _ = create_arrays(Teams, Weeks)
+    for w in Weeks:
+        s.add(Distinct([Select(slots[w], k) for k in Teams]))
+
+
+def add_hard_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_each_pair_once(home, Teams, Weeks, tagged(s, "constraint_each_pair_once"))
+    constraint_one_match_per_week(Teams, Weeks, tagged(s, "constraint_one_match_per_week"))
+    constraint_max_two_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_max_two_per_period"))
+
+
+# -----------------
+# DOMAIN CONSTRAINT
+# -----------------
+def add_domain_constrain(per, Teams, Weeks, Periods, s):
+    slots, _ = create_arrays(Teams, Weeks)
+    for w in Weeks:
+        for k in Teams:
+            s.add(And(Select(slots[w], k) >= 0, Select(slots[w], k) < len(Teams)))
+        for i in Teams:
+            s.add(And(per[i][w] >= 0, per[i][w] < len(Periods)))
+
+
+# ----------------------
+# CHANNELING CONSTRAINT
+# ----------------------
+
+# The period of a team is half its slot, and i hosts j when i is in an even slot followed by j
+def constraint_array_channeling(home, per, Teams, Weeks, s):
+    _, position = create_arrays(Teams, Weeks)
+    for w in Weeks:
+        for i in Teams:
+            slot_i = Select(position[w], i)
+            s.add(per[i][w] == slot_i / 2)
+            for j in Teams:
+                if i != j:
+                    s.add(home[i][j][w] == And(slot_i % 2 == 0, Select(position[w], j) == slot_i + 1))
+
+
+def add_channeling_constraint(home, per, Teams, Weeks, Periods, s):
+    add_domain_constrain(per, Teams, Weeks, Periods, tagged(s, "add_domain_constrain"))
+    constraint_array_channeling(home, per, Teams, Weeks, tagged(s, "constraint_array_channeling"))
+
+
+# -------------------
+# IMPLIED CONSTRAINTS
+# -------------------
+
+# Every team is in exactly one match per week (implied by the permutation)
+def constraint_one_match_per_team(home, Teams, Weeks, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(exactly_one([home[i][j][w] for j in Teams if j != i] + [home[j][i][w] for j in Teams if j != i]))
+
+
+def add_implied_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_one_match_per_team(home, Teams, Weeks, tagged(s, "constraint_one_match_per_team"))
+
+
+# -----------------------------
+# SYMMETRY BREAKING CONSTRAINTS
+# -----------------------------
+
+# (sb1) Fix the first match to be team 0 (home) vs team 1 in period 0
+def add_sb1(Teams, Weeks, s):
+    slots, _ = create_arrays(Teams, Weeks)
+    s.add(Select(slots[0], 0) == 0)
+    s.add(Select(slots[0], 1) == 1)
+
+
+def add_symmetry_breaking_constraints(home, per, Teams, Weeks, s, use_optimization):
+    add_sb1(Teams, Weeks, tagged(s, "add_sb1"))
+    add_sb2(home, Teams, Weeks, tagged(s, "add_sb2"))
+    if not use_optimization:
+        add_team_order_constraint(home, Teams, Weeks, tagged(s, "add_team_order_constraint"))
@@ -0,0 +1,140 @@
+from source.SMT.model.smt_idl_model import exactly_one, constrain_home_symmetry, add_symmetry_breaking_constraints
+from source.SMT.model.smt_model import get_params, add_period_order_constraint, tagged
+from itertools import combinations
+from z3 import *
+
+# Bounded domains only: periods and home counts are fixed-width bitvectors, so that
+# Z3 bit-blasts the whole problem to SAT
+LOGIC = "QF_BV"
+
+
+def width(max_value):
+    """
+    Number of bits needed to represent the values 0..max_value.
+    """
+    return max(1, max_value.bit_length())
+
+
+def bv_count(bool_vars, bits):
+    """
+    Number of true bool_vars as a bitvector of the given width.
+    """
+    return Sum([If(b, BitVecVal(1, bits), BitVecVal(0, bits)) for b in bool_vars])
+
+
+# ------------------
+# DECISION VARIABLES
+# ------------------
+
+def create_variables(Teams, Weeks, Periods):
+    # home[i][j][w] true if i plays at home against j in week w
+    home = []
+    for i in Teams:
+        home_row = []
+        for j in Teams:
+            if i == j:
+                home_row.append([])  # empty list for i = j
+            else:
+                home_row.append([Bool(f"h_{i}_{j}_{w}") for w in Weeks])
+        home.append(home_row)
+
+    # period[i,w] = p means team i plays in period p in week w
+    bits = width(len(Periods) - 1)
+    per = [[BitVec(f"p_{i}_{w}", bits) for w in Weeks] for i in Teams]
+
+    return home, per
+
+
+# ----------------
+# HARD CONSTRAINTS
+# ----------------
+
+# (1) every team plays with every other team only once
+def constraint_each_pair_once(home, Teams, Weeks, s):
+    for i, j in combinations(Teams, 2):
+        s.add(exactly_one([home[i][j][w] for w in Weeks] + [home[j][i][w] for w in Weeks]))
+
+
+# (2) every team plays once a week
+def constraint_one_match_per_week(home, Teams, Weeks, s):
+    for i in Teams:
+        for w in Weeks:
+            week_match = []
+            for j in Teams:
+                if i != j:
+                    week_match.append(home[i][j][w])
+                    week_match.append(home[j][i][w])
+            s.add(exactly_one(week_match))
+
+
+# (3) every team plays at most twice in the same period over the tournament
+def constraint_max_two_per_period(per, Teams, Weeks, Periods, s):
+    bits = width(len(Weeks))
+    for i in Teams:
+        for p in Periods:
+            count = bv_count([per[i][w] == p for w in Weeks], bits)
+            s.add(ULE(count, 2))
+
+
+def add_hard_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_each_pair_once(home, Teams, Weeks, tagged(s, "constraint_each_pair_once"))
+    constraint_one_match_per_week(home, Teams, Weeks, tagged(s, "constraint_one_match_per_week"))
+    constraint_max_two_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_max_two_per_period"))
+
+
+# -----------------
+# DOMAIN CONSTRAINT
+# -----------------
+def add_domain_constrain(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(ULT(per[i][w], len(Periods)))
+
+
+# ----------------------
+# CHANNELING CONSTRAINT
+# ----------------------
+
+def constraint_period_consistency(home, per, Teams, Weeks, Periods, s):
+    for w in Weeks:
+        for i, j in combinations(Teams, 2):
+            plays_together = Or(home[i][j][w], home[j][i][w])
+            s.add(Implies(plays_together, per[i][w] == per[j][w]))
+
+
+def add_channeling_constraint(home, per, Teams, Weeks, Periods, s):
+    add_domain_constrain(per, Teams, Weeks, Periods, tagged(s, "add_domain_constrain"))
+    constraint_period_consistency(home, per, Teams, Weeks, Periods, tagged(s, "constraint_period_consistency"))
+
+
+# -------------------
+# IMPLIED CONSTRAINTS
+# -------------------
+
+def constraint_two_teams_per_period(per, Teams, Weeks, Periods, s):
+    bits = width(len(Teams))
+    for w in Weeks:
+        for p in Periods:
+            # Count how many teams play in period p in week w
+            s.add(bv_count([per[i][w] == p for i in Teams], bits) == 2)
+
+
+def add_implied_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_two_teams_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_two_teams_per_period"))
+    constrain_home_symmetry(home, Teams, Weeks, tagged(s, "constrain_home_symmetry"))
+
+
+# -----------------------
+# OPTIMIZATION CONSTRAINT
+# -----------------------
+
+def add_max_diff_constraint(home, Teams, Weeks, max_diff, s):
+    total_games = len(Weeks)
+    bits = width(total_games)
+    min_home = (total_games - max_diff + 1) // 2
+    max_home = (total_games + max_diff) // 2
+
+    for i in Teams:
+        home_games = bv_count([home[i][j][w] for j in Teams if j != i for w in Weeks], bits)
+        s.add(UGE(home_games, min_home))
+        s.add(ULE(home_games, max_home))
@@ -0,0 +1,157 @@
+from source.SAT.model.sat_model import totalizer
+from source.SMT.model.smt_model import get_params, create_variables, add_period_order_constraint, tagged
+from itertools import combinations
+from z3 import *
+
+# Every arithmetic atom is of the form x - y <= c (or a bound on a single variable),
+# so Z3 can use its difference logic solver instead of the general simplex
+LOGIC = "QF_IDL"
+
+
+# -----------------------
+# CARDINALITY CONSTRAINTS
+# -----------------------
+
+# Purely propositional: PB constraints would leave the difference logic fragment
+
+def at_most_one(bool_vars):
+    return And([Or(Not(a), Not(b)) for a, b in combinations(bool_vars, 2)])
+
+def exactly_one(bool_vars):
+    return And(Or(bool_vars), at_most_one(bool_vars))
+
+
+# ----------------
+# HARD CONSTRAINTS
+# ----------------
+
+# (1) every team plays with every other team only once
+def constraint_each_pair_once(home, Teams, Weeks, s):
+    for i, j in combinations(Teams, 2):
+        s.add(exactly_one([home[i][j][w] for w in Weeks] + [home[j][i][w] for w in Weeks]))
+
+
+# (2) every team plays once a week
+def constraint_one_match_per_week(home, Teams, Weeks, s):
+    for i in Teams:
+        for w in Weeks:
+            week_match = []
+            for j in Teams:
+                if i != j:
+                    week_match.append(home[i][j][w])
+                    week_match.append(home[j][i][w])
+            s.add(exactly_one(week_match))
+
+
+# (3) every team plays at most twice in the same period over the tournament:
+# no three weeks share the same period
+def constraint_max_two_per_period(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for w1, w2, w3 in combinations(Weeks, 3):
+            s.add(Not(And(per[i][w1] == per[i][w2], per[i][w2] == per[i][w3])))
+
+
+def add_hard_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_each_pair_once(home, Teams, Weeks, tagged(s, "constraint_each_pair_once"))
+    constraint_one_match_per_week(home, Teams, Weeks, tagged(s, "constraint_one_match_per_week"))
+    constraint_max_two_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_max_two_per_period"))
+
+
+# -----------------
+# DOMAIN CONSTRAINT
+# -----------------
+def add_domain_constrain(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(And(per[i][w] >= 0, per[i][w] <= len(Periods) - 1))
+
+
+# ----------------------
+# CHANNELING CONSTRAINT
+# ----------------------
+
+def constraint_period_consistency(home, per, Teams, Weeks, Periods, s):
+    for w in Weeks:
+        for i, j in combinations(Teams, 2):
+            plays_together = Or(home[i][j][w], home[j][i][w])
+            s.add(Implies(plays_together, per[i][w] == per[j][w]))
+
+
+def add_channeling_constraint(home, per, Teams, Weeks, Periods, s):
+    add_domain_constrain(per, Teams, Weeks, Periods, tagged(s, "add_domain_constrain"))
+    constraint_period_consistency(home, per, Teams, Weeks, Periods, tagged(s, "constraint_period_consistency"))
+
+
+# -------------------
+# IMPLIED CONSTRAINTS
+# -------------------
+
+# Teams not playing each other are in different periods: with n/2 matches and n/2 periods,
+# every period hosts exactly one match (the counting constraint of the LIA model)
+def constraint_two_teams_per_period(home, per, Teams, Weeks, s):
+    for w in Weeks:
+        for i, j in combinations(Teams, 2):
+            s.add(Or(home[i][j][w], home[j][i][w], per[i][w] != per[j][w]))
+
+
+# home[i,j,w] ==> Not(home[j,i,w])
+def constrain_home_symmetry(home, Teams, Weeks, s):
+    for i, j in combinations(Teams, 2):
+        for w in Weeks:
+            s.add(Or(Not(home[i][j][w]), Not(home[j][i][w])))
+
+
+def add_implied_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_two_teams_per_period(home, per, Teams, Weeks, tagged(s, "constraint_two_teams_per_period"))
+    constrain_home_symmetry(home, Teams, Weeks, tagged(s, "constrain_home_symmetry"))
+
+
+# -----------------------------
+# SYMMETRY BREAKING CONSTRAINTS
+# -----------------------------
+
+# (sb1) Fix the first match to be team 0 vs team 1 in period 0
+def add_sb1(home, per, s):
+    s.add(home[0][1][0])
+    s.add(per[0][0] == 0)
+    s.add(per[1][0] == 0)
+
+
+# (sb2) Fix opponents for team 0
+def add_sb2(home, Teams, Weeks, s):
+    for w in Weeks:
+        if w < len(Teams) - 1:
+            opponent = w + 1
+            s.add(Xor(home[0][opponent][w], home[opponent][0][w]))
+
+
+def add_team_order_constraint(home, Teams, Weeks, s):
+    for i, j in combinations(Teams, 2):
+        for w in Weeks:
+            s.add(Not(home[j][i][w]))
+
+
+def add_symmetry_breaking_constraints(home, per, Teams, Weeks, s, use_optimization):
+    add_sb1(home, per, tagged(s, "add_sb1"))
+    add_sb2(home, Teams, Weeks, tagged(s, "add_sb2"))
+    if not use_optimization:
+        add_team_order_constraint(home, Teams, Weeks, tagged(s, "add_team_order_constraint"))
+
+
+# -----------------------
+# OPTIMIZATION CONSTRAINT
+# -----------------------
+
+# Home games are counted with a (propositional) totalizer over the weekly home indicators
+def add_max_diff_constraint(home, Teams, Weeks, max_diff, s):
+    total_games = len(Weeks)
+    min_home = (total_games - max_diff + 1) // 2
+    max_home = (total_games + max_diff) // 2
+
+    for i in Teams:
+        home_week = [Or([home[i][j][w] for j in Teams if j != i]) for w in Weeks]
+        counts = totalizer(home_week, f"tot_{i}_{max_diff}", tagged(s, "totalizer"))
+        if min_home >= 1:
+            s.add(counts[min_home])
+        if max_home < total_games:
+            s.add(Not(counts[max_home + 1]))
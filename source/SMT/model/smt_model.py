@@ -58,6 +58,12 @@ def exactly_k(bool_vars, k, name=None):
     return And(at_most_k(bool_vars, k), at_least_k(bool_vars, k))
 
 
+# The solver a constraint function adds to: the solver wrappers of build_model (UNSAT core
+# tracking, lazy constraints) are labelled with the function name, a plain solver is kept
+def tagged(s, constraint):
+    return s.tagged(constraint) if hasattr(s, "tagged") else s
+
+
 # ----------------
 # HARD CONSTRAINTS
 # ----------------
@@ -90,9 +96,9 @@ def constraint_max_two_per_period(per, Teams, Weeks, Periods, s):
 
 
 def add_hard_constraints(home, per, Teams, Weeks, Periods, s):
-    constraint_each_pair_once(home, Teams, Weeks, s)
-    constraint_one_match_per_week(home, Teams, Weeks, s)
-    constraint_max_two_per_period(per, Teams, Weeks, Periods, s)
+    constraint_each_pair_once(home, Teams, Weeks, tagged(s, "constraint_each_pair_once"))
+    constraint_one_match_per_week(home, Teams, Weeks, tagged(s, "constraint_one_match_per_week"))
+    constraint_max_two_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_max_two_per_period"))
 
 
 # -----------------
@@ -118,7 +124,7 @@ def constraint_period_consistency(home, per, Teams, Weeks, Periods, s):
 
 
 def add_channeling_constraint(home, per, Teams, Weeks, Periods, s):
-    constraint_period_consistency(home, per, Teams, Weeks, Periods, s)
+    constraint_period_consistency(home, per, Teams, Weeks, Periods, tagged(s, "constraint_period_consistency"))
 
 
 # -------------------
@@ -145,8 +151,8 @@ def constrain_home_symmetry(home, Teams, Weeks, s):
 
 
 def add_implied_constraints(home, per, Teams, Weeks, Periods, s):
-    constraint_two_teams_per_period(per, Teams, Weeks, Periods, s)
-    constrain_home_symmetry(home, Teams, Weeks, s) 
+    constraint_two_teams_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_two_teams_per_period"))
+    constrain_home_symmetry(home, Teams, Weeks, tagged(s, "constrain_home_symmetry"))
 
 
 
@@ -180,11 +186,20 @@ def add_team_order_constraint(home, Teams, Weeks, s):
 
 
 
+# (sb3) Periods are interchangeable: order them by the lowest team playing in them in week 0.
+# Expressed on per[i][w] == p only, so it is shared by the variants with a period term per team
+def add_period_order_constraint(per, Teams, Periods, s):
+    for p in Periods[:-1]:
+        for i in Teams:
+            s.add(Implies(per[i][0] == p + 1, Or([per[j][0] == p for j in Teams if j < i])))
+
+
+
 def add_symmetry_breaking_constraints(home, per, Teams, Weeks, s, use_optimization):
-    add_sb1(home, per, s)
-    add_sb2(home, Teams, Weeks, s)
+    add_sb1(home, per, tagged(s, "add_sb1"))
+    add_sb2(home, Teams, Weeks, tagged(s, "add_sb2"))
     if not use_optimization:
-        add_team_order_constraint(home, Teams, Weeks, s)
+        add_team_order_constraint(home, Teams, Weeks, tagged(s, "add_team_order_constraint"))
 
 
 
@@ -203,8 +218,29 @@ def add_max_diff_constraint(home, Teams, Weeks, max_diff, s):
             for w in Weeks:
                 home_games.append(home[i][j][w])
         
-        min_home = (total_games - max_diff) // 2
+        # |2 * home - total_games| <= max_diff, rounding min_home up
+        min_home = (total_games - max_diff + 1) // 2
         max_home = (total_games + max_diff) // 2
         
         s.add(at_least_k(home_games, min_home))
         s.add(at_most_k(home_games, max_home))
+
+
+def max_imbalance_objective(home, Teams, Weeks):
+    """
+    Returns the integer term max_imbalance with the constraints defining it as an upper
+    bound on every team's |2 * home_games - weeks| (tight when minimized).
+
+    Returns:
+        tuple: (max_imbalance, list of constraints)
+    """
+    total_games = len(Weeks)
+    max_imbalance = Int("max_imbalance")
+    constraints = []
+
+    for i in Teams:
+        home_games = Sum([If(home[i][j][w], 1, 0) for j in Teams if j != i for w in Weeks])
+        constraints.append(max_imbalance >= 2 * home_games - total_games)
+        constraints.append(max_imbalance >= total_games - 2 * home_games)
+
+    return max_imbalance, constraints
@@ -0,0 +1,95 @@
+from source.SMT.model.smt_model import get_params, create_variables, constraint_each_pair_once, \
+    constraint_one_match_per_week, constrain_home_symmetry, add_symmetry_breaking_constraints, \
+    add_max_diff_constraint, tagged
+from z3 import *
+
+# Counting constraints are Z3's native pseudo-Boolean atoms (PbLe / PbEq) over Boolean
+# period indicators, instead of integer sums of if-then-else terms
+
+
+# ------------------
+# DECISION VARIABLES
+# ------------------
+
+# The home / period variables of the LIA model are kept (read by the objective and the decoding)
+
+def in_period(i, w, p):
+    """
+    Indicator true if team i plays in period p in week w (recreated by name, so that every
+    constraint function sees the same variables).
+    """
+    return Bool(f"x_{i}_{w}_{p}")
+
+
+# ----------------
+# HARD CONSTRAINTS
+# ----------------
+
+# (1) and (2) are the PB constraints of the LIA model over the home variables
+
+# (3) every team plays at most twice in the same period over the tournament
+def constraint_max_two_per_period(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for p in Periods:
+            s.add(PbLe([(in_period(i, w, p), 1) for w in Weeks], 2))
+
+
+def add_hard_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_each_pair_once(home, Teams, Weeks, tagged(s, "constraint_each_pair_once"))
+    constraint_one_match_per_week(home, Teams, Weeks, tagged(s, "constraint_one_match_per_week"))
+    constraint_max_two_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_max_two_per_period"))
+
+
+# ----------------------
+# CHANNELING CONSTRAINT
+# ----------------------
+
+# Every team is in exactly one period per week, the one of its period variable
+def constraint_period_indicators(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(PbEq([(in_period(i, w, p), 1) for p in Periods], 1))
+            for p in Periods:
+                s.add(in_period(i, w, p) == (per[i][w] == p))
+
+
+# Opponents play in the same period
+def constraint_period_consistency(home, Teams, Weeks, Periods, s):
+    for w in Weeks:
+        for i in Teams:
+            for j in Teams:
+                if i < j:
+                    plays_together = Or(home[i][j][w], home[j][i][w])
+                    for p in Periods:
+                        s.add(Implies(plays_together, in_period(i, w, p) == in_period(j, w, p)))
+
+
+def add_channeling_constraint(home, per, Teams, Weeks, Periods, s):
+    constraint_period_indicators(per, Teams, Weeks, Periods, tagged(s, "constraint_period_indicators"))
+    constraint_period_consistency(home, Teams, Weeks, Periods, tagged(s, "constraint_period_consistency"))
+
+
+# -------------------
+# IMPLIED CONSTRAINTS
+# -------------------
+
+def constraint_two_teams_per_period(Teams, Weeks, Periods, s):
+    for w in Weeks:
+        for p in Periods:
+            s.add(PbEq([(in_period(i, w, p), 1) for i in Teams], 2))
+
+
+def add_implied_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_two_teams_per_period(Teams, Weeks, Periods, tagged(s, "constraint_two_teams_per_period"))
+    constrain_home_symmetry(home, Teams, Weeks, tagged(s, "constrain_home_symmetry"))
+
+
+# -----------------------------
+# SYMMETRY BREAKING CONSTRAINTS
+# -----------------------------
+
+# (sb3) Periods ordered by their lowest team in week 0, on the period indicators
+def add_period_order_constraint(per, Teams, Periods, s):
+    for p in Periods[:-1]:
+        for i in Teams:
+            s.add(Implies(in_period(i, 0, p + 1), Or([in_period(j, 0, p) for j in Teams if j < i])))
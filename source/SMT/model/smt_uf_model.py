@@ -0,0 +1,119 @@
+from source.SMT.model.smt_model import get_params, constraint_two_teams_per_period, \
+    add_symmetry_breaking_constraints, add_period_order_constraint, tagged
+from z3 import *
+
+# The schedule is given by uninterpreted functions over (team, week) instead of one variable
+# per (team, team, week): the formula only grows with n^2 applications
+LOGIC = "QF_UFLIA"
+
+# opp(i, w): opponent of team i in week w
+opp = Function("opp", IntSort(), IntSort(), IntSort())
+# period(i, w): period in which team i plays in week w
+period = Function("period", IntSort(), IntSort(), IntSort())
+# hosts(i, w): true if team i plays at home in week w
+hosts = Function("hosts", IntSort(), IntSort(), BoolSort())
+
+
+# ------------------
+# DECISION VARIABLES
+# ------------------
+
+def create_variables(Teams, Weeks, Periods):
+    # home[i][j][w] and per[i][w] are terms over the functions, read by the objective and the decoding
+    home = []
+    for i in Teams:
+        home_row = []
+        for j in Teams:
+            if i == j:
+                home_row.append([])  # empty list for i = j
+            else:
+                home_row.append([And(opp(i, w) == j, hosts(i, w)) for w in Weeks])
+        home.append(home_row)
+
+    per = [[period(i, w) for w in Weeks] for i in Teams]
+
+    return home, per
+
+
+# ----------------
+# HARD CONSTRAINTS
+# ----------------
+
+# (1) every team plays with every other team only once: its n-1 opponents are distinct
+def constraint_each_pair_once(Teams, Weeks, s):
+    for i in Teams:
+        s.add(Distinct([opp(i, w) for w in Weeks]))
+
+
+# (2) every team plays once a week: opp is an involution without fixed points in every week
+def constraint_one_match_per_week(Teams, Weeks, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(opp(i, w) != i)
+            s.add(opp(opp(i, w), w) == i)
+
+
+# (3) every team plays at most twice in the same period over the tournament
+def constraint_max_two_per_period(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for p in Periods:
+            s.add(Sum([If(per[i][w] == p, 1, 0) for w in Weeks]) <= 2)
+
+
+def add_hard_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_each_pair_once(Teams, Weeks, tagged(s, "constraint_each_pair_once"))
+    constraint_one_match_per_week(Teams, Weeks, tagged(s, "constraint_one_match_per_week"))
+    constraint_max_two_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_max_two_per_period"))
+
+
+# -----------------
+# DOMAIN CONSTRAINT
+# -----------------
+
+# Finite-domain axioms: the functions only range over teams and periods on the (team, week) grid
+def add_domain_constrain(per, Teams, Weeks, Periods, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(And(opp(i, w) >= 0, opp(i, w) < len(Teams)))
+            s.add(And(per[i][w] >= 0, per[i][w] < len(Periods)))
+
+
+# ----------------------
+# CHANNELING CONSTRAINT
+# ----------------------
+
+# Opponents play in the same period, exactly one of them at home
+def constraint_match_consistency(Teams, Weeks, s):
+    for i in Teams:
+        for w in Weeks:
+            s.add(period(opp(i, w), w) == period(i, w))
+            s.add(hosts(opp(i, w), w) == Not(hosts(i, w)))
+
+
+def add_channeling_constraint(home, per, Teams, Weeks, Periods, s):
+    add_domain_constrain(per, Teams, Weeks, Periods, tagged(s, "add_domain_constrain"))
+    constraint_match_consistency(Teams, Weeks, tagged(s, "constraint_match_consistency"))
+
+
+# -------------------
+# IMPLIED CONSTRAINTS
+# -------------------
+
+def add_implied_constraints(home, per, Teams, Weeks, Periods, s):
+    constraint_two_teams_per_period(per, Teams, Weeks, Periods, tagged(s, "constraint_two_teams_per_period"))
+
+
+# -----------------------
+# OPTIMIZATION CONSTRAINT
+# -----------------------
+
+# Home games are counted on hosts(i, w), one term per week
+def add_max_diff_constraint(home, Teams, Weeks, max_diff, s):
+    total_games = len(Weeks)
+    min_home = (total_games - max_diff + 1) // 2
+    max_home = (total_games + max_diff) // 2
+
+    for i in Teams:
+        home_games = Sum([If(hosts(i, w), 1, 0) for w in Weeks])
+        s.add(home_games >= min_home)
+        s.add(home_games <= max_home)
@@ -0,0 +1,47 @@
+from z3 import *
+import math
+import time
+
+
+def relaxation_lower_bound(n_teams, timeout=10):
+    """
+    Real relaxation probe of the max imbalance: the home indicators become reals in [0, 1]
+    under the pair and weekly constraints (the period constraints do not involve the home
+    games), and the max |2 * home_games - weeks| is minimized by the simplex of z3.Optimize.
+
+    Its optimum rounded up is a valid lower bound of the integer problem; since every team
+    plays an odd number of games, the imbalance is odd and the bound is rounded up to odd.
+
+    Returns:
+        dict: {"bound", "value", "time"}; bound is 1 (the trivial bound) if the probe times out
+    """
+    start_time = time.time()
+    Teams = range(n_teams)
+    Weeks = range(n_teams - 1)
+
+    x = {(i, j, w): Real(f"rx_{i}_{j}_{w}") for i in Teams for j in Teams if i != j for w in Weeks}
+    t = Real("relaxed_imbalance")
+
+    optimizer = Optimize()
+    optimizer.set("timeout", int(timeout * 1000))
+    optimizer.add([And(v >= 0, v <= 1) for v in x.values()])
+    for i in Teams:
+        for j in Teams:
+            if i < j:
+                optimizer.add(Sum([x[i, j, w] + x[j, i, w] for w in Weeks]) == 1)
+        for w in Weeks:
+            optimizer.add(Sum([x[i, j, w] + x[j, i, w] for j in Teams if j != i]) == 1)
+        home_games = Sum([x[i, j, w] for j in Teams if j != i for w in Weeks])
+        optimizer.add(t >= 2 * home_games - len(Weeks), t >= len(Weeks) - 2 * home_games)
+    optimizer.minimize(t)
+
+    bound, value = 1, None
+    if optimizer.check() == sat:
+        relaxed = optimizer.model().eval(t, model_completion=True)
+        value = float(relaxed.as_fraction())
+        bound = math.ceil(value - 1e-9)
+        if len(Weeks) % 2 and bound % 2 == 0:
+            bound += 1
+        bound = max(1, bound)
+
+    return {"bound": bound, "value": value, "time": round(time.time() - start_time, 3)}
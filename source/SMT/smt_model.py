@@ -1,6 +1,9 @@
-from source.SMT.instance_solver import solve_instance 
-from source.SMT.build_model import build_model        
+from source.SMT.instance_solver import solve_instance, SOLVERS, STRATEGIES
+from source.SMT.build_model import build_model, VARIANTS, clear_formula_cache
 from source.SMT import smt_utils as utils             
+from source.common.metadata import run_metadata
+from source.results.logs import open_log, close_log
+from source.common.trace import pop_trace
 import os.path as pt
 from z3 import *
 import os, time
@@ -11,30 +14,68 @@ current_dir = os.getcwd()
 DEFAULT_SMT_OUTPUT_DIR = os.path.join(current_dir, 'res/SMT') 
 
 
-def smt_solver(n_teams, solver_name, use_sb=False, use_optimization=False):
+def smt_solver(n_teams, solver_name, use_sb=False, use_optimization=False, options=None):
     """
     Solves the SMT model using Z3.
     
     Params:
         n_teams: Number of teams
-        solver_name: The solver name (always "z3")
+        solver_name: The solver name ("z3" or an SMT-LIB2 backend: "optimathsat", "cvc5")
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
+        options: Dictionary of solver options (variant)
     
     Returns:
         dict: Result object containing solution and statistics
     """
     
-    if solver_name.lower() != "z3":
-        raise ValueError(f"Solver {solver_name} not supported for SMT. Use 'z3'")
+    if solver_name.lower() not in SOLVERS:
+        raise ValueError(f"Solver {solver_name} not supported for SMT. Use one of {', '.join(SOLVERS)}")
 
     # Solve the instance
-    result = solve_instance(n_teams, solver_name, use_sb, use_optimization, None)
+    result = solve_instance(n_teams, solver_name, use_sb, use_optimization, None, options)
     
     return result
 
 
-def run_model(results_dict, n, solver, sb=False, opt=False):
+def result_key(solver, sb, opt, options=None):
+    """
+    Returns the result key of a configuration: non-default encodings, the period ordering
+    and (Z3) optimization strategies get their own entry.
+    """
+    variant = (options or {}).get("variant") or "lia"
+    strategy = (options or {}).get("strategy") or "binary"
+    suffix = [variant] if variant != "lia" else []
+    if sb and (options or {}).get("period_order"):
+        suffix.append("po")
+    if opt and solver == "z3" and strategy != "binary":
+        suffix.append(strategy)
+    return utils.make_key(solver, sb, opt, "_".join(suffix) or None)
+
+
+def variant_options(options=None):
+    """
+    Returns the options of every variant to run: with variant "compare" all the encodings
+    (one per theory) are run, otherwise only the selected one.
+    """
+    if (options or {}).get("variant") != "compare":
+        return [options]
+    return [{**options, "variant": variant} for variant in VARIANTS]
+
+
+def compare_variants(n, results_dict, solver, options):
+    """
+    Prints the best encoding of every configuration of an instance size (compared on the
+    default optimization strategy).
+    """
+    runs = [{**run, "strategy": None} for run in variant_options(options)]
+    for sb in [False, True]:
+        for opt in [False, True]:
+            compared = {run["variant"]: results_dict.get(result_key(solver, sb, opt, run)) for run in runs}
+            print(f"n={n} {solver} sb={sb} opt={opt}: best encoding = {utils.best_strategy(compared)}")
+
+
+def run_model(results_dict, n, solver, sb=False, opt=False, options=None):
     """
     Runs the SMT model with the given parameters and updates the results dictionary.
     Params:
@@ -43,10 +84,14 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
         solver: Solver to use ("z3")
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization
+        options: Dictionary of solver options (variant, strategy)
     Returns:
         results_dict: Updated dictionary with results for the given configuration
     """
-    key = utils.make_key(solver, sb, opt)
+    variant = (options or {}).get("variant") or "lia"
+    strategy = (options or {}).get("strategy") or "binary"
+    key = result_key(solver, sb, opt, options)
+    log = open_log("SMT", n, key)
 
     try:
         print(
@@ -55,9 +100,11 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
             f"\n  - solver = {solver}"
             f"\n  - symmetry breaking = {sb}"
             f"\n  - optimization = {opt}"
+            f"\n  - encoding = {variant}"
+            + (f"\n  - strategy = {strategy}" if opt and solver == "z3" else "")
         )
 
-        result = smt_solver(n, solver, sb, opt) 
+        result = smt_solver(n, solver, sb, opt, options) 
 
         time, optimal, solution, obj = utils.process_result(result, opt)
 
@@ -67,8 +114,10 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
             "sol": solution,
             "time": time,
             "optimal": optimal,
-            "obj": obj
+            "obj": obj,
+            "params": result.get("extra_params", {}).get("solver_params")
         }
+        results_dict[key]["trace"] = pop_trace(results_dict[key]["params"])
 
     except Exception as e:
         print(f"Error in {key} for n={n}: {e}")
@@ -79,10 +128,18 @@ def run_model(results_dict, n, solver, sb=False, opt=False):
             "obj": None
         }
     
+    # The seed is the random_seed among the KEY=VALUE Z3 parameters, if given
+    seed = next((param.split("=", 1)[1] for param in (options or {}).get("z3_params") or []
+                 if param.split("=", 1)[0].endswith("random_seed")), None)
+    close_log()
+    results_dict[key]["metadata"] = run_metadata(
+        "SMT", solver, get_version_string() if solver == "z3" else None, variant=variant,
+        seed=seed, threads=(options or {}).get("threads"), log=log)
+
     return results_dict
 
 
-def run_single_instance(n, solver, use_sb=False, use_optimization=False):
+def run_single_instance(n, solver, use_sb=False, use_optimization=False, options=None):
     """
     Runs a single instance of the SMT model with the given parameters.
 
@@ -91,6 +148,7 @@ def run_single_instance(n, solver, use_sb=False, use_optimization=False):
         solver: The solver to use ("z3")
         use_sb: Whether to use symmetry breaking
         use_optimization: Whether to use optimization techniques
+        options: Dictionary of solver options (variant, "compare" runs every variant)
     """
 
     if solver is None:
@@ -101,28 +159,60 @@ def run_single_instance(n, solver, use_sb=False, use_optimization=False):
 
     results_dict = {}
 
-    results_dict = run_model(results_dict, n, solver, use_sb, use_optimization)
+    for run_options in variant_options(options):
+        results_dict = run_model(results_dict, n, solver, use_sb, use_optimization, run_options)
 
     utils.write_solution(output_dir, n, results_dict)
 
 
-def run_all():
+def run_all(options=None):
     """
     Runs all configurations for the SMT model.
+
+    Params:
+        options: Dictionary of solver options (variant, strategy). With strategy "compare",
+                 the optimization configurations of Z3 are run with every strategy and the
+                 winner of each instance size is reported; with variant "compare" the same is
+                 done for the encodings.
     """
-    solvers = ["z3"] 
+    # External solvers are only run when installed
+    solvers = [name for name, path in SOLVERS.items() if path is None or os.path.exists(path)]
     instances = [6, 8, 10, 12, 14, 16]
     output_dir = DEFAULT_SMT_OUTPUT_DIR
     os.makedirs(output_dir, exist_ok=True)
 
     for n in instances:
         results_dict = {}
+        # Formulas are only shared between the configurations of the same instance
+        clear_formula_cache()
+
+        compare = (options or {}).get("strategy") == "compare"
 
         for solver in solvers:
             for sb in [False, True]:
                 for opt in [False, True]: 
-                    results_dict = run_model(results_dict, n, solver, sb, opt)
-
-                    gc.collect()
-
-        utils.write_solution(output_dir, n, results_dict)
\ No newline at end of file
+                    strategies = STRATEGIES if (compare and opt and solver == "z3") else [None]
+                    for strategy in strategies:
+                        for run_options in variant_options(options):
+                            if compare:
+                                run_options = {**(run_options or {}), "strategy": strategy}
+                            results_dict = run_model(results_dict, n, solver, sb, opt, run_options)
+
+                            gc.collect()
+
+        utils.write_solution(output_dir, n, results_dict)
+
+        if compare:
+            for run_options in variant_options(options):
+                for sb in [False, True]:
+                    compared = {strategy: results_dict.get(result_key("z3", sb, True,
+                                                                      {**run_options, "strategy": strategy}))
+                                for strategy in STRATEGIES}
+                    winner = utils.best_strategy(compared)
+                    counts = ", ".join(f"{strategy}={utils.query_count(entry)}" for strategy, entry in compared.items())
+                    print(f"n={n} {run_options['variant']} sb={sb}: best optimization strategy = {winner} "
+                          f"(queries: {counts})")
+
+        if (options or {}).get("variant") == "compare":
+            for solver in solvers:
+                compare_variants(n, results_dict, solver, options)
\ No newline at end of file
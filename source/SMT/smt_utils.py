@@ -1,7 +1,8 @@
+from source.SMT.z3_compat import evaluate
 from z3 import *
 import json
 import math
-import os
+from source.results.writer import write_solution
 
 
 def print_solution(time, optimal, solution, obj):
@@ -59,7 +60,14 @@ def parse_solution(result):
         schedule_periods[p][w] = [home_team, away_team]  (team index 1-based)
     """
 
-    if result["status"] != sat or "model" not in result:
+    if result["status"] != sat:
+        return []
+
+    # Already decoded schedule (external SMT-LIB2 solvers)
+    if result.get("schedule") is not None:
+        return result["schedule"]
+
+    if "model" not in result:
         return []
 
     model = result["model"]
@@ -79,8 +87,8 @@ def parse_solution(result):
                     continue
 
                 # Check if i plays at home vs j in week w
-                if model.evaluate(home[i][j][w], model_completion=True):
-                    period_val = model.evaluate(per[i][w]).as_long()
+                if evaluate(model, home[i][j][w]):
+                    period_val = evaluate(model, per[i][w])
 
                     # Place the match [home, away] in the right period and week
                     if schedule_periods[period_val][w] is None:
@@ -90,7 +98,19 @@ def parse_solution(result):
 
 
 
-def make_key(solver_name, sb, opt):
+def load_z3_config(path):
+    """
+    Loads a JSON dictionary of Z3 parameters, e.g. {"random_seed": 7, "smt.arith.solver": 6,
+    "smt.relevancy": 0, "restart.max": 100}.
+    """
+    with open(path) as f:
+        config = json.load(f)
+    if not isinstance(config, dict) or any(isinstance(v, (dict, list)) for v in config.values()):
+        raise ValueError(f"{path} must contain a dictionary of scalar Z3 parameters")
+    return config
+
+
+def make_key(solver_name, sb, opt, variant=None):
     """
     Creates a unique key for the solver configuration.
 
@@ -98,6 +118,7 @@ def make_key(solver_name, sb, opt):
         solver_name: String name of the solver (e.g., "z3").
         sb: Boolean indicating if symmetry breaking is used.
         opt: Boolean indicating if optimization is used.
+        variant: Optional encoding variant suffix (e.g., "idl").
 
     Returns:
         A string key representing the solver configuration.
@@ -109,35 +130,12 @@ def make_key(solver_name, sb, opt):
         "sb" if sb else "nosb",
         "opt" if opt else "noopt"
     ]
+    if variant:
+        parts.append(variant)
 
     return "_".join(parts)
 
 
-def write_solution(output_dir, n, results_dict):
-    """
-    Writes the results to a JSON file.
-    Params:
-        output_dir: The directory where the results will be saved.
-        n: An identifier for the results (e.g., number of teams).
-        results_dict: A dictionary containing the results to be written.
-    """
-
-    out_path = os.path.join(output_dir, f"{n}.json")
-    with open(out_path, 'w') as f:
-        f.write('{\n')
-        for i, (key, val) in enumerate(results_dict.items()):
-            f.write(f'  "{key}": {{\n')
-
-            sol_str = json.dumps(val["sol"], separators=(',', ':'))
-            f.write(f'    "time": {val["time"]},\n')
-            f.write(f'    "optimal": {"true" if val["optimal"] else "false"},\n')
-            f.write(f'    "obj": {json.dumps(val["obj"])},\n')
-            f.write(f'    "sol": {sol_str}\n')
-
-            f.write('  }' + (',' if i < len(results_dict) - 1 else '') + '\n')
-        f.write('}\n')
-
-
 def process_result(result, use_optimization):
     """
     Processes the result from a SAT solver with validation
@@ -154,13 +152,38 @@ def process_result(result, use_optimization):
     if use_optimization and has_solution:
         max_diff = result["extra_params"].get("max_diff")
         obj = max_diff
-        is_optimal = (obj == 1)
+        # 1 is the trivial lower bound; OMT backends also prove other optima
+        is_optimal = (obj == 1) or bool(result["extra_params"].get("proven_optimal"))
     else:
         is_optimal = has_solution
         obj = None
 
-    if time_val >= 300:
+    if time_val >= 300 or not is_optimal:
         is_optimal = False
+        time_val = 300
 
     
-    return time_val, is_optimal, solution, obj
\ No newline at end of file
+    return time_val, is_optimal, solution, obj
+
+
+def query_count(entry):
+    """
+    Number of solver queries of a result entry: checked bounds, or optimizer chunks for omt.
+    """
+    params = (entry or {}).get("params") or {}
+    if "queries" in params:
+        return len(params["queries"])
+    return params.get("chunks")
+
+
+def best_strategy(entries):
+    """
+    Returns the name of the best of {strategy: result entry}: proven optimal first,
+    then lowest objective, then fastest.
+    """
+    entries = {name: val for name, val in entries.items() if val is not None and val["sol"]}
+    if not entries:
+        return None
+    return min(entries, key=lambda name: (not entries[name]["optimal"],
+                                          entries[name]["obj"] if entries[name]["obj"] is not None else math.inf,
+                                          entries[name]["time"]))
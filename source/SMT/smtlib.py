@@ -0,0 +1,156 @@
+from z3 import *
+import re
+import os
+
+# Formulas exported with options["export_smt2"]
+DEFAULT_EXPORT_DIR = os.path.join(os.getcwd(), "artifacts/SMT")
+
+# Pseudo-Boolean operators are Z3 extensions, printed as ((_ pbeq ...) ...) by sexpr()
+PB_OPS = (Z3_OP_PB_LE, Z3_OP_PB_GE, Z3_OP_PB_EQ, Z3_OP_PB_AT_MOST, Z3_OP_PB_AT_LEAST)
+
+
+def _linear(expr, op):
+    params = expr.params()
+    k = params[0]
+    coefficients = params[1:] if op in (Z3_OP_PB_LE, Z3_OP_PB_GE, Z3_OP_PB_EQ) else []
+    coefficients = coefficients or [1] * expr.num_args()
+    total = Sum([If(arg, c, 0) for arg, c in zip(expr.children(), coefficients)])
+    if op in (Z3_OP_PB_LE, Z3_OP_PB_AT_MOST):
+        return total <= k
+    if op in (Z3_OP_PB_GE, Z3_OP_PB_AT_LEAST):
+        return total >= k
+    return total == k
+
+
+def portable(expr):
+    """
+    Rewrites the Z3-specific pseudo-Boolean atoms of an expression into standard
+    linear integer arithmetic (sums of if-then-else terms), so that the formula can be
+    read by any SMT-LIB2 solver.
+    """
+    replacements, seen, stack = [], set(), [expr]
+    while stack:
+        e = stack.pop()
+        if not is_app(e) or e.get_id() in seen:
+            continue
+        seen.add(e.get_id())
+        op = next((op for op in PB_OPS if is_app_of(e, op)), None)
+        if op is not None:
+            replacements.append((e, _linear(e, op)))
+        else:
+            stack.extend(e.children())
+    return substitute(expr, *replacements) if replacements else expr
+
+
+def to_smtlib(assertions, logic=None, objective=None, values=None):
+    """
+    Builds an SMT-LIB2 script from a list of Z3 assertions.
+
+    Params:
+        assertions: Z3 Boolean expressions
+        logic: Optional logic for (set-logic ...)
+        objective: Optional integer term to minimize, with the OMT (minimize ...) extension
+        values: Optional terms whose values are requested after a sat answer
+    Returns:
+        str: The script
+    """
+    solver = Solver()
+    solver.add([portable(a) for a in assertions])
+
+    lines = ["(set-option :produce-models true)"]
+    if logic:
+        lines.append(f"(set-logic {logic})")
+    lines.append(solver.sexpr())
+    if objective is not None:
+        lines.append(f"(minimize {objective.sexpr()})")
+    lines.append("(check-sat)")
+    if objective is not None:
+        lines.append("(get-objectives)")
+    if values:
+        lines.append(f"(get-value ({' '.join(v.sexpr() for v in values)}))")
+    lines.append("(exit)")
+
+    return "\n".join(lines) + "\n"
+
+
+def parse_status(output):
+    """
+    Returns the first sat / unsat / unknown answer of a solver output.
+    """
+    for line in output.splitlines():
+        if line.strip() in ("sat", "unsat", "unknown"):
+            return line.strip()
+    return "unknown"
+
+
+def parse_values(output):
+    """
+    Parses the ((name value) ...) pairs of a (get-value ...) answer into Python values
+    (bool, int; bitvector literals #b.., #x.. and (_ bvN w) are converted to int).
+    """
+    values = {}
+    for name, value in re.findall(r"\(\s*([A-Za-z_][\w]*)\s+(true|false|-?\d+|#b[01]+|#x[0-9a-fA-F]+|"
+                                  r"\(_ bv\d+ \d+\)|\(- \d+\))\s*\)", output):
+        if value in ("true", "false"):
+            values[name] = value == "true"
+        elif value.startswith("#b"):
+            values[name] = int(value[2:], 2)
+        elif value.startswith("#x"):
+            values[name] = int(value[2:], 16)
+        elif value.startswith("(_ bv"):
+            values[name] = int(value.split()[1][2:])
+        elif value.startswith("(-"):
+            values[name] = -int(value[3:-1])
+        else:
+            values[name] = int(value)
+    return values
+
+
+def schedule_from_values(values, Teams, Weeks, Periods):
+    """
+    Decodes the home/period values of a (get-value ...) answer into the schedule
+    schedule_periods[p][w] = [home_team, away_team] (1-based teams).
+    """
+    schedule_periods = [[None for _ in Weeks] for _ in Periods]
+    for w in Weeks:
+        for i in Teams:
+            for j in Teams:
+                if i != j and values.get(f"h_{i}_{j}_{w}"):
+                    period_val = values.get(f"p_{i}_{w}")
+                    if period_val is not None and schedule_periods[period_val][w] is None:
+                        schedule_periods[period_val][w] = [i + 1, j + 1]
+    return schedule_periods
+
+
+def export_logic(model, objective=None):
+    """
+    Returns the SMT-LIB2 logic of a variant module (QF_LIA unless it declares one); with an
+    objective, whose term is linear integer arithmetic, QF_LIA also covers difference logic
+    and no logic is set for theory mixes.
+    """
+    logic = getattr(model, "LOGIC", "QF_LIA")
+    if objective is None:
+        return logic
+    return "QF_LIA" if logic in ("QF_LIA", "QF_IDL") else None
+
+
+def export_smt2(assertions, n_teams, extra_params, bound=None, logic=None, objective=None):
+    """
+    Writes the formula of a query in SMT-LIB2 under artifacts/SMT, named after the instance,
+    the configuration and the current bound (if any).
+
+    Returns:
+        str: The path of the written file
+    """
+    os.makedirs(DEFAULT_EXPORT_DIR, exist_ok=True)
+    name = (f"{n_teams}_{extra_params.get('variant', 'lia')}_{'sb' if extra_params.get('sb') else 'nosb'}"
+            f"_{'opt' if extra_params.get('opt') else 'noopt'}")
+    if bound is not None:
+        name += f"_le{bound}"
+    if objective is not None:
+        name += "_omt"
+    path = os.path.join(DEFAULT_EXPORT_DIR, f"{name}.smt2")
+
+    with open(path, "w") as f:
+        f.write(to_smtlib(assertions, logic=logic, objective=objective))
+    return path
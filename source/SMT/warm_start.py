@@ -0,0 +1,60 @@
+from source.common.heuristic import greedy_schedule, relabel_for_symmetry_breaking, order_periods, max_imbalance
+from source.SMT.z3_compat import set_initial_value
+from z3 import *
+
+
+def heuristic_assignment(n_teams, home, per, use_sb=False, use_optimization=False, time_limit=10,
+                         period_order=False):
+    """
+    Runs the greedy heuristic and maps its schedule onto the model variables (relabeled to
+    satisfy the symmetry breaking constraints when they are enabled).
+
+    Returns:
+        tuple: (assignment, imbalance) where assignment is a list of (variable, value) covering
+               every home and period variable, and imbalance the max imbalance of the schedule
+               (None if the heuristic did not repair all the period conflicts)
+    """
+    matches, valid = greedy_schedule(n_teams, time_limit=time_limit)
+    if use_sb:
+        matches = relabel_for_symmetry_breaking(matches, n_teams, team_order=not use_optimization)
+        if period_order:
+            matches = order_periods(matches)
+
+    hosts = {(h, a, w) for h, a, w, _ in matches}
+    assignment = []
+    for i in range(n_teams):
+        for j in range(n_teams):
+            if i != j:
+                for w, var in enumerate(home[i][j]):
+                    assignment.append((var, BoolVal((i, j, w) in hosts)))
+    for h, a, w, p in matches:
+        for team in (h, a):
+            var = per[team][w]
+            assignment.append((var, var.sort().cast(p)))
+
+    return assignment, max_imbalance(matches, n_teams) if valid else None
+
+
+def add_phase_hints(solver, assignment):
+    """
+    Sets the heuristic values as initial phases of the solver variables (terms that are not
+    variables, as in the uf variant, are skipped).
+
+    Returns:
+        bool: Whether the Z3 version supports initial values
+    """
+    supported = True
+    for var, value in assignment:
+        if is_const(var) and not set_initial_value(solver, var, value):
+            supported = False
+            break
+    return supported
+
+
+def add_soft_hints(optimizer, assignment):
+    """
+    Adds the heuristic values as soft constraints of unit weight, in their own group so
+    that they only break ties after the max imbalance (declared first) is minimized.
+    """
+    for var, value in assignment:
+        optimizer.add_soft(var == value, 1, "warm_start")
@@ -0,0 +1,86 @@
+from z3 import *
+
+# Thin layer over the Z3 API calls of the SMT approach whose availability or names differ
+# between z3-solver 4.8 and 4.13, so that the module behaves the same on every Docker base
+
+# Alternative names of global parameters, tried in order
+PARAM_ALIASES = {
+    "parallel.threads.max": ["parallel.threads.max", "threads"],
+    "parallel.enable": ["parallel.enable"],
+}
+
+
+def version():
+    """
+    Returns the Z3 version as a (major, minor, build) tuple.
+    """
+    return tuple(get_version()[:3])
+
+
+def set_global_param(name, value):
+    """
+    Sets a global parameter under its current name or, on older versions, an alias.
+
+    Returns:
+        bool: Whether one of the names was accepted
+    """
+    for alias in PARAM_ALIASES.get(name, [name]):
+        try:
+            set_param(alias, value)
+            return True
+        except Z3Exception:
+            continue
+    return False
+
+
+def on_model(optimizer, callback):
+    """
+    Registers an improving-model callback on z3.Optimize (4.8.10 onwards).
+
+    Returns:
+        bool: Whether the callback is supported
+    """
+    if not hasattr(optimizer, "set_on_model"):
+        return False
+    optimizer.set_on_model(callback)
+    return True
+
+
+def set_initial_value(solver, var, value):
+    """
+    Sets the initial phase of a variable (4.13 onwards).
+
+    Returns:
+        bool: Whether initial values are supported
+    """
+    if not hasattr(solver, "set_initial_value"):
+        return False
+    solver.set_initial_value(var, value)
+    return True
+
+
+def evaluate(model, expr):
+    """
+    Evaluates a term in a model (with model completion) as a Python bool or int, or None when
+    the value is not a literal (e.g. on a model of a cancelled check).
+    """
+    value = model.eval(expr, model_completion=True)
+    if is_true(value) or is_false(value):
+        return is_true(value)
+    if is_int_value(value):
+        return value.as_long()
+    if is_bv_value(value):
+        return value.as_long()
+    return None
+
+
+def objective_lower(handle):
+    """
+    Lower bound of an Optimize objective as an int, or None (the handle returns a non-numeric
+    term or raises on some versions while the search is unfinished).
+    """
+    try:
+        lower = handle.lower()
+    except Z3Exception:
+        return None
+    return lower.as_long() if is_int_value(lower) else None
@@ -0,0 +1,394 @@
+from source.checker.fix import fix_file
+from source.checker.golden import regressions
+from source.checker.problems import DEFAULT_PROBLEM, PROBLEMS, problem_definition
+from source.checker.schema import RESULT_SCHEMA, reported_objective, validate
+import json
+import multiprocessing as mp
+import os
+import sys
+import time
+
+# Time limit of a run, in seconds: the time reported by the runs that did not terminate
+TIME_LIMIT = 300
+
+DEFAULT_RESULTS_DIR = os.path.join(os.getcwd(), "res")
+
+# Proven optima / best known bounds of the max imbalance per number of teams
+BOUNDS_FILE = os.path.join(os.path.dirname(os.path.abspath(__file__)), "bounds.json")
+
+
+def result_files(paths=None):
+    """
+    Returns the result JSON files to check: every file under res/ by default, otherwise the
+    given files and the files under the given directories (e.g. res/SAT).
+    """
+    files = []
+    for path in paths or [DEFAULT_RESULTS_DIR]:
+        if os.path.isdir(path):
+            for root, _, names in os.walk(path):
+                files.extend(os.path.join(root, name) for name in names if name.endswith(".json"))
+        else:
+            files.append(path)
+    return sorted(files)
+
+
+def time_errors(result):
+    """
+    Checks the time field against the project rules: a run that terminated (optimal, or solved
+    for the decision version) reports its time below the limit, any other one exactly the limit.
+
+    Returns:
+        list: The errors found
+    """
+    time, optimal = result["time"], result["optimal"]
+    if time < 0:
+        return [f"Negative running time {time}"]
+    if not optimal and time != TIME_LIMIT:
+        return [f"The run did not terminate (optimal is false) but reports time {time} instead of {TIME_LIMIT}"]
+    if optimal and time >= TIME_LIMIT:
+        return [f"The run claims to have terminated (optimal is true) but reports time {time}, "
+                f"not below the limit {TIME_LIMIT}"]
+    return []
+
+
+def objective_errors(result, problem):
+    """
+    Checks the reported objective against the objective of the problem recomputed from the
+    schedule.
+
+    Returns:
+        list: The errors found (empty if the objective agrees or is not reported)
+    """
+    reported = reported_objective(result, problem.objective_key)
+    if reported is None or not result["sol"]:
+        return []
+    value = problem.objective(result["sol"])
+    if value != reported:
+        return [f"The objective {reported} differs from the {problem.objective_name} {value} of the schedule"]
+    return []
+
+
+def load_bounds(path=BOUNDS_FILE):
+    """
+    Loads the bounds database: per number of teams, the proven "optimum" and "lower_bound" of
+    the max imbalance (when known), "feasible": false for the instances without schedules and
+    the "source" of the values.
+
+    Returns:
+        dict: The bounds, by number of teams
+    """
+    with open(path) as f:
+        return {int(n): entry for n, entry in json.load(f).items()}
+
+
+def bounds_errors(result, n, bounds):
+    """
+    Checks a (valid) result against the bounds database: a schedule for an infeasible instance
+    or below the lower bound is impossible, and an optimality claim above the proven optimum is
+    wrong.
+
+    Returns:
+        list: The errors found (empty if the instance is not in the database)
+    """
+    entry = bounds.get(n)
+    if entry is None:
+        return []
+    if entry.get("feasible") is False:
+        return [f"Schedule reported for n = {n}, which has none ({entry.get('source')})"] if result["sol"] else []
+
+    errors = []
+    imbalance = PROBLEMS["sts"].objective(result["sol"])
+    if imbalance is not None and imbalance < entry.get("lower_bound", 1):
+        errors.append(f"The max imbalance {imbalance} is below the lower bound {entry['lower_bound']} "
+                      f"({entry.get('source')})")
+    obj = reported_objective(result)
+    if result["optimal"] and obj is not None and "optimum" in entry and obj > entry["optimum"]:
+        errors.append(f"Optimality claimed at {obj}, worse than the proven optimum {entry['optimum']} "
+                      f"({entry.get('source')})")
+    return errors
+
+
+def result_errors(result, approach="result", problem=None):
+    """
+    Checks a single result: first against the result schema, then (if the format is valid) the
+    constraints of the problem (see problems.py) and the time, and finally the recomputed
+    objective.
+
+    Params:
+        problem: The problem definition (the STS problem by default)
+
+    Returns:
+        list: The errors found (empty for a valid result)
+    """
+    problem = problem or PROBLEMS[DEFAULT_PROBLEM]
+    errors = validate(result, RESULT_SCHEMA["values"], f"$.{approach}")
+    if not errors:
+        errors = problem.constraint_errors(result) + time_errors(result)
+    if not errors:
+        errors = objective_errors(result, problem)
+    return errors
+
+
+def checked_results(n, results_dict):
+    """
+    Validates the results of a run right before they are written: every invalid one is replaced
+    by an unsolved entry with "outcome": "internal error" and its errors, so that no invalid
+    result reaches res/.
+
+    Returns:
+        dict: The results to write
+    """
+    checked = {}
+    for key, result in results_dict.items():
+        # The solvers store the mandatory keys with Python types (e.g. tuples in sol)
+        serialized = json.loads(json.dumps({k: result.get(k) for k in ("time", "optimal", "obj", "sol")}))
+        errors = result_errors(serialized, key)
+        if errors:
+            print(f"Invalid result {key} for n={n}, written as an internal error:")
+            for error in errors:
+                print(f"  - {error}")
+            # Built in the normalized form of the writer: mandatory keys first, no None values
+            replacement = {"time": TIME_LIMIT, "optimal": False, "obj": None, "sol": [],
+                           "outcome": "internal error", "errors": errors}
+            if result.get("metadata") is not None:
+                replacement["metadata"] = result["metadata"]
+            result = replacement
+        checked[key] = result
+    return checked
+
+
+def explanation(result, problem):
+    """
+    Explains an invalid result with the violated constraint instances of its schedule and the
+    minimal matches involved (see Problem.explain), once its format and team indices are valid.
+
+    Returns:
+        list: The violations, empty if there is no explanation
+    """
+    if problem.explain is None or validate(result, RESULT_SCHEMA["values"]) or not result["sol"]:
+        return []
+    # The first stage validates what the explanation relies on (the team indices)
+    if problem.stages and any(check(result) for check in problem.stages[0]):
+        return []
+    return problem.explain(result)
+
+
+def check_file(path, bounds=None, problem=None):
+    """
+    Checks every approach of a result file (see result_errors) and against the bounds database
+    (the number of teams is the file name, e.g. 12.json) if it applies to the problem.
+
+    Returns:
+        list: One report {"file", "approach", "optimal", "obj", "value", "errors", "violations"}
+              per approach (obj reported, value recomputed from the schedule, violations explaining
+              an invalid schedule); a single report with the read or format error if the file is
+              not valid JSON or not an object
+    """
+    problem = problem or PROBLEMS[DEFAULT_PROBLEM]
+    try:
+        with open(path) as f:
+            data = json.load(f)
+    except (OSError, ValueError) as e:
+        return [{"file": path, "approach": None, "optimal": False, "obj": None, "value": None,
+                 "errors": [f"Cannot read the file: {e}"], "violations": []}]
+
+    if not isinstance(data, dict):
+        return [{"file": path, "approach": None, "optimal": False, "obj": None, "value": None,
+                 "errors": validate(data), "violations": []}]
+
+    reports = []
+    for approach, result in data.items():
+        errors = result_errors(result, approach, problem)
+        n = os.path.splitext(os.path.basename(path))[0]
+        if not errors and bounds and problem.bounds and n.isdigit():
+            errors = bounds_errors(result, int(n), bounds)
+        optimal = isinstance(result, dict) and result.get("optimal") is True
+        reports.append({"file": path, "approach": approach, "optimal": optimal,
+                        "obj": None if errors else reported_objective(result, problem.objective_key),
+                        "value": None if errors else problem.objective(result["sol"]),
+                        "errors": errors, "violations": explanation(result, problem) if errors else []})
+    return reports
+
+
+def _check_task(task):
+    """
+    Checks a result file in a worker process.
+    """
+    path, bounds, problem = task
+    return check_file(path, bounds, problem)
+
+
+def check_files(paths, bounds, problem, workers=None, progress=sys.stdout):
+    """
+    Checks the result files in parallel, printing the progress to the given stream.
+
+    Params:
+        workers: Number of worker processes (default: all cores; 1 checks in this process)
+
+    Returns:
+        list: The reports of all the files, in the order of the paths
+    """
+    workers = min(workers or os.cpu_count(), max(1, len(paths)))
+    tasks = [(path, bounds, problem) for path in paths]
+    reports = []
+
+    def advance(k, path):
+        progress.write(f"\r[{k}/{len(paths)}] {os.path.relpath(path)}".ljust(60))
+        progress.flush()
+
+    if workers == 1:
+        for k, task in enumerate(tasks, start=1):
+            reports.extend(_check_task(task))
+            advance(k, task[0])
+    else:
+        with mp.get_context("spawn").Pool(workers) as pool:
+            for k, (task, file_reports) in enumerate(zip(tasks, pool.imap(_check_task, tasks)), start=1):
+                reports.extend(file_reports)
+                advance(k, task[0])
+    if paths:
+        progress.write("\n")
+    return reports
+
+
+def consistency_errors(reports, problem=None):
+    """
+    Compares the valid results of all the approaches on the same instance (result file name):
+    the results claiming optimality must agree on the objective, and no schedule may have an
+    objective below a claimed optimum (either points to a soundness bug).
+
+    Returns:
+        list: The inconsistencies found, one string each
+    """
+    problem = problem or PROBLEMS[DEFAULT_PROBLEM]
+    instances = {}
+    for report in reports:
+        if not report["errors"]:
+            instances.setdefault(os.path.basename(report["file"]), []).append(report)
+
+    def name(report):
+        return f"{os.path.basename(os.path.dirname(os.path.abspath(report['file'])))}/{report['approach']}"
+
+    errors = []
+    for instance, results in sorted(instances.items(), key=lambda item: (len(item[0]), item[0])):
+        optimal = [r for r in results if r["optimal"] and r["obj"] is not None]
+        optima = sorted({r["obj"] for r in optimal})
+        if len(optima) > 1:
+            claimants = {o: [name(r) for r in optimal if r["obj"] == o] for o in optima}
+            claims = "; ".join(f"{o} by {', '.join(c) if len(c) <= 3 else f'{len(c)} results'}"
+                               for o, c in claimants.items())
+            errors.append(f"{instance}: different optimal objectives claimed ({claims})")
+        for r in results:
+            if r["value"] is not None and optima and r["value"] < max(optima):
+                claimed = next(o for o in optimal if o["obj"] == max(optima))
+                errors.append(f"{instance}: {name(r)} has a schedule with {problem.objective_name} {r['value']}, "
+                              f"below the optimum {max(optima)} claimed by {name(claimed)}")
+    return errors
+
+
+def print_table(reports, problem=None):
+    """
+    Prints the pass/fail table of the checked results, with the errors of the failed ones.
+    """
+    width = max([len(os.path.relpath(r["file"])) for r in reports] + [4])
+    approach_width = max([len(str(r["approach"])) for r in reports] + [8])
+    print(f"\n{'File':<{width}}  {'Approach':<{approach_width}}  Status")
+    for report in reports:
+        status = "FAIL" if report["errors"] else "PASS"
+        print(f"{os.path.relpath(report['file']):<{width}}  {str(report['approach']):<{approach_width}}  {status}")
+        for error in report["errors"]:
+            print(f"{'':<{width}}  {'':<{approach_width}}    - {error}")
+        if report["violations"]:
+            teams = sorted({t for v in report["violations"] for t in v["teams"]})
+            print(f"{'':<{width}}  {'':<{approach_width}}    {len(report['violations'])} violated constraints, "
+                  f"teams involved: {', '.join(map(str, teams))}")
+            for violation in report["violations"]:
+                matches = ", ".join(f"week {w} period {p}" for w, p in violation["matches"]) or "no match"
+                print(f"{'':<{width}}  {'':<{approach_width}}      * {violation['constraint']} ({matches})")
+
+    failed = sum(1 for r in reports if r["errors"])
+    print(f"\n{len(reports) - failed}/{len(reports)} results valid")
+
+    inconsistencies = consistency_errors(reports, problem)
+    if inconsistencies:
+        print("\nInconsistent results across approaches:")
+        for error in inconsistencies:
+            print(f"  - {error}")
+
+
+def summary(reports, problem=None):
+    """
+    Builds the machine-readable summary of the checked results, grouped by approach (the
+    directory of the result file, e.g. SAT).
+
+    Returns:
+        dict: {"total", "valid", "invalid", "approaches", "inconsistencies"}, with per approach the
+              number of valid results, of results claiming optimality and of invalid ones, and the
+              errors of the invalid ones
+    """
+    approaches = {}
+    for report in reports:
+        name = os.path.basename(os.path.dirname(os.path.abspath(report["file"])))
+        entry = approaches.setdefault(name, {"valid": 0, "optimal": 0, "invalid": 0, "failures": []})
+        entry["optimal"] += int(report["optimal"])
+        if report["errors"]:
+            entry["invalid"] += 1
+            entry["failures"].append({"file": os.path.relpath(report["file"]),
+                                      "configuration": report["approach"], "errors": report["errors"],
+                                      "violations": report["violations"]})
+        else:
+            entry["valid"] += 1
+
+    invalid = sum(entry["invalid"] for entry in approaches.values())
+    return {"total": len(reports), "valid": len(reports) - invalid, "invalid": invalid,
+            "approaches": dict(sorted(approaches.items())), "inconsistencies": consistency_errors(reports, problem)}
+
+
+def run_check(paths=None, summary_path=None, problem=DEFAULT_PROBLEM, workers=None, fix=False, against=None,
+              variant=None):
+    """
+    Checks the result files and prints the table.
+
+    Params:
+        paths: Result files / directories (res/ by default)
+        summary_path: File the JSON summary is written to ("-" for the standard output), if any
+        problem: Name of the problem definition the results are checked against (see PROBLEMS)
+        workers: Number of worker processes checking the files (default: all cores)
+        fix: Whether to normalize the benign format issues of the files in place first (see fix.py)
+        against: Directory of golden results the results under res/ must not regress from (see
+                 golden.py)
+        variant: Name of the variant of the manifest (variants.json) whose per-team limits are checked
+
+    Returns:
+        bool: Whether every result is valid, the results are consistent across approaches and
+              (with against) nothing regressed
+    """
+    start_time = time.time()
+    problem = problem_definition(problem, variant)
+    files = result_files(paths)
+    if fix:
+        for path in files:
+            for change in fix_file(path):
+                print(f"Fixed {os.path.relpath(path)}: {change}", file=sys.stderr if summary_path == "-" else sys.stdout)
+    # The progress goes to the standard error when the summary is printed instead of the table
+    reports = check_files(files, load_bounds(), problem, workers,
+                          progress=sys.stderr if summary_path == "-" else sys.stdout)
+    regressed = regressions(against, DEFAULT_RESULTS_DIR) if against else []
+    report_summary = summary(reports, problem)
+    if against:
+        report_summary["regressions"] = regressed
+
+    if summary_path == "-":
+        print(json.dumps(report_summary, indent=2))
+    else:
+        print_table(reports, problem)
+        if against:
+            print(f"\n{len(regressed)} regressions from the golden results in {against}")
+            for error in regressed:
+                print(f"  - {error}")
+        print(f"Checked {len(files)} files in {time.time() - start_time:.1f}s")
+        if summary_path:
+            with open(summary_path, "w") as f:
+                json.dump(report_summary, f, indent=2)
+            print(f"Summary written to {summary_path}")
+    return (all(not report["errors"] for report in reports) and not consistency_errors(reports, problem)
+            and not regressed)
@@ -0,0 +1,77 @@
+from source.checker.schema import RESULT_SCHEMA, validate
+from source.results.serializer import MANDATORY_KEYS, integral, write_file
+import json
+
+
+def fix_result(result):
+    """
+    Normalizes the benign format issues of a result: mandatory keys in another casing
+    ("Time"), integral floats in time / obj / sol (12.0) and the key order. Nothing else is
+    changed: it is up to the checker to report what is semantically wrong.
+
+    Returns:
+        tuple: (fixed result, list of the changes made)
+    """
+    if not isinstance(result, dict):
+        return result, []
+    changes = []
+
+    renamed = {}
+    for key, value in result.items():
+        lower = key.lower()
+        if lower in MANDATORY_KEYS and key != lower and lower not in result:
+            changes.append(f"key '{key}' renamed to '{lower}'")
+            key = lower
+        renamed[key] = value
+
+    for key in ("time", "obj"):
+        if key in renamed and integral(renamed[key]) is not renamed[key]:
+            changes.append(f"{key} {renamed[key]} written as an integer")
+            renamed[key] = integral(renamed[key])
+    if isinstance(renamed.get("sol"), list):
+        sol = json.loads(json.dumps(renamed["sol"]), parse_float=lambda s: integral(float(s)))
+        if json.dumps(sol) != json.dumps(renamed["sol"]):
+            changes.append("integral floats of sol written as integers")
+            renamed["sol"] = sol
+
+    ordered = {key: renamed[key] for key in MANDATORY_KEYS if key in renamed}
+    ordered.update((key, value) for key, value in renamed.items() if key not in MANDATORY_KEYS)
+    if list(ordered) != list(result) and not changes:
+        changes.append("keys reordered")
+    return ordered, changes
+
+
+def fix_file(path):
+    """
+    Normalizes the benign format issues of a result file in place (see fix_result), plus the
+    approach keys in another casing (the keys are lower case). Only the approaches that match
+    the result schema once fixed are changed, the others are left as they are.
+
+    Returns:
+        list: The changes made (empty if the file was left untouched)
+    """
+    try:
+        with open(path) as f:
+            data = json.load(f)
+    except (OSError, ValueError):
+        return []
+    if not isinstance(data, dict):
+        return []
+
+    fixed, changes = {}, []
+    for approach, result in data.items():
+        fixed_result, result_changes = fix_result(result)
+        if validate(fixed_result, RESULT_SCHEMA["values"]):
+            fixed[approach] = result
+            continue
+        key = approach.lower() if approach.lower() not in data else approach
+        if key != approach:
+            changes.append(f"approach '{approach}' renamed to '{key}'")
+        fixed[key] = fixed_result
+        changes.extend(f"{key}: {change}" for change in result_changes)
+
+    if not changes:
+        return []
+    # The approaches left as they are may be invalid: the checker reports them
+    write_file(path, fixed, strict=False)
+    return changes
@@ -0,0 +1,64 @@
+import json
+import os
+from source.checker.schema import reported_objective
+
+
+def _load(path):
+    try:
+        with open(path) as f:
+            data = json.load(f)
+    except (OSError, ValueError):
+        return None
+    return data if isinstance(data, dict) else None
+
+
+def result_regressions(key, golden, current):
+    """
+    Compares a result with its golden counterpart: a lost schedule, a worse objective or a lost
+    optimality claim is a regression.
+
+    Returns:
+        list: The regressions found
+    """
+    if current is None:
+        return [f"{key}: missing (golden: obj {golden.get('obj')}, optimal {golden.get('optimal')})"]
+    errors = []
+    before, after = reported_objective(golden), reported_objective(current)
+    if golden.get("sol") and not current.get("sol"):
+        errors.append(f"{key}: no schedule anymore")
+    elif before is not None and after is not None and after > before:
+        errors.append(f"{key}: objective regressed from {before} to {after}")
+    elif before is not None and after is None and current.get("sol"):
+        errors.append(f"{key}: objective {before} not reported anymore")
+    if golden.get("optimal") is True and current.get("optimal") is not True:
+        errors.append(f"{key}: optimality claim lost")
+    return errors
+
+
+def regressions(golden_dir, results_dir):
+    """
+    Treats golden_dir (e.g. a copy of res/ before a refactor) as golden results: every result it
+    contains must still be in results_dir (same relative file and configuration key) with an
+    objective no worse and its optimality claim. New results are not compared.
+
+    Returns:
+        list: The regressions found, one string each
+    """
+    errors = []
+    for root, _, names in sorted(os.walk(golden_dir)):
+        for name in sorted(names, key=lambda s: (len(s), s)):
+            if not name.endswith(".json"):
+                continue
+            golden_path = os.path.join(root, name)
+            relative = os.path.relpath(golden_path, golden_dir)
+            golden = _load(golden_path)
+            if golden is None:
+                continue
+            current = _load(os.path.join(results_dir, relative))
+            if current is None:
+                errors.append(f"{relative}: missing or unreadable")
+                continue
+            for key, result in golden.items():
+                errors.extend(f"{relative}: {error}"
+                              for error in result_regressions(key, result, current.get(key)))
+    return errors
@@ -0,0 +1,323 @@
+from solution_checker import check_solution
+from collections import Counter
+from functools import partial
+from itertools import combinations
+import json
+import os
+
+# Max number of matches of a team in the same period over the tournament
+PERIOD_LIMIT = 2
+
+# Manifest of the experimental variants with per-team limits
+VARIANTS_FILE = os.path.join(os.path.dirname(os.path.abspath(__file__)), "variants.json")
+
+# Message of check_solution for a period violation, replaced by the ones of period_errors
+OFFICIAL_PERIOD_ERROR = "Some teams play more than twice in the period"
+
+# Per-team limits a variant can set, in addition to the STS constraints
+TEAM_LIMITS = ("period_limit", "max_consecutive", "max_home", "max_away")
+
+
+class Problem:
+    """
+    Definition of a problem variant checked by the engine in checker.py: the constraint checks
+    of its schedules and its objective.
+
+    Params:
+        name: Name of the variant
+        stages: Lists of constraint checks (result -> list of errors); a stage runs only if the
+                previous ones found no error (e.g. the indices before the matches)
+        objective: Recomputes the objective from a (valid) schedule
+        objective_key: Result key of the reported objective
+        objective_name: Name of the objective in the messages
+        bounds: Whether the bounds database (of the max imbalance) applies
+        explain: Lists the violated constraint instances of an invalid schedule (result -> list
+                 of violations), if the variant has it
+    """
+
+    def __init__(self, name, stages, objective, objective_key="obj", objective_name="max imbalance", bounds=True,
+                 explain=None):
+        self.name = name
+        self.stages = stages
+        self.objective = objective
+        self.objective_key = objective_key
+        self.objective_name = objective_name
+        self.bounds = bounds
+        self.explain = explain
+
+    def constraint_errors(self, result):
+        """
+        Runs the constraint checks stage by stage.
+
+        Returns:
+            list: The errors of the first stage that found any
+        """
+        for stage in self.stages:
+            errors = [error for check in stage for error in check(result)]
+            if errors:
+                return errors
+        return []
+
+
+def check_result(result):
+    """
+    Checks one approach of a result file with the rules of solution_checker.py.
+
+    Returns:
+        list: The errors found (empty for a valid result)
+    """
+    try:
+        message = check_solution(result.get("sol", []), result.get("obj"), result.get("time"), result.get("optimal"))
+    except Exception as e:
+        return [f"Malformed result: {e}"]
+    return [] if isinstance(message, str) else list(message)
+
+
+def schedule_imbalance(solution):
+    """
+    Recomputes the max home/away imbalance |home - away| over the teams of a schedule.
+
+    Returns:
+        int: The max imbalance, None for an empty schedule
+    """
+    if not solution:
+        return None
+    n = 2 * len(solution)
+    home = [0] * (n + 1)
+    for row in solution:
+        for h, _ in row:
+            home[h] += 1
+    return max(abs(2 * home[t] - (n - 1)) for t in range(1, n + 1))
+
+
+def total_imbalance(solution):
+    """
+    Recomputes the total home/away imbalance sum_t |home - away| of a schedule (the second
+    objective of the SAT lexicographic optimization).
+
+    Returns:
+        int: The total imbalance, None for an empty schedule
+    """
+    if not solution:
+        return None
+    n = 2 * len(solution)
+    home = [0] * (n + 1)
+    for row in solution:
+        for h, _ in row:
+            home[h] += 1
+    return sum(abs(2 * home[t] - (n - 1)) for t in range(1, n + 1))
+
+
+def index_errors(result):
+    """
+    Checks that the teams are numbered 1..n, telling a schedule numbered 0..n-1 (a 0-based
+    decoding of the solver variables) from teams merely out of range.
+
+    Returns:
+        list: The errors found (empty for an empty schedule)
+    """
+    if not result["sol"]:
+        return []
+    n = 2 * len(result["sol"])
+    teams = {team for row in result["sol"] for match in row for team in match}
+    if teams == set(range(n)):
+        return [f"0-based team indices: the teams are numbered 0..{n - 1} instead of 1..{n}"]
+    out_of_range = sorted(t for t in teams if not 1 <= t <= n)
+    if out_of_range:
+        return [f"Team indices out of range 1..{n}: {', '.join(map(str, out_of_range))}"]
+    return []
+
+
+def pair_errors(result):
+    """
+    Checks that every pair of teams meets exactly once, naming the missing and the duplicated
+    pairs (the official checker only reports that some match is duplicated).
+
+    Returns:
+        list: The errors found (empty for an empty schedule)
+    """
+    if not result["sol"]:
+        return []
+    n = 2 * len(result["sol"])
+    played = Counter(tuple(sorted(match)) for row in result["sol"] for match in row)
+    missing = [pair for pair in combinations(range(1, n + 1), 2) if pair not in played]
+    duplicated = [pair for pair, count in sorted(played.items()) if count > 1]
+
+    errors = []
+    if missing:
+        errors.append(f"Missing matches: {', '.join(f'{h}-{a}' for h, a in missing)}")
+    if duplicated:
+        errors.append(f"Duplicated matches: {', '.join(f'{h}-{a} (x{played[h, a]})' for h, a in duplicated)}")
+    return errors
+
+
+def period_errors(result):
+    """
+    Checks the period limit, reporting for every violation the team, the period, its number
+    of matches there and the weeks (and opponents) of those matches (the official checker only
+    reports that some team plays more than twice in some period).
+
+    Returns:
+        list: The errors found, one per (team, period) over the limit
+    """
+    errors = []
+    for p, row in enumerate(result["sol"], start=1):
+        weeks = {}
+        for w, match in enumerate(row, start=1):
+            for team, opponent in (match, match[::-1]):
+                weeks.setdefault(team, []).append((w, opponent))
+        for team in sorted(weeks):
+            if len(weeks[team]) > PERIOD_LIMIT:
+                matches = ", ".join(f"week {w} vs {opponent}" for w, opponent in weeks[team])
+                errors.append(f"Team {team} plays {len(weeks[team])} times in period {p} "
+                              f"(limit {PERIOD_LIMIT}): {matches}")
+    return errors
+
+
+def official_errors(result):
+    """
+    Runs check_result, without its period message when period_errors reports the violations in
+    detail, so that each one is reported once.
+
+    Returns:
+        list: The errors found (empty for a valid result)
+    """
+    errors = check_result(result)
+    if OFFICIAL_PERIOD_ERROR in errors and period_errors(result):
+        errors.remove(OFFICIAL_PERIOD_ERROR)
+    return errors
+
+
+def violations(result):
+    """
+    Explains an invalid schedule with every violated constraint instance (not only the first
+    failure) and, for each one, the minimal matches involved: the two matches of a duplicated
+    pair or of a team playing twice in a week, the three matches of a team over the period limit
+    (any two of them are allowed), the match of a team against itself; a missing pair involves
+    no match. Matches are (week, period), 1-based.
+
+    Returns:
+        list: The violations {"constraint", "teams", "matches"}, by constraint
+    """
+    solution = result["sol"]
+    n = 2 * len(solution)
+    slots = [(w, p, h, a) for p, row in enumerate(solution, start=1) for w, (h, a) in enumerate(row, start=1)]
+    found = []
+
+    by_pair = {}
+    for w, p, h, a in slots:
+        if h == a:
+            found.append({"constraint": f"team {h} plays against itself", "teams": [h], "matches": [(w, p)]})
+        else:
+            by_pair.setdefault((min(h, a), max(h, a)), []).append((w, p))
+    for pair in combinations(range(1, n + 1), 2):
+        if pair not in by_pair:
+            found.append({"constraint": f"pair {pair[0]}-{pair[1]} never plays", "teams": list(pair), "matches": []})
+        elif len(by_pair[pair]) > 1:
+            found.append({"constraint": f"pair {pair[0]}-{pair[1]} plays more than once", "teams": list(pair),
+                          "matches": sorted(by_pair[pair])[:2]})
+
+    by_week, by_period = {}, {}
+    for w, p, h, a in slots:
+        for team in {h, a}:
+            by_week.setdefault((team, w), []).append((w, p))
+            by_period.setdefault((team, p), []).append((w, p))
+    for (team, w), matches in sorted(by_week.items()):
+        if len(matches) > 1:
+            found.append({"constraint": f"team {team} plays more than once in week {w}", "teams": [team],
+                          "matches": sorted(matches)[:2]})
+    for (team, p), matches in sorted(by_period.items()):
+        if len(matches) > PERIOD_LIMIT:
+            found.append({"constraint": f"team {team} plays more than {PERIOD_LIMIT} times in period {p}",
+                          "teams": [team], "matches": sorted(matches)[:PERIOD_LIMIT + 1]})
+    return found
+
+
+def team_limit_errors(result, limits):
+    """
+    Checks the per-team limits of a variant: "period_limit" (matches in the same period),
+    "max_consecutive" (consecutive home or away games), "max_home" and "max_away" (games over the
+    tournament). The limits apply to every team, and limits["teams"][<team>] overrides them for
+    a single team.
+
+    Returns:
+        list: The errors found, one per team and limit
+    """
+    solution = result["sol"]
+    if not solution:
+        return []
+    n = 2 * len(solution)
+    weeks = len(solution[0])
+    # Per team, True (home) / False (away) in every week, and the periods it plays in
+    venues = {t: [None] * weeks for t in range(1, n + 1)}
+    periods = {t: Counter() for t in range(1, n + 1)}
+    for p, row in enumerate(solution, start=1):
+        for w, (h, a) in enumerate(row):
+            venues[h][w], venues[a][w] = True, False
+            periods[h][p] += 1
+            periods[a][p] += 1
+
+    errors = []
+    for team in range(1, n + 1):
+        team_limits = {k: v for k, v in limits.items() if k in TEAM_LIMITS}
+        team_limits.update(limits.get("teams", {}).get(str(team), {}))
+
+        if "period_limit" in team_limits:
+            over = {p: c for p, c in sorted(periods[team].items()) if c > team_limits["period_limit"]}
+            if over:
+                errors.append(f"Team {team} exceeds its period limit {team_limits['period_limit']}: "
+                              + ", ".join(f"{c} matches in period {p}" for p, c in over.items()))
+        if "max_consecutive" in team_limits:
+            run, longest, start = 0, (0, 0), 0
+            for w, venue in enumerate(venues[team]):
+                run = run + 1 if w > 0 and venue == venues[team][w - 1] else 1
+                if run > longest[0]:
+                    longest, start = (run, w - run + 2), venue
+            if longest[0] > team_limits["max_consecutive"]:
+                errors.append(f"Team {team} plays {longest[0]} consecutive {'home' if start else 'away'} games "
+                              f"from week {longest[1]} (limit {team_limits['max_consecutive']})")
+        for key, venue, label in (("max_home", True, "home"), ("max_away", False, "away")):
+            count = venues[team].count(venue)
+            if key in team_limits and count > team_limits[key]:
+                errors.append(f"Team {team} plays {count} {label} games (limit {team_limits[key]})")
+    return errors
+
+
+def load_variants(path=VARIANTS_FILE):
+    """
+    Loads the manifest of the variants: per name, its "description" and its per-team limits
+    (see team_limit_errors).
+
+    Returns:
+        dict: The variants, by name
+    """
+    with open(path) as f:
+        return json.load(f)
+
+
+def problem_definition(name, variant=None):
+    """
+    Returns the problem definition to check the results against: the named one, with the
+    per-team limits of the manifest variant as a further stage of constraint checks.
+    """
+    problem = PROBLEMS[name]
+    if variant is None:
+        return problem
+    limits = load_variants()[variant]
+    return Problem(f"{name}_{variant}", problem.stages + [[partial(team_limit_errors, limits=limits)]],
+                   problem.objective, problem.objective_key, problem.objective_name, problem.bounds,
+                   problem.explain)
+
+
+# The STS constraints, shared by the variants
+STS_STAGES = [[index_errors], [official_errors, pair_errors, period_errors]]
+
+PROBLEMS = {
+    # The project problem: max imbalance reported as obj
+    "sts": Problem("sts", STS_STAGES, schedule_imbalance, explain=violations),
+    # Lexicographic variant: total imbalance reported as total_imbalance (SAT --lexicographic)
+    "sts_total": Problem("sts_total", STS_STAGES, total_imbalance, objective_key="total_imbalance",
+                         objective_name="total imbalance", bounds=False, explain=violations),
+}
+
+DEFAULT_PROBLEM = "sts"
@@ -0,0 +1,96 @@
+from source.checker.checker import result_errors
+from source.checker.problems import schedule_imbalance
+from source.common.brute_force import reference_optimum
+import json
+
+# Instances solved by the brute force reference (4 has no schedule)
+REFERENCE_TEAMS = (4, 6, 8)
+
+# Configurations compared with the reference: (approach, solver); the MIP uses HiGHS, which
+# needs no license
+REFERENCE_SOLVERS = [("cp", "gecode"), ("sat", "z3"), ("smt", "z3"), ("mip", "highs")]
+
+
+def run_approach(approach, solver, n, use_sb, options):
+    """
+    Runs an approach with optimization on an instance, without writing its results.
+
+    Returns:
+        dict: The results of the run, by configuration key
+    """
+    # Imported on demand: every approach has its own solver dependencies
+    if approach == "cp":
+        from source.CP import cp_model
+        return cp_model.run_model({}, n, solver, use_sb, 1, True, options.get("cp"))
+    if approach == "sat":
+        from source.SAT import sat_model
+        return sat_model.run_model({}, n, solver, use_sb, True, options.get("sat"))
+    if approach == "smt":
+        from source.SMT import smt_model
+        return smt_model.run_model({}, n, solver, use_sb, True, options.get("smt"))
+    from source.MIP import mip_model
+    return mip_model.run_model({}, n, solver, use_sb, True, options.get("mip"))
+
+
+def compare(n, reference, key, result):
+    """
+    Compares a result with the brute force reference of its instance.
+
+    Returns:
+        list: The mismatches found
+    """
+    # The same normalization as before writing the results
+    result = json.loads(json.dumps({k: result.get(k) for k in ("time", "optimal", "obj", "sol")}))
+    if not reference["feasible"]:
+        return [f"{key}: schedule reported for n = {n}, which has none"] if result["sol"] else []
+
+    errors = [f"{key}: {error}" for error in result_errors(result, key)]
+    if errors or not result["sol"]:
+        return errors
+    if result["optimal"] and result["obj"] != reference["optimum"]:
+        errors.append(f"{key}: optimum {result['obj']} claimed, the reference optimum is {reference['optimum']}")
+    imbalance = schedule_imbalance(result["sol"])
+    if imbalance < reference["optimum"]:
+        errors.append(f"{key}: schedule with max imbalance {imbalance}, below the reference optimum "
+                      f"{reference['optimum']}")
+    return errors
+
+
+def run_reference(teams=REFERENCE_TEAMS, options=None):
+    """
+    Compares the optimal objectives of every approach (with and without symmetry breaking)
+    with the exhaustive reference solver on tiny instances. An approach whose dependencies are
+    missing is skipped.
+
+    Params:
+        teams: Instances to solve
+        options: Options of the runs per approach ({"cp": {...}, "sat": {...}, ...})
+
+    Returns:
+        bool: Whether every result agrees with the reference
+    """
+    options = options or {}
+    mismatches, compared = [], 0
+    for n in teams:
+        reference = reference_optimum(n)
+        print(f"\nn={n}: reference optimum "
+              f"{reference['optimum'] if reference['feasible'] else 'none (no schedule)'}")
+
+        for approach, solver in REFERENCE_SOLVERS:
+            for use_sb in (False, True):
+                try:
+                    results = run_approach(approach, solver, n, use_sb, options)
+                except ImportError as e:
+                    print(f"  {approach}: skipped ({e})")
+                    break
+                for key, result in results.items():
+                    compared += 1
+                    errors = compare(n, reference, f"{approach.upper()}/{key}", result)
+                    print(f"  {approach.upper()}/{key}: {'MISMATCH' if errors else 'OK'} "
+                          f"(obj {result.get('obj')}, optimal {result.get('optimal')})")
+                    mismatches.extend(f"n={n} {error}" for error in errors)
+
+    print(f"\n{compared} results compared, {len(mismatches)} mismatches with the reference")
+    for mismatch in mismatches:
+        print(f"  - {mismatch}")
+    return not mismatches
@@ -0,0 +1,73 @@
+# Objective of a run without one in the official format, equivalent to null
+NOT_AVAILABLE = "N/A"
+
+# Format of a result file: approach name -> result. The mandatory keys are the ones of the
+# official format; writers may add further keys (params, bound, ...) after them.
+RESULT_SCHEMA = {
+    "type": "object",
+    "values": {
+        "type": "object",
+        "required": {
+            "time": {"type": "int"},
+            "optimal": {"type": "bool"},
+            "obj": {"type": ["int", "null", NOT_AVAILABLE]},
+            "sol": {
+                "type": "list",
+                "items": {                      # periods
+                    "type": "list",
+                    "items": {                  # weeks
+                        "type": "list",
+                        "length": 2,            # [home, away]
+                        "items": {"type": "int"},
+                    },
+                },
+            },
+        },
+    },
+}
+
+_TYPES = {
+    "object": lambda v: isinstance(v, dict),
+    "list": lambda v: isinstance(v, list),
+    # bool is a subclass of int in Python, but not a valid int in the format
+    "int": lambda v: isinstance(v, int) and not isinstance(v, bool),
+    "bool": lambda v: isinstance(v, bool),
+    "null": lambda v: v is None,
+    NOT_AVAILABLE: lambda v: v == NOT_AVAILABLE,
+}
+
+
+def reported_objective(result, key="obj"):
+    """
+    Returns the objective reported by a result, None if it reports none (null or "N/A").
+    """
+    value = result.get(key)
+    return None if value == NOT_AVAILABLE else value
+
+
+def validate(value, schema=RESULT_SCHEMA, path="$"):
+    """
+    Validates a value (by default a whole result file) against the schema.
+
+    Returns:
+        list: The violations, as "<path>: <message>" strings (empty for a valid value)
+    """
+    types = schema["type"] if isinstance(schema["type"], list) else [schema["type"]]
+    if not any(_TYPES[t](value) for t in types):
+        return [f"{path}: expected {' or '.join(types)}, found {type(value).__name__}"]
+
+    errors = []
+    if "length" in schema and len(value) != schema["length"]:
+        errors.append(f"{path}: expected {schema['length']} items, found {len(value)}")
+    if "items" in schema and isinstance(value, list):
+        for i, item in enumerate(value):
+            errors.extend(validate(item, schema["items"], f"{path}[{i}]"))
+    if "values" in schema and isinstance(value, dict):
+        for key, item in value.items():
+            errors.extend(validate(item, schema["values"], f"{path}.{key}"))
+    for key, child in schema.get("required", {}).items():
+        if key not in value:
+            errors.append(f"{path}: missing key '{key}'")
+        else:
+            errors.extend(validate(value[key], child, f"{path}.{key}"))
+    return errors
@@ -0,0 +1,34 @@
+# Bounds of the MIP oracle computed in this process, per (n, time limit)
+_MIP_BOUNDS = {}
+
+
+def initial_lower_bound(n_teams, options=None, solver_params=None):
+    """
+    With options["mip_bound"] (seconds, 0 for the LP relaxation only), asks the MIP bound oracle
+    for a lower bound on the max imbalance, used as the initial lower bound of the CP/SAT/SMT
+    optimization; the oracle runs once per instance size and is recorded in solver_params.
+
+    Returns:
+        int: The lower bound (1, always valid, without the option or if the oracle fails)
+    """
+    seconds = (options or {}).get("mip_bound")
+    if seconds is None:
+        return 1
+
+    if (n_teams, seconds) not in _MIP_BOUNDS:
+        try:
+            # Imported on demand: the MIP approach needs AMPL and its license
+            from source.MIP.bound_oracle import mip_lower_bound
+            _MIP_BOUNDS[n_teams, seconds] = mip_lower_bound(n_teams, time_limit=max(1, seconds),
+                                                            lp_only=seconds == 0)
+        except Exception as e:
+            print(f"MIP bound oracle failed: {e}")
+            _MIP_BOUNDS[n_teams, seconds] = None
+
+    oracle = _MIP_BOUNDS[n_teams, seconds]
+    if oracle is None:
+        return 1
+    if solver_params is not None:
+        solver_params["mip_bound"] = oracle
+    print(f"MIP lower bound: {oracle['bound']} ({oracle['source']})")
+    return oracle["bound"]
@@ -0,0 +1,146 @@
+from itertools import permutations, product
+
+
+def _matchings(teams, pairs):
+    """
+    Yields all perfect matchings of teams using only pairs not in the given set.
+    """
+    if not teams:
+        yield []
+        return
+    first, rest = teams[0], teams[1:]
+    for k, other in enumerate(rest):
+        pair = (first, other)
+        if pair in pairs:
+            continue
+        for matching in _matchings(rest[:k] + rest[k + 1:], pairs):
+            yield [pair] + matching
+
+
+def enumerate_schedules(n_teams, fixed=None, oriented=True):
+    """
+    Enumerates by brute force every valid schedule of the STS problem, directly from its
+    definition: every pair plays once, every team plays once a week, one match per period
+    and week, and every team plays at most twice in the same period.
+
+    Params:
+        n_teams: Number of teams (only tiny instances are tractable)
+        fixed: Optional {week: [(home, away, period), ...]} of weeks fixed in advance
+        oriented: Whether to enumerate both orientations of every match (otherwise the lower
+                  index plays at home)
+    Returns:
+        generator: Schedules as lists of (home, away, week, period), 0-based
+    """
+    num_weeks, num_periods = n_teams - 1, n_teams // 2
+    fixed = fixed or {}
+    counts = [[0] * num_periods for _ in range(n_teams)]
+    played = set()
+    schedule = []
+
+    def week_options(w):
+        if w in fixed:
+            yield [(h, a, p) for h, a, p in fixed[w]]
+            return
+        for matching in _matchings(list(range(n_teams)), played):
+            for periods in permutations(range(num_periods)):
+                for flips in product((False, True) if oriented else (False,), repeat=num_periods):
+                    yield [(b, a, p) if flip else (a, b, p)
+                           for (a, b), p, flip in zip(matching, periods, flips)]
+
+    def search(w):
+        if w == num_weeks:
+            yield list(schedule)
+            return
+        for week in week_options(w):
+            pairs = [(min(h, a), max(h, a)) for h, a, _ in week]
+            if any(pair in played for pair in pairs):
+                continue
+            if any(counts[t][p] >= 2 for h, a, p in week for t in (h, a)):
+                continue
+
+            for h, a, p in week:
+                counts[h][p] += 1
+                counts[a][p] += 1
+                schedule.append((h, a, w, p))
+            played.update(pairs)
+
+            yield from search(w + 1)
+
+            for h, a, p in week:
+                counts[h][p] -= 1
+                counts[a][p] -= 1
+                schedule.pop()
+            played.difference_update(pairs)
+
+    return search(0)
+
+
+def satisfies_symmetry_breaking(matches, n_teams, use_optimization=False):
+    """
+    Checks the symmetry breaking constraints shared by the models: team 0 plays team w+1
+    in week w, at home against team 1 in period 0 of week 0; without optimization the
+    lower index always plays at home.
+    """
+    if (0, 1, 0, 0) not in matches:
+        return False
+    for h, a, w, _ in matches:
+        if 0 in (h, a) and h + a != w + 1:
+            return False
+        if not use_optimization and h > a:
+            return False
+    return True
+
+
+
+def _orient(pairs, n_teams, bound):
+    """
+    Searches an orientation of the pairs (home, away) with max home/away imbalance within bound.
+
+    Returns:
+        list: The oriented pairs, None if there is none
+    """
+    limit = (n_teams - 1 + bound) // 2
+    home, away = [0] * n_teams, [0] * n_teams
+    oriented = []
+
+    def search(k):
+        if k == len(pairs):
+            return True
+        a, b = pairs[k]
+        for h, t in ((a, b), (b, a)):
+            if home[h] < limit and away[t] < limit:
+                home[h] += 1
+                away[t] += 1
+                oriented.append((h, t))
+                if search(k + 1):
+                    return True
+                home[h] -= 1
+                away[t] -= 1
+                oriented.pop()
+        return False
+
+    return oriented if search(0) else None
+
+
+def reference_optimum(n_teams):
+    """
+    Exhaustive reference solver of the STS optimization version for tiny instances (up to 8
+    teams). The orientation of the matches does not take part in the schedule constraints, so the
+    optimum is found by enumerating the schedules until the first valid one (proving
+    infeasibility if there is none), then by exhaustive search over the orientations of its
+    matches with increasing bounds on the max imbalance.
+
+    Returns:
+        dict: {"feasible", "optimum", "schedule"}: the optimal max imbalance (None if infeasible)
+              and an optimal schedule as a list of (home, away, week, period), 0-based
+    """
+    schedule = next(enumerate_schedules(n_teams, oriented=False), None)
+    if schedule is None:
+        return {"feasible": False, "optimum": None, "schedule": None}
+
+    pairs = [(h, a) for h, a, _, _ in schedule]
+    for bound in range(1, n_teams, 2):
+        oriented = _orient(pairs, n_teams, bound)
+        if oriented is not None:
+            return {"feasible": True, "optimum": bound,
+                    "schedule": [(h, a, w, p) for (h, a), (_, _, w, p) in zip(oriented, schedule)]}
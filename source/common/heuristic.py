@@ -0,0 +1,193 @@
+import random
+import time
+
+
+def circle_method(n_teams):
+    """
+    Builds a round robin with the circle (Berger) method: team n-1 is fixed and the
+    others rotate. Home and away are alternated so that every team has imbalance 1.
+
+    Params:
+        n_teams: Number of teams (even)
+    Returns:
+        rounds[w] = list of (home, away) pairs of week w, teams 0-based
+    """
+    if n_teams % 2:
+        raise ValueError("Number of teams must be even")
+
+    m = n_teams - 1
+    rounds = []
+    for w in range(m):
+        week = [(w, m) if w % 2 == 0 else (m, w)]
+        for k in range(1, n_teams // 2):
+            a, b = (w + k) % m, (w - k) % m
+            week.append((a, b) if k % 2 == 1 else (b, a))
+        rounds.append(week)
+
+    return rounds
+
+
+def _violations(counts):
+    return sum(max(0, c - 2) for row in counts for c in row)
+
+
+def _repair_periods(matches, counts, deadline, rng, tenure=10):
+    """
+    Tabu search on the periods: among all swaps of a conflicting match (one of its teams
+    plays more than twice in its period) with another match of the same week, the one
+    giving the best change in the number of violations is applied. A swapped pair is
+    tabu for a randomized tenure unless the move reaches a new best.
+    """
+    by_week = {}
+    for idx, (_, _, w, _) in enumerate(matches):
+        by_week.setdefault(w, []).append(idx)
+
+    def delta(i, j):
+        (h1, a1, _, p1), (h2, a2, _, p2) = matches[i], matches[j]
+        changes = [(h1, p1, -1), (a1, p1, -1), (h1, p2, 1), (a1, p2, 1),
+                   (h2, p2, -1), (a2, p2, -1), (h2, p1, 1), (a2, p1, 1)]
+        return sum(max(0, counts[t][p] + d - 2) - max(0, counts[t][p] - 2) for t, p, d in changes)
+
+    def move(i, j):
+        (h1, a1, w, p1), (h2, a2, _, p2) = matches[i], matches[j]
+        for t in (h1, a1):
+            counts[t][p1] -= 1
+            counts[t][p2] += 1
+        for t in (h2, a2):
+            counts[t][p2] -= 1
+            counts[t][p1] += 1
+        matches[i], matches[j] = (h1, a1, w, p2), (h2, a2, w, p1)
+
+    current = best = _violations(counts)
+    best_matches = list(matches)
+    tabu = {}
+
+    step = 0
+    while current > 0 and time.time() < deadline:
+        step += 1
+        conflicted = [idx for idx, (h, a, _, p) in enumerate(matches) if counts[h][p] > 2 or counts[a][p] > 2]
+
+        candidates = []
+        for i in conflicted:
+            for j in by_week[matches[i][2]]:
+                if j == i:
+                    continue
+                d = delta(i, j)
+                if tabu.get((i, j), -1) >= step and current + d >= best:
+                    continue
+                candidates.append((d, rng.random(), i, j))
+        if not candidates:
+            continue
+
+        d, _, i, j = min(candidates)
+        move(i, j)
+        current += d
+        tabu[(i, j)] = tabu[(j, i)] = step + tenure + rng.randrange(tenure)
+
+        if current < best:
+            best, best_matches = current, list(matches)
+
+    matches[:] = best_matches
+    for row in counts:
+        row[:] = [0] * len(row)
+    for h, a, _, p in matches:
+        counts[h][p] += 1
+        counts[a][p] += 1
+
+    return best
+
+
+def greedy_schedule(n_teams, time_limit=10, seed=0):
+    """
+    Greedy STS heuristic: circle method for weeks and opponents, with the row of each
+    pair in the circle as its period. This already satisfies the period constraint for
+    every team but the fixed one, and the remaining conflicts are repaired by a tabu
+    search that swaps periods inside the weeks.
+
+    Params:
+        n_teams: Number of teams (even)
+        time_limit: Time budget of the repair, in seconds
+        seed: Seed of the tabu search
+    Returns:
+        tuple: (matches, valid) where matches is a list of (home, away, week, period), 0-based,
+               and valid tells whether the period constraint is satisfied
+    """
+    num_periods = n_teams // 2
+    counts = [[0] * num_periods for _ in range(n_teams)]
+    matches = []
+
+    for w, week in enumerate(circle_method(n_teams)):
+        for p, (h, a) in enumerate(week):
+            counts[h][p] += 1
+            counts[a][p] += 1
+            matches.append((h, a, w, p))
+
+    violations = _repair_periods(matches, counts, time.time() + time_limit, random.Random(seed))
+
+    return matches, violations == 0
+
+
+def relabel_for_symmetry_breaking(matches, n_teams, team_order=False):
+    """
+    Relabels a schedule so that it satisfies the symmetry breaking constraints of the models:
+    team 0 plays team w+1 in week w, at home against team 1 in period 0 of week 0.
+    With team_order, the lower index always plays at home.
+
+    Teams, periods and the global home/away orientation are permuted, so validity and
+    imbalances are preserved (except for team_order, which changes the orientation).
+    """
+    opponent = {}
+    for h, a, w, p in matches:
+        if h == 0:
+            opponent[w] = a
+        elif a == 0:
+            opponent[w] = h
+
+    team_map = {0: 0}
+    team_map.update({opponent[w]: w + 1 for w in opponent})
+
+    first = next(m for m in matches if m[2] == 0 and 0 in (m[0], m[1]))
+    period_map = {first[3]: 0, 0: first[3]}
+    flip = first[0] != 0
+
+    relabeled = []
+    for h, a, w, p in matches:
+        h, a = team_map[h], team_map[a]
+        if flip:
+            h, a = a, h
+        if team_order and h > a:
+            h, a = a, h
+        relabeled.append((h, a, w, period_map.get(p, p)))
+
+    return relabeled
+
+
+def order_periods(matches):
+    """
+    Permutes the periods of a schedule so that, in week 0, they are ordered by the lowest
+    team playing in them (period symmetry breaking). Validity and imbalances are preserved.
+    """
+    first_week = sorted((min(h, a), p) for h, a, w, p in matches if w == 0)
+    period_map = {p: rank for rank, (_, p) in enumerate(first_week)}
+    return [(h, a, w, period_map[p]) for h, a, w, p in matches]
+
+
+def to_solution(matches, n_teams):
+    """
+    Converts a list of (home, away, week, period) into the result format
+    sol[p][w] = [home, away] with 1-based teams.
+    """
+    solution = [[None for _ in range(n_teams - 1)] for _ in range(n_teams // 2)]
+    for h, a, w, p in matches:
+        solution[p][w] = [h + 1, a + 1]
+    return solution
+
+
+def max_imbalance(matches, n_teams):
+    """
+    Computes the max home-away imbalance of a list of (home, away, week, period).
+    """
+    home = [0] * n_teams
+    for h, _, _, _ in matches:
+        home[h] += 1
+    return max(abs(2 * home[t] - (n_teams - 1)) for t in range(n_teams))
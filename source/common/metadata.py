@@ -0,0 +1,70 @@
+from datetime import datetime, timezone
+from importlib import metadata as importlib_metadata
+import os
+import socket
+from source.results.runs import current_run
+
+# Repository root, whose .git the commit is read from
+REPO_DIR = os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+
+def git_commit():
+    """
+    Returns the commit the results are produced with: GIT_COMMIT if set, otherwise read from
+    .git (the image has no git binary, but the checkout is copied with its .git).
+
+    Returns:
+        str: The commit hash, None if unknown
+    """
+    if os.environ.get("GIT_COMMIT"):
+        return os.environ["GIT_COMMIT"]
+    git_dir = os.path.join(REPO_DIR, ".git")
+    try:
+        with open(os.path.join(git_dir, "HEAD")) as f:
+            head = f.read().strip()
+        if not head.startswith("ref: "):
+            return head
+        ref = head[len("ref: "):]
+        if os.path.exists(os.path.join(git_dir, ref)):
+            with open(os.path.join(git_dir, ref)) as f:
+                return f.read().strip()
+        with open(os.path.join(git_dir, "packed-refs")) as f:
+            for line in f:
+                if line.rstrip().endswith(" " + ref):
+                    return line.split()[0]
+    except OSError:
+        pass
+    return None
+
+
+def package_version(name):
+    """
+    Returns the installed version of a Python package, None if it is not installed.
+    """
+    try:
+        return importlib_metadata.version(name)
+    except importlib_metadata.PackageNotFoundError:
+        return None
+
+
+def run_metadata(approach, solver, solver_version=None, variant=None, seed=None, threads=None, log=None):
+    """
+    Builds the metadata block stored in every result, tracing it back to its configuration.
+
+    Params:
+        approach: CP, SAT, SMT or MIP
+        solver: The solver of the run
+        solver_version: Its version, if known
+        variant: The model variant (search strategy, encoding, formulation, ...)
+        seed: The random seed, if set
+        threads: The number of threads, if set
+        log: The path of the solver log of the run, if captured
+
+    Returns:
+        dict: {"commit", "run_id", "approach", "variant", "solver", "solver_version", "seed",
+               "threads", "hostname", "timestamp", "log"} (UTC ISO 8601 timestamp)
+    """
+    return {"commit": git_commit(), "run_id": current_run()["run_id"], "approach": approach, "variant": variant,
+            "solver": solver, "solver_version": solver_version, "seed": seed, "threads": threads,
+            "hostname": socket.gethostname(),
+            "timestamp": datetime.now(timezone.utc).isoformat(timespec="seconds"), "log": log}
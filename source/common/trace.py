@@ -0,0 +1,27 @@
+import time
+
+
+def record_improvement(solver_params, start_time, obj):
+    """
+    Appends [seconds since start_time, obj] to solver_params["trace"], the anytime trace of
+    the improving solutions of a run, if obj improves on the last recorded objective.
+
+    Params:
+        solver_params: The solver parameters of the run, stored in the results
+        start_time: The start time of the run (time.time())
+        obj: The objective of the new solution
+    """
+    trace = solver_params.setdefault("trace", [])
+    if obj is not None and (not trace or obj < trace[-1][1]):
+        trace.append([round(time.time() - start_time, 3), obj])
+
+
+def pop_trace(params):
+    """
+    Removes the anytime trace from the solver parameters of a run, to store it as the
+    "trace" key of the result entry.
+
+    Returns:
+        list: The [time, obj] pairs of the improving solutions ([] if none was recorded)
+    """
+    return (params or {}).pop("trace", None) or []
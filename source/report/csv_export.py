@@ -0,0 +1,82 @@
+from source.report.table import DEFAULT_RESULTS_DIR, load_results
+import csv
+import json
+import sys
+
+# Leading columns of every row; the remaining ones are the other result keys and the
+# flattened solver parameters (params.<key>.<subkey>), in order of appearance
+RUN_COLUMNS = ["approach", "n", "weeks", "periods", "matches", "configuration", "solver", "sb", "opt", "variant",
+               "time", "optimal", "obj", "solved", "bound"]
+
+
+def parse_configuration(key):
+    """
+    Splits a configuration key (<solver>_<sb|nosb>[_<heuristic>]_<opt|noopt>[_<variant>...])
+    into its solver, symmetry breaking and optimization flags and the remaining variant tokens.
+    """
+    tokens = key.split("_")
+    variant = [t for t in tokens[1:] if t not in ("sb", "nosb", "opt", "noopt")]
+    return {"solver": tokens[0], "sb": "sb" in tokens, "opt": "opt" in tokens, "variant": "_".join(variant)}
+
+
+def flatten(value, prefix):
+    """
+    Flattens nested dicts into dotted keys; lists are kept as JSON strings.
+    """
+    if isinstance(value, dict):
+        flat = {}
+        for key, item in value.items():
+            flat.update(flatten(item, f"{prefix}.{key}"))
+        return flat
+    return {prefix: json.dumps(value) if isinstance(value, list) else value}
+
+
+def proven_bound(result):
+    """
+    Returns the proven lower bound of a run, wherever its approach stores it (None if none).
+    """
+    params = result.get("params") or {}
+    for bound in (result.get("bound"), (params.get("bounds") or {}).get("best_bound"), params.get("lower_bound")):
+        if bound is not None:
+            return bound
+    return None
+
+
+def run_rows(results):
+    """
+    Builds one flat row per run of the results ({approach: {n: {configuration: result}}}).
+
+    Returns:
+        tuple: (columns, rows), the rows as dicts
+    """
+    rows, extra = [], []
+    for approach, instances in results.items():
+        for n, configurations in instances.items():
+            for key, result in configurations.items():
+                row = {"approach": approach, "n": n, "weeks": n - 1, "periods": n // 2, "matches": n * (n - 1) // 2,
+                       "configuration": key, **parse_configuration(key),
+                       "time": result.get("time"), "optimal": result.get("optimal"), "obj": result.get("obj"),
+                       "solved": bool(result.get("sol")), "bound": proven_bound(result)}
+                for name, value in result.items():
+                    if name in ("time", "optimal", "obj", "sol", "bound"):
+                        continue
+                    row.update(flatten(value, name))
+                extra.extend(column for column in row if column not in RUN_COLUMNS and column not in extra)
+                rows.append(row)
+    return RUN_COLUMNS + extra, rows
+
+
+def run_csv(results_dir=DEFAULT_RESULTS_DIR, output=None):
+    """
+    Exports every run under res/ as a row of a flat CSV (instance features, configuration,
+    objective, bound, time and the flattened solver parameters and statistics under params.*),
+    to output or the standard output.
+    """
+    columns, rows = run_rows(load_results(results_dir))
+    f = open(output, "w", newline="") if output else sys.stdout
+    writer = csv.DictWriter(f, fieldnames=columns)
+    writer.writeheader()
+    writer.writerows(rows)
+    if output:
+        f.close()
+        print(f"{len(rows)} runs written to {output}")
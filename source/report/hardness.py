@@ -0,0 +1,102 @@
+from source.checker.checker import TIME_LIMIT, load_bounds
+from source.report.csv_export import proven_bound
+from source.report.table import DEFAULT_RESULTS_DIR, load_results, optimization_runs, print_table
+import csv
+
+# Columns of the ranking (--report hardness), hardest instance first
+HARDNESS_COLUMNS = ["rank", "n", "solved", "optimal runs", "avg time", "avg gap", "runs"]
+
+
+def instance_runs(results):
+    """
+    Groups the optimization runs of the results by instance.
+
+    Returns:
+        dict: {n: [(approach, configuration, result)]}
+    """
+    runs = {}
+    for approach, n, key, result in optimization_runs(results):
+        runs.setdefault(n, []).append((approach, key, result))
+    return runs
+
+
+def lower_bound(n, runs, bounds):
+    """
+    Returns the best lower bound known of the max imbalance of an instance: the proven optimum
+    of a run or of the bounds database, else the best bound proven by a run or in the database
+    (at least 1).
+    """
+    optima = [result["obj"] for _, _, result in runs if result.get("optimal") and result.get("obj") is not None]
+    entry = bounds.get(n, {})
+    if optima or "optimum" in entry:
+        return min(optima + ([entry["optimum"]] if "optimum" in entry else []))
+    proven = [proven_bound(result) for _, _, result in runs]
+    return max([1, entry.get("lower_bound", 1)] + [b for b in proven if b is not None])
+
+
+def gap(result, bound):
+    """
+    Returns the relative gap (obj - bound) / obj of a run stopped at the timeout, 1 if it found
+    no schedule.
+    """
+    if not result.get("sol") or result.get("obj") is None:
+        return 1.0
+    return (result["obj"] - bound) / result["obj"] if result["obj"] else 0.0
+
+
+def instance_hardness(results, bounds=None):
+    """
+    Aggregates the difficulty of every instance over the optimization runs of all the
+    approaches: the fraction of approaches with a configuration proving the optimum, the
+    fraction of such runs, the average time (TIME_LIMIT for an unproven run) and the average
+    gap of the runs stopped at the timeout.
+
+    Returns:
+        list: {"n", "solved", "optimal", "time", "gap", "runs"} per instance, hardest first (fewest
+              approaches proving the optimum, then largest gap, then longest time); gap None if
+              every run proved the optimum
+    """
+    bounds = load_bounds() if bounds is None else bounds
+    ranking = []
+    for n, runs in instance_runs(results).items():
+        approaches = {approach for approach, _, _ in runs}
+        proving = {approach for approach, _, result in runs if result.get("optimal")}
+        unproven = [result for _, _, result in runs if not result.get("optimal")]
+        bound = lower_bound(n, runs, bounds)
+        ranking.append({
+            "n": n,
+            "solved": len(proving) / len(approaches),
+            "optimal": (len(runs) - len(unproven)) / len(runs),
+            "time": sum(result.get("time", TIME_LIMIT) if result.get("optimal") else TIME_LIMIT
+                        for _, _, result in runs) / len(runs),
+            "gap": sum(gap(result, bound) for result in unproven) / len(unproven) if unproven else None,
+            "runs": len(runs),
+        })
+    return sorted(ranking, key=lambda e: (e["solved"], -(e["gap"] or 0), -e["time"], -e["n"]))
+
+
+def hardness_rows(ranking):
+    """
+    Formats the ranking as the rows of HARDNESS_COLUMNS.
+    """
+    return [[str(rank), str(e["n"]), f"{e['solved']:.0%}", f"{e['optimal']:.0%}", f"{e['time']:.1f}s",
+             "-" if e["gap"] is None else f"{e['gap']:.1%}", str(e["runs"])]
+            for rank, e in enumerate(ranking, 1)]
+
+
+def run_hardness(results_dir=DEFAULT_RESULTS_DIR, output=None):
+    """
+    Prints the instances under res/ ranked by difficulty, hardest first (see instance_hardness),
+    and writes the ranking as CSV to output if given (e.g. to pick a tuning subset).
+    """
+    ranking = instance_hardness(load_results(results_dir))
+    print(f"\nInstance hardness (optimization runs, {TIME_LIMIT}s per unproven run, hardest first)")
+    print_table(HARDNESS_COLUMNS, hardness_rows(ranking))
+    if output:
+        with open(output, "w", newline="") as f:
+            writer = csv.writer(f)
+            writer.writerow(["rank", "n", "solved", "optimal", "time", "gap", "runs"])
+            writer.writerows([rank, e["n"], round(e["solved"], 4), round(e["optimal"], 4), round(e["time"], 3),
+                              None if e["gap"] is None else round(e["gap"], 4), e["runs"]]
+                             for rank, e in enumerate(ranking, 1))
+        print(f"{len(ranking)} instances written to {output}")
@@ -0,0 +1,70 @@
+from source.report.table import DEFAULT_RESULTS_DIR, configurations, load_results
+
+
+def escape(text):
+    """
+    Escapes the LaTeX special characters of a configuration name.
+    """
+    return text.replace("\\", "\\textbackslash{}").replace("_", "\\_").replace("&", "\\&").replace("%", "\\%")
+
+
+def latex_cell(result):
+    """
+    Formats a result as a LaTeX cell: the objective (optimization runs) or the time, bold when
+    optimal (solved, without optimization), marked with a dagger when the run hit the time
+    limit with a solution; N/A without a schedule.
+    """
+    if result is None:
+        return ""
+    if not result.get("sol"):
+        return "N/A"
+    value = str(result["obj"] if result.get("obj") is not None else result["time"])
+    if result.get("optimal"):
+        return f"\\textbf{{{value}}}"
+    return f"{value}$^\\dagger$"
+
+
+def latex_table(approach, instances):
+    """
+    Builds the booktabs table of an approach, one row per configuration and one column per
+    instance.
+
+    Returns:
+        str: The table environment
+    """
+    columns = list(instances)
+    lines = [
+        "\\begin{table}[ht]",
+        "\\centering",
+        f"\\begin{{tabular}}{{l{'c' * len(columns)}}}",
+        "\\toprule",
+        "Configuration & " + " & ".join(str(n) for n in columns) + " \\\\",
+        "\\midrule",
+    ]
+    for key in configurations(instances):
+        cells = [latex_cell(instances[n].get(key)) for n in columns]
+        lines.append(f"{escape(key)} & " + " & ".join(cells) + " \\\\")
+    lines += [
+        "\\bottomrule",
+        "\\end{tabular}",
+        f"\\caption{{{approach} results: objective of the optimization runs, time (s) otherwise. "
+        f"Bold: optimal (solved); $^\\dagger$: time limit reached; N/A: no solution.}}",
+        f"\\label{{tab:results_{approach.lower()}}}",
+        "\\end{table}",
+    ]
+    return "\n".join(lines)
+
+
+def run_latex(results_dir=DEFAULT_RESULTS_DIR, output=None):
+    """
+    Exports the results table of every approach as LaTeX (booktabs, \\usepackage{booktabs} in
+    the report preamble), to output or the standard output.
+    """
+    tables = "\n\n".join(latex_table(approach, instances)
+                         for approach, instances in load_results(results_dir).items()) + "\n"
+    if output:
+        with open(output, "w") as f:
+            f.write(tables)
+        print(f"LaTeX tables written to {output}")
+    else:
+        print(tables, end="")
@@ -0,0 +1,93 @@
+from source.report.table import DEFAULT_RESULTS_DIR, is_optimization, load_results, results_table
+from source.report.scoring import borda_scores
+
+
+def markdown_table(header, rows):
+    """
+    Formats a table as GitHub markdown.
+    """
+    lines = ["| " + " | ".join(header) + " |", "|" + "|".join(" --- " for _ in header) + "|"]
+    lines += ["| " + " | ".join(cell or " " for cell in row) + " |" for row in rows]
+    return "\n".join(lines)
+
+
+def winners(results):
+    """
+    Finds the best optimization run of every instance over all the approaches: the lowest
+    objective, then optimal first, then the shortest time.
+
+    Returns:
+        dict: {n: (approach, configuration, result)}, for the instances with a solution
+    """
+    best = {}
+    for approach, instances in results.items():
+        for n, configurations in instances.items():
+            for key, result in configurations.items():
+                if result.get("obj") is None or not result.get("sol"):
+                    continue
+                rank = (result["obj"], not result.get("optimal"), result["time"])
+                if n not in best or rank < best[n][0]:
+                    best[n] = (rank, approach, key, result)
+    return {n: entry[1:] for n, entry in sorted(best.items())}
+
+
+def optima_counts(results):
+    """
+    Counts, per approach, the optimization runs proven optimal, the runs with a solution and the
+    instances solved to optimality by at least one configuration.
+
+    Returns:
+        list: Rows [approach, optimal runs, solved runs, total runs, instances solved optimally]
+    """
+    rows = []
+    for approach, instances in results.items():
+        runs = [r for configurations in instances.values() for key, r in configurations.items() if is_optimization(key)]
+        optimal_instances = sum(1 for configurations in instances.values()
+                                if any(r.get("optimal") and r.get("obj") is not None for r in configurations.values()))
+        rows.append([approach, str(sum(1 for r in runs if r.get("optimal"))),
+                     str(sum(1 for r in runs if r.get("sol"))), str(len(runs)),
+                     f"{optimal_instances}/{len(instances)}"])
+    return rows
+
+
+def markdown_report(results):
+    """
+    Builds the markdown summary of the results.
+
+    Returns:
+        str: The report
+    """
+    sections = ["# Results summary"]
+
+    sections.append("## Best run per instance\n\n" + markdown_table(
+        ["n", "approach", "configuration", "obj", "time (s)", "optimal"],
+        [[str(n), approach, key, str(r["obj"]), str(r["time"]), "yes" if r.get("optimal") else "no"]
+         for n, (approach, key, r) in winners(results).items()]))
+
+    sections.append("## Optima per approach\n\n" + markdown_table(
+        ["approach", "optimal runs", "solved runs", "optimization runs", "instances solved optimally"],
+        optima_counts(results)))
+
+    sections.append("## Borda ranking of the configurations\n\n" + markdown_table(
+        ["rank", "configuration", "score", "optimal instances"],
+        [[str(rank), name, f"{score:.2f}", str(optimal)]
+         for rank, (name, score, optimal) in enumerate(borda_scores(results), start=1)]))
+
+    for approach, instances in results.items():
+        sections.append(f"## {approach}\n\n" + markdown_table(*results_table(instances)))
+    sections.append("Cells: `obj / time` (optimization runs) or time, `*` = optimal (solved, without optimization), "
+                    "N/A = no solution.")
+    return "\n\n".join(sections) + "\n"
+
+
+def run_markdown(results_dir=DEFAULT_RESULTS_DIR, output=None):
+    """
+    Writes the markdown summary of the results under res/ to output or the standard output.
+    """
+    report = markdown_report(load_results(results_dir))
+    if output:
+        with open(output, "w") as f:
+            f.write(report)
+        print(f"Markdown summary written to {output}")
+    else:
+        print(report, end="")
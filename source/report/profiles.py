@@ -0,0 +1,119 @@
+import csv
+import os
+from source.report.table import DEFAULT_RESULTS_DIR, is_optimization, load_results
+
+DEFAULT_PROFILE_DIR = os.path.join(os.getcwd(), "artifacts/analysis")
+
+
+def solve_times(results):
+    """
+    Returns the time to optimality of every approach on every instance: the shortest time of its
+    optimization runs proven optimal, None if none is.
+
+    Returns:
+        dict: {approach: {n: time or None}}
+    """
+    times = {}
+    for approach, instances in results.items():
+        times[approach] = {}
+        for n, configurations in instances.items():
+            solved = [r["time"] for key, r in configurations.items()
+                      if is_optimization(key) and r.get("optimal") and r.get("sol")]
+            times[approach][n] = min(solved) if solved else None
+    return times
+
+
+def performance_profile(times):
+    """
+    Computes the performance profile of every approach (Dolan and More): for every instance the
+    ratio of its time to the best one over the approaches (times below the 1 s resolution
+    count as 1 s), and the fraction of the instances within every ratio tau.
+
+    Returns:
+        dict: {approach: [(tau, fraction)]}, a step function over the ratios of the approach
+    """
+    instances = sorted({n for per_instance in times.values() for n in per_instance})
+    best = {n: min((max(1, t[n]) for t in times.values() if t.get(n) is not None), default=None)
+            for n in instances}
+    profiles = {}
+    for approach, per_instance in times.items():
+        ratios = sorted(max(1, per_instance[n]) / best[n] for n in instances if per_instance.get(n) is not None)
+        profiles[approach] = [(round(tau, 4), round(sum(1 for r in ratios if r <= tau) / len(instances), 4))
+                              for tau in sorted(set(ratios))]
+    return profiles
+
+
+def cactus(times):
+    """
+    Computes the cactus plot data of every approach: the k-th shortest of its solve times, i.e.
+    the time needed to solve k instances.
+
+    Returns:
+        dict: {approach: [(k, time)]}
+    """
+    return {approach: list(enumerate(sorted(t for t in per_instance.values() if t is not None), start=1))
+            for approach, per_instance in times.items()}
+
+
+def write_csv(path, header, curves):
+    """
+    Writes the points of every curve as rows [approach, x, y].
+    """
+    with open(path, "w", newline="") as f:
+        writer = csv.writer(f)
+        writer.writerow(header)
+        for approach, points in curves.items():
+            writer.writerows([approach, x, y] for x, y in points)
+
+
+def plot(path, curves, xlabel, ylabel, log_x=False):
+    """
+    Draws the curves as step functions to path (the format from its extension).
+    """
+    # Imported on demand: only the plots need matplotlib
+    import matplotlib
+    matplotlib.use("Agg")
+    import matplotlib.pyplot as plt
+
+    fig, ax = plt.subplots(figsize=(6, 4))
+    for approach, points in curves.items():
+        if points:
+            xs, ys = zip(*points)
+            ax.step(xs, ys, where="post", marker="o", label=approach)
+    if log_x:
+        ax.set_xscale("log", base=2)
+    ax.set_xlabel(xlabel)
+    ax.set_ylabel(ylabel)
+    ax.grid(True, alpha=0.3)
+    ax.legend()
+    fig.tight_layout()
+    fig.savefig(path)
+    plt.close(fig)
+
+
+def run_profiles(results_dir=DEFAULT_RESULTS_DIR, output=None):
+    """
+    Exports the performance profiles and the cactus plot data of the approaches under res/ as
+    profile.csv and cactus.csv, with their plots (PNG) when matplotlib is installed, to the
+    output directory (artifacts/analysis by default).
+    """
+    output = output or DEFAULT_PROFILE_DIR
+    os.makedirs(output, exist_ok=True)
+    times = solve_times(load_results(results_dir))
+    profile, solved = performance_profile(times), cactus(times)
+
+    write_csv(os.path.join(output, "profile.csv"), ["approach", "tau", "fraction"], profile)
+    write_csv(os.path.join(output, "cactus.csv"), ["approach", "solved", "time"], solved)
+    written = ["profile.csv", "cactus.csv"]
+    try:
+        plot(os.path.join(output, "profile.png"), profile, "ratio to the best time (tau)",
+             "fraction of instances solved optimally", log_x=True)
+        plot(os.path.join(output, "cactus.png"), solved, "instances solved optimally", "time (s)")
+        written += ["profile.png", "cactus.png"]
+    except ImportError:
+        print("matplotlib is not installed: the plots are skipped")
+
+    for approach, per_instance in times.items():
+        count = sum(1 for t in per_instance.values() if t is not None)
+        print(f"{approach}: {count}/{len(per_instance)} instances solved optimally")
+    print(f"{', '.join(written)} written to {output}")
@@ -0,0 +1,77 @@
+from source.report.table import DEFAULT_RESULTS_DIR, load_results, optimization_runs, print_table
+
+
+def entrants(results):
+    """
+    Collects the optimization runs of every configuration of every approach, which compete
+    against each other on every instance (a missing run counts as no solution).
+
+    Returns:
+        tuple: (entrants, instances), {"APPROACH/configuration": {n: result}} and the sorted instances
+    """
+    runs = {}
+    for approach, n, key, result in optimization_runs(results):
+        runs.setdefault(f"{approach}/{key}", {})[n] = result
+    return runs, sorted({n for per_instance in runs.values() for n in per_instance})
+
+
+def quality(result):
+    """
+    Returns the quality of a run, lower is better: the objective, then proven optimal first;
+    the runs without a solution come last.
+    """
+    if not result or not result.get("sol") or result.get("obj") is None:
+        return None
+    return result["obj"], not result.get("optimal")
+
+
+def pair_score(result, other):
+    """
+    Scores a run against another on the same instance, as in the MiniZinc Challenge: 1 for a
+    better quality, 0 for a worse one, and on the same quality the share of the time of the
+    other, other / (own + other) (half of the point when both take no time). Two runs without
+    a solution score 0.
+    """
+    own, theirs = quality(result), quality(other)
+    if own is None:
+        return 0
+    if theirs is None or own < theirs:
+        return 1
+    if own > theirs:
+        return 0
+    total = result["time"] + other["time"]
+    return 0.5 if total == 0 else other["time"] / total
+
+
+def borda_scores(results):
+    """
+    Computes the Borda score of every configuration: the sum of its pair scores against all the
+    other configurations on every instance.
+
+    Returns:
+        list: [(configuration, score, optimal instances)], best score first
+    """
+    runs, instances = entrants(results)
+    scores = []
+    for name, per_instance in runs.items():
+        score = sum(pair_score(per_instance.get(n), others.get(n))
+                    for n in instances for other, others in runs.items() if other != name)
+        optimal = sum(1 for result in per_instance.values() if result.get("optimal") and result.get("sol"))
+        scores.append((name, score, optimal))
+    return sorted(scores, key=lambda entry: (-entry[1], entry[0]))
+
+
+def run_scores(results_dir=DEFAULT_RESULTS_DIR):
+    """
+    Prints the ranking of the optimization configurations under res/ by Borda score.
+    """
+    results = load_results(results_dir)
+    scores = borda_scores(results)
+    _, instances = entrants(results)
+
+    print(f"\nBorda scores over {len(instances)} instances ({len(scores)} optimization configurations)")
+    print_table(["rank", "configuration", "score", "optimal"],
+                [[str(rank), name, f"{score:.2f}", f"{optimal}/{len(instances)}"]
+                 for rank, (name, score, optimal) in enumerate(scores, start=1)])
+    print("\nPer instance and opponent: 1 for a better objective (or a proof of the same), the share "
+          "other / (own + other) of the time on a tie, 0 otherwise")
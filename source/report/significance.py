@@ -0,0 +1,129 @@
+from source.report.table import DEFAULT_RESULTS_DIR, load_results, print_table
+
+# Metrics compared by the tests: time to the result (300 when not optimal) and objective
+METRICS = ["time", "obj"]
+
+
+def parse_side(side, results):
+    """
+    Parses a side of the comparison: an approach ("SAT") or one of its configurations
+    ("SAT/z3_sb_opt").
+
+    Returns:
+        tuple: (approach, configuration), the configuration None for a whole approach
+    """
+    approach, _, key = side.partition("/")
+    if approach not in results:
+        raise ValueError(f"No results for approach '{approach}' (available: {', '.join(results)})")
+    if key and not any(key in configurations for configurations in results[approach].values()):
+        raise ValueError(f"No results for configuration '{key}' of {approach}")
+    return approach, key or None
+
+
+def side_values(results, approach, key, metric):
+    """
+    Returns the value of the metric of a side on every instance: that of the configuration, or
+    the best one over the configurations of the approach (lowest time / objective). Runs without
+    a solution have no objective.
+
+    Returns:
+        dict: {n: value}, for the instances where the side has a value
+    """
+    values = {}
+    for n, configurations in results[approach].items():
+        if key is None:
+            runs = list(configurations.values())
+        else:
+            runs = [configurations[key]] if key in configurations else []
+        candidates = [run.get(metric) for run in runs if metric == "time" or run.get("sol")]
+        candidates = [value for value in candidates if value is not None]
+        if candidates:
+            values[n] = min(candidates)
+    return values
+
+
+def signed_ranks(differences):
+    """
+    Ranks the absolute values of the non-zero differences, ties getting the average rank.
+
+    Returns:
+        list: (rank, sign) of every non-zero difference
+    """
+    nonzero = sorted((abs(d), 1 if d > 0 else -1) for d in differences if d != 0)
+    ranks, i = [], 0
+    while i < len(nonzero):
+        j = i
+        while j < len(nonzero) and nonzero[j][0] == nonzero[i][0]:
+            j += 1
+        ranks.extend(((i + 1 + j) / 2, sign) for _, sign in nonzero[i:j])
+        i = j
+    return ranks
+
+
+def wilcoxon(differences):
+    """
+    Paired Wilcoxon signed-rank test (zero differences dropped). The p-value is exact and
+    two-sided: the distribution of the positive rank sum is enumerated over the 2^m sign
+    assignments, on the doubled ranks so that the average ranks of ties stay integer.
+
+    Returns:
+        dict: {"pairs", "w_plus", "w_minus", "statistic", "p_value"} (p_value 1 without differences)
+    """
+    ranks = signed_ranks(differences)
+    w_plus = sum(rank for rank, sign in ranks if sign > 0)
+    w_minus = sum(rank for rank, sign in ranks if sign < 0)
+    statistic = min(w_plus, w_minus)
+
+    # counts[s]: number of sign assignments whose positive doubled rank sum is s
+    counts = [1]
+    for rank, _ in ranks:
+        doubled = int(2 * rank)
+        counts = [(counts[s] if s < len(counts) else 0) + (counts[s - doubled] if s >= doubled else 0)
+                  for s in range(len(counts) + doubled)]
+    tail = sum(c for s, c in enumerate(counts) if s <= 2 * statistic)
+    p_value = min(1.0, 2 * tail / 2 ** len(ranks)) if ranks else 1.0
+
+    return {"pairs": len(differences), "w_plus": w_plus, "w_minus": w_minus,
+            "statistic": statistic, "p_value": p_value}
+
+
+def compare_sides(results, first, second, metric):
+    """
+    Tests the metric of two sides paired on the instances where both have a value.
+
+    Returns:
+        dict: The test result with the instances, the median difference (first - second) and
+              the better side ("=" without differences)
+    """
+    a = side_values(results, *parse_side(first, results), metric)
+    b = side_values(results, *parse_side(second, results), metric)
+    instances = sorted(set(a) & set(b))
+    differences = [a[n] - b[n] for n in instances]
+    test = wilcoxon(differences)
+
+    ordered = sorted(differences)
+    middle = len(ordered) // 2
+    median = None if not ordered else \
+        ordered[middle] if len(ordered) % 2 else (ordered[middle - 1] + ordered[middle]) / 2
+    better = "=" if test["w_plus"] == test["w_minus"] else first if test["w_plus"] < test["w_minus"] else second
+    return {**test, "metric": metric, "instances": instances, "median_difference": median, "better": better}
+
+
+def run_significance(first, second, results_dir=DEFAULT_RESULTS_DIR, alpha=0.05):
+    """
+    Prints the paired Wilcoxon signed-rank tests of two approaches (or configurations) on the
+    time and the objective over the instances under res/.
+    """
+    results = load_results(results_dir)
+    rows = []
+    for metric in METRICS:
+        test = compare_sides(results, first, second, metric)
+        rows.append([metric, str(test["pairs"]), f"{test['w_plus']:g}", f"{test['w_minus']:g}",
+                     f"{test['p_value']:.4f}", "-" if test["median_difference"] is None
+                     else f"{test['median_difference']:g}",
+                     test["better"] if test["p_value"] < alpha else "not significant"])
+
+    print(f"\nWilcoxon signed-rank test: {first} vs {second}")
+    print_table(["metric", "pairs", "W+", "W-", "p-value", "median diff", f"better (alpha={alpha})"], rows)
+    print("\nPairs: instances with a value on both sides (approach: best configuration per instance); "
+          "differences are first - second, lower is better")
@@ -0,0 +1,106 @@
+import json
+import os
+
+DEFAULT_RESULTS_DIR = os.path.join(os.getcwd(), "res")
+
+# Approaches in the order of the report
+APPROACHES = ["CP", "SAT", "SMT", "MIP"]
+
+
+def load_results(results_dir=DEFAULT_RESULTS_DIR):
+    """
+    Loads the results under res/ (res/<approach>/<n>.json).
+
+    Returns:
+        dict: {approach: {n: {configuration: result}}}, the approaches in report order
+    """
+    results = {}
+    names = sorted(os.listdir(results_dir)) if os.path.isdir(results_dir) else []
+    for approach in sorted(names, key=lambda a: (APPROACHES.index(a) if a in APPROACHES else len(APPROACHES), a)):
+        directory = os.path.join(results_dir, approach)
+        if not os.path.isdir(directory):
+            continue
+        for name in os.listdir(directory):
+            stem, ext = os.path.splitext(name)
+            if ext != ".json" or not stem.isdigit():
+                continue
+            with open(os.path.join(directory, name)) as f:
+                results.setdefault(approach, {})[int(stem)] = json.load(f)
+    return {approach: dict(sorted(instances.items())) for approach, instances in results.items()}
+
+
+def is_optimization(key):
+    """
+    Tells whether a configuration key is an optimization run (the decision runs are
+    <...>_noopt[_<variant>]).
+    """
+    return "_noopt" not in key
+
+
+def optimization_runs(results):
+    """
+    Yields the optimization runs of the results ({approach: {n: {configuration: result}}}) as
+    (approach, n, configuration, result).
+    """
+    for approach, instances in results.items():
+        for n, configurations in instances.items():
+            for key, result in configurations.items():
+                if is_optimization(key):
+                    yield approach, n, key, result
+
+
+def configurations(instances):
+    """
+    Returns the configurations of an approach over all its instances, in order of appearance.
+    """
+    keys = []
+    for results in instances.values():
+        keys.extend(key for key in results if key not in keys)
+    return keys
+
+
+def cell(result):
+    """
+    Formats a result as a table cell: the objective (optimization runs) and the time, marked
+    with "*" when optimal (or solved, without optimization); "N/A" without a schedule.
+    """
+    if result is None:
+        return ""
+    if not result.get("sol"):
+        return "N/A"
+    value = f"{result['obj']} / {result['time']}s" if result.get("obj") is not None else f"{result['time']}s"
+    return value + (" *" if result.get("optimal") else "")
+
+
+def results_table(instances):
+    """
+    Builds the table of an approach: one row per configuration, one column per instance.
+
+    Returns:
+        tuple: (header, rows), the rows as lists of cells, the first one the configuration
+    """
+    rows = [[key] + [cell(results.get(key)) for results in instances.values()]
+            for key in configurations(instances)]
+    return ["configuration"] + [f"n={n}" for n in instances], rows
+
+
+def print_table(header, rows):
+    """
+    Prints a table with aligned columns.
+    """
+    widths = [max(len(row[i]) for row in [header] + rows) for i in range(len(header))]
+    print("  ".join(h.ljust(w) for h, w in zip(header, widths)))
+    print("  ".join("-" * w for w in widths))
+    for row in rows:
+        print("  ".join(c.ljust(w) for c, w in zip(row, widths)))
+
+
+def run_table(results_dir=DEFAULT_RESULTS_DIR):
+    """
+    Prints the configuration x instance table of every approach: objective (with optimization),
+    time, and "*" for the optimal (or solved) results.
+    """
+    for approach, instances in load_results(results_dir).items():
+        print(f"\n{approach}")
+        print_table(*results_table(instances))
+    print("\nobj / time, * = optimal (solved without optimization), N/A = no solution")
@@ -0,0 +1,96 @@
+from source.checker.checker import TIME_LIMIT
+from source.report.table import DEFAULT_RESULTS_DIR, load_results, optimization_runs, print_table
+
+
+def solved_runs(results, exclude=None):
+    """
+    Yields the optimization runs with a solution as (approach, n, configuration, result),
+    skipping the approach exclude.
+    """
+    for approach, n, key, result in optimization_runs(results):
+        if approach != exclude and result.get("sol") and result.get("obj") is not None:
+            yield approach, n, key, result
+
+
+def virtual_best(results, exclude=None):
+    """
+    Computes the virtual best solver (VBS) of every instance over all the approaches and their
+    configurations (but exclude): the best objective and the shortest time to optimality, with
+    the runs reaching them.
+
+    Returns:
+        dict: {n: {"obj", "obj_by", "time", "time_by"}}, time None (and time_by empty) if
+              no run proved the optimum
+    """
+    best = {}
+    for approach, n, key, result in solved_runs(results, exclude):
+        entry = best.setdefault(n, {"obj": None, "obj_by": [], "time": None, "time_by": []})
+        run = f"{approach}/{key}"
+        if entry["obj"] is None or result["obj"] < entry["obj"]:
+            entry["obj"], entry["obj_by"] = result["obj"], [run]
+        elif result["obj"] == entry["obj"]:
+            entry["obj_by"].append(run)
+        if result.get("optimal"):
+            if entry["time"] is None or result["time"] < entry["time"]:
+                entry["time"], entry["time_by"] = result["time"], [run]
+            elif result["time"] == entry["time"]:
+                entry["time_by"].append(run)
+    return dict(sorted(best.items()))
+
+
+def total_time(vbs, instances):
+    """
+    Sums the VBS times to optimality over the instances, TIME_LIMIT (the time of a run not
+    solved optimally) for the unsolved ones.
+    """
+    return sum(vbs[n]["time"] if n in vbs and vbs[n]["time"] is not None else TIME_LIMIT for n in instances)
+
+
+def contributions(results, vbs):
+    """
+    Measures the contribution of every approach to the VBS: the instances where it reaches the
+    best objective and the best time, and the loss of the VBS without it (instances with a worse
+    objective, and the increase of the total time to optimality).
+
+    Returns:
+        list: Rows [approach, best obj, fastest, worse obj without, time added without]
+    """
+    instances = list(vbs)
+    rows = []
+    for approach in results:
+        without = virtual_best(results, exclude=approach)
+        best_obj = sum(1 for entry in vbs.values() if any(r.startswith(f"{approach}/") for r in entry["obj_by"]))
+        fastest = sum(1 for entry in vbs.values() if any(r.startswith(f"{approach}/") for r in entry["time_by"]))
+        worse = sum(1 for n in instances if n not in without or without[n]["obj"] > vbs[n]["obj"])
+        rows.append([approach, f"{best_obj}/{len(instances)}", f"{fastest}/{len(instances)}", str(worse),
+                     f"{total_time(without, instances) - total_time(vbs, instances)}s"])
+    return rows
+
+
+def runs_cell(runs):
+    """
+    Formats the runs reaching a VBS value: the first one and the number of the others.
+    """
+    if not runs:
+        return "-"
+    return runs[0] + (f" (+{len(runs) - 1})" if len(runs) > 1 else "")
+
+
+def run_vbs(results_dir=DEFAULT_RESULTS_DIR):
+    """
+    Prints the virtual best solver of every instance under res/ and the contribution of every
+    approach to it.
+    """
+    results = load_results(results_dir)
+    vbs = virtual_best(results)
+
+    print("\nVirtual best solver")
+    print_table(["n", "obj", "obj reached by", "time", "fastest proof"],
+                [[str(n), str(entry["obj"]), runs_cell(entry["obj_by"]),
+                  "-" if entry["time"] is None else f"{entry['time']}s", runs_cell(entry["time_by"])]
+                 for n, entry in vbs.items()])
+
+    print(f"\nContribution per approach (VBS total time to optimality: {total_time(vbs, vbs)}s, "
+          f"{TIME_LIMIT}s per unproven instance)")
+    print_table(["approach", "best obj", "fastest", "worse obj without", "time added without"],
+                contributions(results, vbs))
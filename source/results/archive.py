@@ -0,0 +1,127 @@
+from datetime import datetime, timezone
+import hashlib
+import io
+import json
+import os
+import platform
+import socket
+import sys
+import tarfile
+from source.common.metadata import REPO_DIR, git_commit, package_version
+from source.results.logs import LOGS_DIR
+from source.results.runs import RUNS_DIR, load_index
+
+# Directory of the archives (--archive), and of the analysis artifacts they bundle
+ARCHIVES_DIR = "archives"
+ARTIFACTS_DIR = "artifacts"
+
+
+def find_run(run_id=None):
+    """
+    Returns the run of the index with the given ID (the most recent one with it), or the latest
+    run without an ID.
+
+    Returns:
+        dict: The run, as in the index
+    """
+    runs = [run for run in load_index()["runs"] if run_id is None or run["run_id"] == run_id]
+    if not runs:
+        raise ValueError(f"No run {run_id + ' ' if run_id else ''}in the index of {RUNS_DIR}")
+    return runs[-1]
+
+
+def environment_report():
+    """
+    Describes the environment the archive is built in: the Python and platform versions, the
+    host, the commit and the versions of the packages of requirements.txt.
+
+    Returns:
+        dict: {"python", "platform", "hostname", "commit", "packages", "created"}
+    """
+    with open(os.path.join(REPO_DIR, "requirements.txt")) as f:
+        packages = [line.strip() for line in f if line.strip() and not line.startswith("#")]
+    return {"python": sys.version.split()[0], "platform": platform.platform(), "hostname": socket.gethostname(),
+            "commit": git_commit(), "packages": {name: package_version(name) for name in packages},
+            "created": datetime.now(timezone.utc).isoformat(timespec="seconds")}
+
+
+def archive_members(run):
+    """
+    Lists the files bundled for a run as (path on disk, path in the archive): its result files
+    (results/<approach>/<n>.json), its solver logs (logs/<approach>/<n>_<run_id>.log) and the
+    analysis artifacts (artifacts/...).
+    """
+    members = [(os.path.join(RUNS_DIR, run["directory"], name), f"results/{name}")
+               for name in run["files"] if os.path.exists(os.path.join(RUNS_DIR, run["directory"], name))]
+    for name in run["files"]:
+        approach, result = name.split("/")
+        log = os.path.join(LOGS_DIR, approach, f"{os.path.splitext(result)[0]}_{run['run_id']}.log")
+        if os.path.exists(log):
+            members.append((log, f"logs/{approach}/{os.path.basename(log)}"))
+    for root, _, names in sorted(os.walk(ARTIFACTS_DIR)):
+        for name in sorted(names):
+            path = os.path.join(root, name)
+            members.append((path, f"artifacts/{os.path.relpath(path, ARTIFACTS_DIR).replace(os.sep, '/')}"))
+    return members
+
+
+def sha256(path):
+    """
+    Returns the SHA-256 digest of a file, as hex.
+    """
+    digest = hashlib.sha256()
+    with open(path, "rb") as f:
+        for chunk in iter(lambda: f.read(1 << 16), b""):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+
+def add_json(tar, name, data):
+    """
+    Adds a JSON document to the archive as the member name.
+    """
+    content = (json.dumps(data, indent=2) + "\n").encode()
+    info = tarfile.TarInfo(name)
+    info.size = len(content)
+    info.mtime = int(datetime.now(timezone.utc).timestamp())
+    tar.addfile(info, io.BytesIO(content))
+
+
+def build_archive(run, output):
+    """
+    Bundles a run into the gzip-compressed tarball output, every member under the directory
+    of the run: its results, logs and the artifacts (see archive_members), environment.json
+    (see environment_report) and manifest.json, listing the run and every file with its size
+    and SHA-256.
+
+    Returns:
+        dict: The manifest
+    """
+    root = run["directory"]
+    members = [(path, arcname) for path, arcname in archive_members(run)
+               if os.path.abspath(path) != os.path.abspath(output)]
+    manifest = {"run": run, "created": datetime.now(timezone.utc).isoformat(timespec="seconds"),
+                "files": [{"path": arcname, "size": os.path.getsize(path), "sha256": sha256(path)}
+                          for path, arcname in members]}
+
+    os.makedirs(os.path.dirname(os.path.abspath(output)), exist_ok=True)
+    with tarfile.open(output, "w:gz") as tar:
+        add_json(tar, f"{root}/manifest.json", manifest)
+        add_json(tar, f"{root}/environment.json", environment_report())
+        for path, arcname in members:
+            tar.add(path, arcname=f"{root}/{arcname}")
+    return manifest
+
+
+def run_archive(run_id=None, output=None):
+    """
+    Archives a run of the index (default: the latest) to output, by default
+    archives/<YYYYmmdd-HHMMSS>_<run_id>.tar.gz, ready to be attached to the submission.
+    """
+    run = find_run(run_id)
+    output = output or os.path.join(ARCHIVES_DIR, f"{run['directory']}.tar.gz")
+    manifest = build_archive(run, output)
+    counts = {kind: sum(1 for f in manifest["files"] if f["path"].startswith(f"{kind}/"))
+              for kind in ["results", "logs", "artifacts"]}
+    print(f"Run {run['run_id']} archived to {output} ({os.path.getsize(output)} bytes): "
+          f"{counts['results']} result files, {counts['logs']} logs, {counts['artifacts']} artifacts")
@@ -0,0 +1,114 @@
+from datetime import datetime, timezone
+import os
+import sys
+from source.results.runs import current_run
+
+# Solver logs, one per instance and run: logs/<approach>/<n>_<run_id>.log
+LOGS_DIR = "logs"
+
+# Verbosity of the logs (--solver-log): errors=stderr of the solver subprocesses only, normal=also
+# the console output of the run, full=also the stdout of the subprocesses (e.g. Glucose models)
+VERBOSITY = ["errors", "normal", "full"]
+
+# Bytes kept of every captured output (--log-max-bytes), the head and the tail
+DEFAULT_MAX_BYTES = 100_000
+
+_CONFIG = {"verbosity": None, "max_bytes": DEFAULT_MAX_BYTES}
+_ACTIVE = {"file": None, "streams": None}
+
+
+class _Tee:
+    """
+    A text stream writing to the console stream and to the log file.
+    """
+
+    def __init__(self, stream, log):
+        self.stream = stream
+        self.log = log
+
+    def write(self, text):
+        self.log.write(text)
+        return self.stream.write(text)
+
+    def flush(self):
+        self.log.flush()
+        self.stream.flush()
+
+    def __getattr__(self, name):
+        return getattr(self.stream, name)
+
+
+def configure_logs(verbosity, max_bytes=None):
+    """
+    Enables the solver logs with a verbosity of VERBOSITY (None disables them).
+    """
+    if verbosity is not None and verbosity not in VERBOSITY:
+        raise ValueError(f"Unknown log verbosity '{verbosity}' (available: {', '.join(VERBOSITY)})")
+    _CONFIG["verbosity"] = verbosity
+    _CONFIG["max_bytes"] = max_bytes or DEFAULT_MAX_BYTES
+
+
+def truncate(text, max_bytes=None):
+    """
+    Keeps the head and the tail of a text longer than max_bytes characters, with a marker of
+    the part dropped.
+    """
+    max_bytes = max_bytes or _CONFIG["max_bytes"]
+    if text is None or len(text) <= max_bytes:
+        return text
+    half = max_bytes // 2
+    return f"{text[:half]}\n[... {len(text) - 2 * half} characters truncated ...]\n{text[-half:]}"
+
+
+def open_log(approach, n, key):
+    """
+    Starts the log of a configuration in logs/<approach>/<n>_<run_id>.log (appended to the
+    logs of the other configurations of the instance in the run); with verbosity normal or
+    full the console output is captured too, until close_log.
+
+    Returns:
+        str: The path of the log, stored in the result metadata (None if the logs are disabled)
+    """
+    if _CONFIG["verbosity"] is None:
+        return None
+    close_log()
+    directory = os.path.join(LOGS_DIR, approach)
+    os.makedirs(directory, exist_ok=True)
+    path = os.path.join(directory, f"{n}_{current_run()['run_id']}.log")
+
+    log = open(path, "a")
+    log.write(f"===== {key} ({datetime.now(timezone.utc).isoformat(timespec='seconds')}) =====\n")
+    _ACTIVE["file"] = log
+    if _CONFIG["verbosity"] != "errors":
+        _ACTIVE["streams"] = sys.stdout, sys.stderr
+        sys.stdout, sys.stderr = _Tee(sys.stdout, log), _Tee(sys.stderr, log)
+    return path
+
+
+def close_log():
+    """
+    Ends the active log, if any, restoring the console streams.
+    """
+    if _ACTIVE["streams"] is not None:
+        sys.stdout, sys.stderr = _ACTIVE["streams"]
+        _ACTIVE["streams"] = None
+    if _ACTIVE["file"] is not None:
+        _ACTIVE["file"].write("\n")
+        _ACTIVE["file"].close()
+        _ACTIVE["file"] = None
+
+
+def log_subprocess(name, result):
+    """
+    Appends the output of a finished solver subprocess (subprocess.CompletedProcess) to the
+    active log: its exit code and stderr, and its stdout with verbosity full, each truncated.
+    """
+    log = _ACTIVE["file"]
+    if log is None:
+        return
+    log.write(f"----- {name} (exit code {result.returncode}) -----\n")
+    streams = [("stderr", result.stderr)] + ([("stdout", result.stdout)] if _CONFIG["verbosity"] == "full" else [])
+    for stream, text in streams:
+        if text:
+            log.write(f"[{stream}]\n{truncate(text)}\n")
+    log.flush()
@@ -0,0 +1,72 @@
+import os
+from source.checker.fix import fix_result
+from source.results.serializer import write_file
+from source.report.table import load_results
+
+# Conflict resolution policies of the merge (--merge-policy)
+POLICIES = ["objective", "proof"]
+
+
+def rank(result, policy):
+    """
+    Returns the sort key of a result under a merge policy, lower is better; the results with a
+    solution always come first.
+        objective: lowest objective, then proven optimal, then fastest
+        proof: proven optimal, then fastest, then lowest objective
+    """
+    solved = bool(result.get("sol"))
+    obj = result.get("obj") if result.get("obj") is not None else 0
+    optimal = bool(result.get("optimal"))
+    if policy == "proof":
+        return not solved, not optimal, result.get("time", 300), obj
+    return not solved, obj, not optimal, result.get("time", 300)
+
+
+def merge_results(directories, policy="objective"):
+    """
+    Merges the results of several result directories: for every approach, instance and
+    configuration the best result under the policy is kept (the first directory wins on a
+    tie), with the directory it comes from stored as metadata["merged_from"].
+
+    Returns:
+        tuple: ({approach: {n: {configuration: result}}}, number of conflicts), a conflict being
+               a configuration with different results in several directories
+    """
+    if policy not in POLICIES:
+        raise ValueError(f"Unknown merge policy '{policy}' (available: {', '.join(POLICIES)})")
+
+    candidates = {}
+    for directory in directories:
+        for approach, instances in load_results(directory).items():
+            for n, configurations in instances.items():
+                for key, result in configurations.items():
+                    candidates.setdefault((approach, n, key), []).append((directory, result))
+
+    merged, conflicts = {}, 0
+    for (approach, n, key), entries in candidates.items():
+        if len({repr(result) for _, result in entries}) > 1:
+            conflicts += 1
+        directory, result = min(entries, key=lambda entry: rank(entry[1], policy))
+        result = {**result, "metadata": {**(result.get("metadata") or {}), "merged_from": directory}}
+        merged.setdefault(approach, {}).setdefault(n, {})[key] = result
+    return merged, conflicts
+
+
+def run_merge(directories, output, policy="objective"):
+    """
+    Merges the result directories (each laid out as res/) into output, resolving the
+    conflicts by the policy.
+    """
+    merged, conflicts = merge_results(directories, policy)
+    files = 0
+    for approach, instances in merged.items():
+        os.makedirs(os.path.join(output, approach), exist_ok=True)
+        for n, configurations in instances.items():
+            # The merged results are written as found: the checker reports the invalid ones
+            write_file(os.path.join(output, approach, f"{n}.json"),
+                       {key: fix_result(result)[0] for key, result in configurations.items()}, strict=False)
+            files += 1
+
+    runs = sum(len(configurations) for instances in merged.values() for configurations in instances.values())
+    print(f"Merged {len(directories)} directories into {output}: {files} files, {runs} runs, "
+          f"{conflicts} conflicts resolved by {policy}")
@@ -0,0 +1,59 @@
+import json
+import os
+import socket
+import urllib.error
+import urllib.request
+from source.results.runs import current_run
+
+# Endpoint the written results are posted to (--push-url), if set
+_ENDPOINT = {"url": None}
+
+# Seconds before a push is given up
+PUSH_TIMEOUT = 10
+
+
+def set_push_url(url):
+    """
+    Makes the approaches POST every result file they write to url.
+    """
+    _ENDPOINT["url"] = url
+
+
+def push_payload(path):
+    """
+    Builds the JSON document posted for a result file res/<approach>/<n>.json.
+
+    Returns:
+        dict: {"approach", "n", "run_id", "hostname", "results"}
+    """
+    with open(path) as f:
+        results = json.load(f)
+    return {"approach": os.path.basename(os.path.dirname(os.path.abspath(path))),
+            "n": int(os.path.splitext(os.path.basename(path))[0]),
+            "run_id": current_run()["run_id"], "hostname": socket.gethostname(), "results": results}
+
+
+def push_file(path):
+    """
+    POSTs a result file just written to the endpoint set by set_push_url (with the bearer token
+    PUSH_TOKEN, if set); does nothing without it. A failed push is reported but never stops the
+    run: the results are still in res/ and in the run directory.
+
+    Returns:
+        bool: Whether the endpoint accepted the results (None without an endpoint)
+    """
+    url = _ENDPOINT["url"]
+    if url is None:
+        return None
+    headers = {"Content-Type": "application/json"}
+    if os.environ.get("PUSH_TOKEN"):
+        headers["Authorization"] = f"Bearer {os.environ['PUSH_TOKEN']}"
+    request = urllib.request.Request(url, data=json.dumps(push_payload(path)).encode(), headers=headers,
+                                     method="POST")
+    try:
+        with urllib.request.urlopen(request, timeout=PUSH_TIMEOUT) as response:
+            print(f"Pushed {path} to {url} (HTTP {response.status})")
+            return True
+    except (urllib.error.URLError, OSError) as e:
+        print(f"Warning: could not push {path} to {url}: {e}")
+        return False
@@ -0,0 +1,125 @@
+import json
+import os
+from source.checker.golden import result_regressions
+from source.results.runs import RUNS_DIR, load_index, started_run
+
+# Slowdown (percent) of a proven result reported as a regression (--slowdown)
+DEFAULT_SLOWDOWN = 20
+
+# Slowdowns under this many seconds are noise (the times are whole seconds)
+MIN_SLOWDOWN_SECONDS = 2
+
+
+def load_run_file(run, name):
+    """
+    Loads the result file <approach>/<n>.json of a run of the index, None if unreadable.
+    """
+    try:
+        with open(os.path.join(RUNS_DIR, run["directory"], name)) as f:
+            return json.load(f)
+    except (OSError, ValueError):
+        return None
+
+
+def previous_result(runs, position, name, key):
+    """
+    Finds the most recent result of the same file and configuration in the runs before the
+    given position of the index.
+
+    Returns:
+        tuple: (run, result), (None, None) if no previous run has it
+    """
+    for run in reversed(runs[:position]):
+        if name in run["files"]:
+            data = load_run_file(run, name)
+            if data is not None and key in data:
+                return run, data[key]
+    return None, None
+
+
+def run_regressions(previous, current, slowdown=DEFAULT_SLOWDOWN):
+    """
+    Compares a result with the one of the previous run of its configuration: a lost schedule
+    or optimality claim (newly unsolved), a worse objective (see result_regressions) and, for a
+    result proven both times, a time more than slowdown percent (and MIN_SLOWDOWN_SECONDS)
+    longer.
+
+    Returns:
+        list: (category, message) of every regression, category "unsolved", "objective" or "slowdown"
+    """
+    found = []
+    for message in result_regressions("", previous, current):
+        message = message[2:]
+        found.append(("objective" if "objective" in message else "unsolved", message))
+    if previous.get("optimal") and current.get("optimal"):
+        before, after = previous.get("time", 0), current.get("time", 0)
+        if after - before >= MIN_SLOWDOWN_SECONDS and after > before * (1 + slowdown / 100):
+            increase = f"+{round(100 * (after - before) / before)}%" if before else "from 0s"
+            found.append(("slowdown", f"time {before}s -> {after}s ({increase})"))
+    return found
+
+
+def batch_regressions(run_id=None, slowdown=DEFAULT_SLOWDOWN):
+    """
+    Compares every result of a run of the index (the run of this process, or run_id, or the
+    latest run) with the previous run of the same configuration.
+
+    Returns:
+        tuple: (run, [(category, file, configuration, previous run ID, message)]), run None if
+               there is no such run in the index
+    """
+    runs = load_index()["runs"]
+    started = started_run()
+    if run_id is None and started is not None:
+        matches = [i for i, r in enumerate(runs) if r["directory"] == started["directory"]]
+    else:
+        matches = [i for i, r in enumerate(runs) if run_id is None or r["run_id"] == run_id]
+    if not matches:
+        return None, []
+    position = matches[-1]
+    run = runs[position]
+
+    found = []
+    for name in run["files"]:
+        for key, result in (load_run_file(run, name) or {}).items():
+            previous_run, previous = previous_result(runs, position, name, key)
+            if previous is None:
+                continue
+            found.extend((category, name, key, previous_run["run_id"], message)
+                         for category, message in run_regressions(previous, result, slowdown))
+    return run, found
+
+
+def print_regressions(run_id=None, slowdown=DEFAULT_SLOWDOWN):
+    """
+    Prints the regression summary of a run against the previous runs (see batch_regressions).
+
+    Returns:
+        bool: Whether no regression was found
+    """
+    run, found = batch_regressions(run_id, slowdown)
+    if run is None:
+        print(f"\nRegressions: no run {run_id + ' ' if run_id else ''}in the index to compare")
+        return True
+
+    print(f"\nRegressions of run {run['run_id']} against the previous runs (slowdown > {slowdown:g}%):")
+    if not found:
+        print("  none")
+        return True
+    for category, title in [("unsolved", "Newly unsolved"), ("objective", "Worse objective"),
+                            ("slowdown", "Slowdown")]:
+        rows = [entry for entry in found if entry[0] == category]
+        if rows:
+            print(f"  {title} ({len(rows)}):")
+            for _, name, key, previous_run, message in rows:
+                print(f"    {name} {key}: {message} (previous run {previous_run})")
+    return False
+
+
+def report_batch(slowdown=DEFAULT_SLOWDOWN):
+    """
+    Prints the regression summary of the batch just completed by this process (--all,
+    --single), nothing if it wrote no result.
+    """
+    if started_run() is not None:
+        print_regressions(slowdown=slowdown)
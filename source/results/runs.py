@@ -0,0 +1,104 @@
+from datetime import datetime, timezone
+import json
+import os
+import shutil
+import sys
+import uuid
+
+# Versioned result directories, one per run of the entrypoint, and their index
+RUNS_DIR = os.path.join(os.getcwd(), "runs")
+INDEX_FILE = os.path.join(RUNS_DIR, "index.json")
+
+# Run of this process, created on the first result written
+_RUN = {}
+
+
+def set_run_id(run_id):
+    """
+    Sets the ID of the run of this process (--run-id), before any result is written.
+    """
+    if _RUN:
+        raise RuntimeError(f"The run {_RUN['run_id']} has already started")
+    _RUN["run_id"] = run_id
+
+
+def current_run():
+    """
+    Returns the run of this process, creating it on the first call: its ID (--run-id, RUN_ID
+    or a random one), its start time and its directory runs/<YYYYmmdd-HHMMSS>_<run_id>.
+
+    Returns:
+        dict: {"run_id", "started", "directory", "command"}
+    """
+    if "started" not in _RUN:
+        started = datetime.now(timezone.utc)
+        run_id = _RUN.get("run_id") or os.environ.get("RUN_ID") or uuid.uuid4().hex[:8]
+        _RUN.update({"run_id": run_id, "started": started.isoformat(timespec="seconds"),
+                     "directory": f"{started.strftime('%Y%m%d-%H%M%S')}_{run_id}",
+                     "command": sys.argv[1:]})
+    return _RUN
+
+
+def started_run():
+    """
+    Returns the run of this process if it has written a result, without creating it.
+
+    Returns:
+        dict: As current_run, None if the run has not started
+    """
+    return _RUN if "started" in _RUN else None
+
+
+def load_index():
+    """
+    Loads the index of the runs (runs/index.json).
+
+    Returns:
+        dict: {"runs": [{"run_id", "started", "directory", "command", "files"}]}, oldest first
+    """
+    if not os.path.exists(INDEX_FILE):
+        return {"runs": []}
+    with open(INDEX_FILE) as f:
+        return json.load(f)
+
+
+def archive_result(path):
+    """
+    Copies a result file res/<approach>/<n>.json just written into the directory of the current
+    run and records it in the index, so that the next runs, which overwrite res/, never lose it.
+
+    Returns:
+        str: The path of the copy
+    """
+    run = current_run()
+    approach = os.path.basename(os.path.dirname(os.path.abspath(path)))
+    directory = os.path.join(RUNS_DIR, run["directory"], approach)
+    os.makedirs(directory, exist_ok=True)
+    copy = os.path.join(directory, os.path.basename(path))
+    shutil.copyfile(path, copy)
+
+    index = load_index()
+    entry = next((r for r in index["runs"] if r["run_id"] == run["run_id"] and r["directory"] == run["directory"]),
+                 None)
+    if entry is None:
+        entry = {**run, "files": []}
+        index["runs"].append(entry)
+    name = f"{approach}/{os.path.basename(path)}"
+    if name not in entry["files"]:
+        entry["files"].append(name)
+    with open(INDEX_FILE, "w") as f:
+        json.dump(index, f, indent=2)
+        f.write("\n")
+    return copy
+
+
+def print_runs():
+    """
+    Prints the runs of the index, oldest first.
+    """
+    runs = load_index()["runs"]
+    for run in runs:
+        print(f"{run['run_id']:<12}  {run['started']}  {len(run['files']):>3} files  runs/{run['directory']}  "
+              f"{' '.join(run['command'])}")
+    if not runs:
+        print(f"No run in {INDEX_FILE}")
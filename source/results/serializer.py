@@ -0,0 +1,81 @@
+import json
+import math
+from source.checker.schema import RESULT_SCHEMA, validate
+
+# Mandatory keys of a result, in the order they are written
+MANDATORY_KEYS = ("time", "optimal", "obj", "sol")
+
+
+def integral(value):
+    """
+    Returns an integral float as an int, any other value unchanged.
+    """
+    if isinstance(value, float) and value.is_integer():
+        return int(value)
+    return value
+
+
+def normalize(result):
+    """
+    Normalizes the Python values of a result to the types of the format: the time floored to
+    int seconds, an integral objective as an int, sol as lists of int pairs (tuples and integral
+    floats of the solvers converted), the mandatory keys first and the other keys set to None
+    dropped.
+
+    Returns:
+        dict: The normalized result
+    """
+    normalized = {key: result.get(key) for key in MANDATORY_KEYS}
+    if isinstance(normalized["time"], float) and math.isfinite(normalized["time"]):
+        normalized["time"] = math.floor(normalized["time"])
+    normalized["obj"] = integral(normalized["obj"])
+    if normalized["sol"] is not None:
+        normalized["sol"] = json.loads(json.dumps(normalized["sol"]), parse_float=lambda s: integral(float(s)))
+    normalized.update((key, value) for key, value in result.items() if key not in MANDATORY_KEYS and value is not None)
+    return normalized
+
+
+def schema_errors(results):
+    """
+    Validates the results of a file ({configuration: result}) against the result schema.
+
+    Returns:
+        list: The violations (empty if every result is valid)
+    """
+    return validate({key: {k: result.get(k) for k in MANDATORY_KEYS if k in result}
+                     for key, result in results.items()}, RESULT_SCHEMA)
+
+
+def serialize(results):
+    """
+    Serializes the results of a file in the format of res/: one result per configuration, the
+    mandatory keys first and sol on a single line.
+
+    Returns:
+        str: The JSON text
+    """
+    lines = ['{']
+    for i, (key, val) in enumerate(results.items()):
+        lines.append(f'  {json.dumps(key)}: {{')
+        entries = [(k, json.dumps(v, separators=(',', ':')) if k == "sol" else json.dumps(v))
+                   for k, v in val.items()]
+        for j, (k, v) in enumerate(entries):
+            lines.append(f'    "{k}": {v}' + (',' if j < len(entries) - 1 else ''))
+        lines.append('  }' + (',' if i < len(results) - 1 else ''))
+    lines.append('}')
+    return "\n".join(lines) + "\n"
+
+
+def write_file(path, results, strict=True):
+    """
+    Writes the results of a file. With strict, the results must match the result schema.
+
+    Raises:
+        ValueError: If strict and a result does not match the schema
+    """
+    if strict:
+        errors = schema_errors(results)
+        if errors:
+            raise ValueError(f"Invalid results for {path}: {'; '.join(errors)}")
+    with open(path, 'w') as f:
+        f.write(serialize(results))
@@ -0,0 +1,136 @@
+import json
+import os
+import sqlite3
+from source.report.csv_export import flatten, parse_configuration
+from source.report.table import load_results
+from source.results.runs import RUNS_DIR
+
+# Default database (--sqlite), next to the run directories: res/ only holds the JSON results
+DEFAULT_DATABASE = os.path.join(RUNS_DIR, "results.db")
+
+SCHEMA = """
+CREATE TABLE IF NOT EXISTS runs (
+    id INTEGER PRIMARY KEY,
+    run_id TEXT,
+    approach TEXT NOT NULL,
+    n INTEGER NOT NULL,
+    configuration TEXT NOT NULL,
+    opt INTEGER,
+    time INTEGER,
+    optimal INTEGER,
+    obj INTEGER,
+    solved INTEGER,
+    commit_hash TEXT,
+    solver TEXT,
+    variant TEXT,
+    hostname TEXT,
+    timestamp TEXT,
+    source TEXT,
+    UNIQUE (run_id, approach, n, configuration)
+);
+CREATE TABLE IF NOT EXISTS solutions (
+    run INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    week INTEGER NOT NULL,
+    period INTEGER NOT NULL,
+    home INTEGER NOT NULL,
+    away INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS statistics (
+    run INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    value TEXT
+);
+CREATE INDEX IF NOT EXISTS runs_instance ON runs (approach, n);
+"""
+
+# Database the approaches store their results in besides the JSON files (--sqlite), if set
+_DATABASE = {"path": None}
+
+
+def set_database(path):
+    """
+    Makes the approaches store every result file they write in the SQLite database path too.
+    """
+    _DATABASE["path"] = path
+
+
+def connect(path=DEFAULT_DATABASE):
+    """
+    Opens the results database, creating its tables if needed.
+    """
+    os.makedirs(os.path.dirname(os.path.abspath(path)), exist_ok=True)
+    conn = sqlite3.connect(path)
+    conn.execute("PRAGMA foreign_keys = ON")
+    conn.executescript(SCHEMA)
+    return conn
+
+
+def store_result(conn, approach, n, key, result, source=None):
+    """
+    Stores a result: its row in runs (replacing the one of the same run, approach, instance and
+    configuration), its schedule in solutions (1-based periods and weeks) and the flattened
+    keys other than the mandatory ones (params.*, trace, ...) in statistics.
+    """
+    metadata = result.get("metadata") or {}
+    conn.execute("DELETE FROM runs WHERE run_id IS ? AND approach = ? AND n = ? AND configuration = ?",
+                 (metadata.get("run_id"), approach, n, key))
+    cursor = conn.execute(
+        "INSERT INTO runs (run_id, approach, n, configuration, opt, time, optimal, obj, solved, commit_hash, solver, "
+        "variant, hostname, timestamp, source) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+        (metadata.get("run_id"), approach, n, key, int(parse_configuration(key)["opt"]), result.get("time"),
+         int(bool(result.get("optimal"))), result.get("obj"), int(bool(result.get("sol"))), metadata.get("commit"),
+         metadata.get("solver"), metadata.get("variant"), metadata.get("hostname"), metadata.get("timestamp"), source))
+    run = cursor.lastrowid
+
+    sol = result.get("sol") or []
+    conn.executemany("INSERT INTO solutions (run, week, period, home, away) VALUES (?, ?, ?, ?, ?)",
+                     [(run, week + 1, period + 1, match[0], match[1])
+                      for period, weeks in enumerate(sol) for week, match in enumerate(weeks)
+                      if isinstance(match, list) and len(match) == 2])
+
+    statistics = {}
+    for name, value in result.items():
+        if name not in ("time", "optimal", "obj", "sol", "metadata"):
+            statistics.update(flatten(value, name))
+    conn.executemany("INSERT INTO statistics (run, key, value) VALUES (?, ?, ?)",
+                     [(run, name, value if value is None or isinstance(value, str) else json.dumps(value))
+                      for name, value in statistics.items()])
+
+
+def store_file(path):
+    """
+    Stores the results of a file res/<approach>/<n>.json in the database set by
+    set_database; does nothing without it.
+    """
+    if _DATABASE["path"] is None:
+        return
+    approach = os.path.basename(os.path.dirname(os.path.abspath(path)))
+    n = int(os.path.splitext(os.path.basename(path))[0])
+    with open(path) as f:
+        data = json.load(f)
+
+    conn = connect(_DATABASE["path"])
+    with conn:
+        for key, result in data.items():
+            store_result(conn, approach, n, key, result, source=path)
+    conn.close()
+
+
+def import_directories(directories, path=DEFAULT_DATABASE):
+    """
+    Imports the result directories laid out as res/ (e.g. res/ and the run directories under
+    runs/) into the database path.
+    """
+    conn = connect(path)
+    count = 0
+    with conn:
+        for directory in directories:
+            for approach, instances in load_results(directory).items():
+                for n, configurations in instances.items():
+                    for key, result in configurations.items():
+                        store_result(conn, approach, n, key, result,
+                                     source=os.path.join(directory, approach, f"{n}.json"))
+                        count += 1
+    total = conn.execute("SELECT COUNT(*) FROM runs").fetchone()[0]
+    conn.close()
+    print(f"{count} results imported into {path} ({total} runs stored)")
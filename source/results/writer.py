@@ -0,0 +1,33 @@
+import os
+from source.checker.checker import checked_results
+from source.results.push import push_file
+from source.results.runs import archive_result
+from source.results.serializer import normalize, write_file
+from source.results.sqlite_store import store_file
+
+
+def write_solution(output_dir, n, results_dict):
+    """
+    Writes the results of an instance to <output_dir>/<n>.json, the single writer of the four
+    approaches: every invalid result is written as an internal error (see checked_results), the
+    results are normalized to the types of the format and the file is checked against the
+    result schema before it is written, then archived in the run directory, stored in the
+    SQLite database and posted to the collection endpoint if set.
+
+    Params:
+        output_dir: The directory where the results will be saved (res/<approach>)
+        n: Number of teams of the instance
+        results_dict: {configuration: result} of the instance
+
+    Returns:
+        str: The path of the file written
+    """
+    results = {key: normalize(result) for key, result in checked_results(n, results_dict).items()}
+
+    out_path = os.path.join(output_dir, f"{n}.json")
+    write_file(out_path, results)
+
+    archive_result(out_path)
+    store_file(out_path)
+    push_file(out_path)
+    return out_path
@@ -0,0 +1,70 @@
+import unittest
+from unittest import mock
+from source.checker.checker import checked_results, result_errors
+from source.checker.problems import OFFICIAL_PERIOD_ERROR
+
+# An optimal schedule of n = 6 (res/CP/6.json, gecode_nosb_base_opt): periods x weeks of [home, away]
+SCHEDULE = [[[3, 2], [4, 5], [5, 6], [1, 6], [1, 4]],
+            [[5, 1], [6, 2], [4, 3], [2, 4], [3, 6]],
+            [[6, 4], [1, 3], [2, 1], [3, 5], [2, 5]]]
+
+
+def result(**changes):
+    """
+    Returns the valid optimal result of SCHEDULE, with the given keys changed.
+    """
+    return {"time": 12, "optimal": True, "obj": 1, "sol": [[list(m) for m in row] for row in SCHEDULE], **changes}
+
+
+class ResultErrorsTest(unittest.TestCase):
+
+    def test_valid_result(self):
+        self.assertEqual(result_errors(result()), [])
+
+    def test_objective_not_available(self):
+        self.assertEqual(result_errors(result(obj=None)), [])
+        self.assertEqual(result_errors(result(obj="N/A")), [])
+
+    def test_schema(self):
+        errors = result_errors(result(time=12.5))
+        self.assertEqual(errors, ["$.result.time: expected int, found float"])
+
+    def test_unproven_run_reports_the_time_limit(self):
+        self.assertEqual(result_errors(result(optimal=False, time=300)), [])
+        self.assertEqual(len(result_errors(result(optimal=False, time=12))), 1)
+        self.assertEqual(len(result_errors(result(optimal=True, time=300))), 1)
+
+    def test_objective_recomputed(self):
+        self.assertEqual(result_errors(result(obj=3)),
+                         ["The objective 3 differs from the max imbalance 1 of the schedule"])
+
+    def test_zero_based_indices(self):
+        shifted = [[[h - 1, a - 1] for h, a in row] for row in SCHEDULE]
+        self.assertEqual(result_errors(result(sol=shifted)),
+                         ["0-based team indices: the teams are numbered 0..5 instead of 1..6"])
+
+    def test_period_violation_reported_once(self):
+        # Swapping the first two periods of week 1 puts teams 1 and 5 three times in period 1,
+        # teams 2 and 3 in period 2
+        schedule = result()["sol"]
+        schedule[0][0], schedule[1][0] = schedule[1][0], schedule[0][0]
+        errors = result_errors(result(sol=schedule))
+        self.assertNotIn(OFFICIAL_PERIOD_ERROR, errors)
+        self.assertEqual([error.split(" (")[0] for error in errors],
+                         ["Team 1 plays 3 times in period 1", "Team 5 plays 3 times in period 1",
+                          "Team 2 plays 3 times in period 2", "Team 3 plays 3 times in period 2"])
+
+
+class CheckedResultsTest(unittest.TestCase):
+
+    def test_invalid_result_written_as_internal_error(self):
+        with mock.patch("sys.stdout"):
+            checked = checked_results(6, {"valid": result(), "invalid": result(obj=3)})
+        self.assertEqual(checked["valid"], result())
+        self.assertEqual(checked["invalid"]["outcome"], "internal error")
+        self.assertEqual((checked["invalid"]["sol"], checked["invalid"]["time"], checked["invalid"]["optimal"]),
+                         ([], 300, False))
+
+
+if __name__ == "__main__":
+    unittest.main()
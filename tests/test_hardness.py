@@ -0,0 +1,54 @@
+import unittest
+from source.report.hardness import gap, hardness_rows, instance_hardness, lower_bound
+
+
+def run(obj, time, optimal=False, **extra):
+    return {"time": time, "optimal": optimal, "obj": obj, "sol": [[[1, 2]]] if obj is not None else [], **extra}
+
+
+RESULTS = {"CP": {6: {"gecode_sb_opt": run(1, 10, True), "gecode_sb_noopt": run(None, 1, True)},
+                  8: {"gecode_sb_opt": run(1, 100, True)},
+                  10: {"gecode_sb_opt": run(3, 300)}},
+           "SAT": {6: {"z3_sb_opt": run(1, 20, True)},
+                   8: {"z3_sb_opt": run(3, 300)},
+                   10: {"z3_sb_opt": run(None, 300, bound=2)}}}
+
+
+class LowerBoundTest(unittest.TestCase):
+
+    def test_proven_optimum(self):
+        runs = [("CP", "a", run(2, 10, True)), ("SAT", "b", run(3, 300, bound=3))]
+        self.assertEqual(lower_bound(8, runs, {}), 2)
+        self.assertEqual(lower_bound(8, runs, {8: {"optimum": 1}}), 1)
+
+    def test_proven_bound(self):
+        runs = [("CP", "a", run(3, 300)), ("SAT", "b", run(None, 300, bound=2))]
+        self.assertEqual(lower_bound(8, runs, {}), 2)
+        self.assertEqual(lower_bound(8, runs, {8: {"lower_bound": 3}}), 3)
+        self.assertEqual(lower_bound(8, runs[:1], {}), 1)
+
+    def test_gap(self):
+        self.assertEqual(gap(run(4, 300), 1), 0.75)
+        self.assertEqual(gap(run(None, 300), 1), 1.0)
+
+
+class InstanceHardnessTest(unittest.TestCase):
+
+    def test_ranking(self):
+        ranking = instance_hardness(RESULTS, bounds={})
+        self.assertEqual([e["n"] for e in ranking], [10, 8, 6])
+        self.assertEqual(ranking[0], {"n": 10, "solved": 0, "optimal": 0, "time": 300, "gap": (1 / 3 + 1) / 2,
+                                      "runs": 2})
+        self.assertEqual(ranking[1], {"n": 8, "solved": 0.5, "optimal": 0.5, "time": 200, "gap": 2 / 3, "runs": 2})
+        # Only the optimization runs count
+        self.assertEqual(ranking[2], {"n": 6, "solved": 1, "optimal": 1, "time": 15, "gap": None, "runs": 2})
+
+    def test_rows(self):
+        self.assertEqual(hardness_rows(instance_hardness(RESULTS, bounds={})),
+                         [["1", "10", "0%", "0%", "300.0s", "66.7%", "2"],
+                          ["2", "8", "50%", "50%", "200.0s", "66.7%", "2"],
+                          ["3", "6", "100%", "100%", "15.0s", "-", "2"]])
+
+
+if __name__ == "__main__":
+    unittest.main()
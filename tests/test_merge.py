@@ -0,0 +1,68 @@
+import json
+import os
+import tempfile
+import unittest
+from unittest import mock
+from source.results.merge import merge_results, rank, run_merge
+
+SOL = [[[1, 2]]]
+
+
+def run(obj, time, optimal=False):
+    return {"time": time, "optimal": optimal, "obj": obj, "sol": SOL if obj is not None else []}
+
+
+def write_results(root, approach, n, results):
+    os.makedirs(os.path.join(root, approach), exist_ok=True)
+    with open(os.path.join(root, approach, f"{n}.json"), "w") as f:
+        json.dump(results, f)
+
+
+class RankTest(unittest.TestCase):
+
+    def test_objective_policy(self):
+        runs = [run(None, 300), run(3, 12, True), run(1, 300), run(1, 40, True)]
+        self.assertEqual(sorted(runs, key=lambda r: rank(r, "objective")),
+                         [run(1, 40, True), run(1, 300), run(3, 12, True), run(None, 300)])
+
+    def test_proof_policy(self):
+        runs = [run(None, 300), run(1, 300), run(3, 12, True), run(1, 40, True)]
+        self.assertEqual(sorted(runs, key=lambda r: rank(r, "proof")),
+                         [run(3, 12, True), run(1, 40, True), run(1, 300), run(None, 300)])
+
+
+class MergeTest(unittest.TestCase):
+
+    def setUp(self):
+        self.tmp = tempfile.TemporaryDirectory()
+        self.first, self.second = os.path.join(self.tmp.name, "a"), os.path.join(self.tmp.name, "b")
+        write_results(self.first, "SAT", 6, {"z3_sb_opt": run(3, 300), "z3_sb_noopt": run(None, 4, True)})
+        write_results(self.second, "SAT", 6, {"z3_sb_opt": run(1, 20, True), "z3_sb_noopt": run(None, 4, True)})
+        write_results(self.second, "MIP", 8, {"highs_sb_opt": run(1, 30, True)})
+
+    def tearDown(self):
+        self.tmp.cleanup()
+
+    def test_best_result_kept(self):
+        merged, conflicts = merge_results([self.first, self.second])
+        self.assertEqual(conflicts, 1)
+        self.assertEqual(merged["SAT"][6]["z3_sb_opt"], {**run(1, 20, True), "metadata": {"merged_from": self.second}})
+        # On a tie the first directory wins
+        self.assertEqual(merged["SAT"][6]["z3_sb_noopt"]["metadata"], {"merged_from": self.first})
+        self.assertEqual(set(merged["MIP"]), {8})
+
+    def test_unknown_policy(self):
+        with self.assertRaises(ValueError):
+            merge_results([self.first], policy="fastest")
+
+    def test_run_merge_writes_every_file(self):
+        output = os.path.join(self.tmp.name, "merged")
+        with mock.patch("sys.stdout"):
+            run_merge([self.first, self.second], output)
+        with open(os.path.join(output, "SAT", "6.json")) as f:
+            self.assertEqual(json.load(f)["z3_sb_opt"]["obj"], 1)
+        self.assertTrue(os.path.exists(os.path.join(output, "MIP", "8.json")))
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,29 @@
+import unittest
+from source.report.profiles import cactus, performance_profile, solve_times
+
+
+def run(time, optimal=True):
+    return {"time": time, "optimal": optimal, "obj": 1, "sol": [[[1, 2]]]}
+
+
+class ProfilesTest(unittest.TestCase):
+
+    def test_solve_times(self):
+        results = {"SAT": {6: {"z3_sb_opt": run(12), "glucose_sb_opt": run(4), "z3_sb_noopt": run(1)},
+                           8: {"z3_sb_opt": run(300, False)}}}
+        self.assertEqual(solve_times(results), {"SAT": {6: 4, 8: None}})
+
+    def test_performance_profile(self):
+        times = {"CP": {6: 2, 8: 10}, "SAT": {6: 4, 8: None}}
+        self.assertEqual(performance_profile(times), {"CP": [(1.0, 1.0)], "SAT": [(2.0, 0.5)]})
+
+    def test_sub_second_times_count_as_one_second(self):
+        self.assertEqual(performance_profile({"CP": {6: 0}, "SAT": {6: 1}}),
+                         {"CP": [(1.0, 1.0)], "SAT": [(1.0, 1.0)]})
+
+    def test_cactus(self):
+        self.assertEqual(cactus({"CP": {6: 10, 8: 2, 10: None}}), {"CP": [(1, 2), (2, 10)]})
+
+
+if __name__ == "__main__":
+    unittest.main()
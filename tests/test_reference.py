@@ -0,0 +1,40 @@
+from itertools import islice
+import unittest
+from source.checker.checker import result_errors
+from source.common.brute_force import enumerate_schedules, reference_optimum, satisfies_symmetry_breaking
+
+
+def to_result(schedule, n_teams, obj):
+    """
+    Converts a 0-based schedule of (home, away, week, period) to an optimal result of the format.
+    """
+    sol = [[None] * (n_teams - 1) for _ in range(n_teams // 2)]
+    for h, a, w, p in schedule:
+        sol[p][w] = [h + 1, a + 1]
+    return {"time": 0, "optimal": True, "obj": obj, "sol": sol}
+
+
+class ReferenceOptimumTest(unittest.TestCase):
+
+    def test_four_teams_infeasible(self):
+        self.assertEqual(next(enumerate_schedules(4), None), None)
+        self.assertEqual(reference_optimum(4), {"feasible": False, "optimum": None, "schedule": None})
+
+    def test_six_teams(self):
+        reference = reference_optimum(6)
+        self.assertTrue(reference["feasible"])
+        self.assertEqual(reference["optimum"], 1)
+        self.assertEqual(result_errors(to_result(reference["schedule"], 6, 1)), [])
+
+    def test_enumerated_schedules_are_valid(self):
+        for schedule in islice(enumerate_schedules(6), 20):
+            self.assertEqual(result_errors(to_result(schedule, 6, None)), [])
+
+    def test_symmetry_breaking(self):
+        schedule = next(s for s in enumerate_schedules(6, oriented=False) if satisfies_symmetry_breaking(s, 6))
+        self.assertIn((0, 1, 0, 0), schedule)
+        self.assertTrue(all(h < a for h, a, _, _ in schedule))
+
+
+if __name__ == "__main__":
+    unittest.main()
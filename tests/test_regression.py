@@ -0,0 +1,84 @@
+import json
+import os
+import tempfile
+import unittest
+from unittest import mock
+from source.results import regression
+from source.results.regression import batch_regressions, previous_result, run_regressions
+
+SOL = [[[1, 2]]]
+
+
+def run(obj, time, optimal=False):
+    return {"time": time, "optimal": optimal, "obj": obj, "sol": SOL if obj is not None else []}
+
+
+class RunRegressionsTest(unittest.TestCase):
+
+    def test_unsolved(self):
+        self.assertEqual(run_regressions(run(1, 10, True), run(None, 300)),
+                         [("unsolved", "no schedule anymore"), ("unsolved", "optimality claim lost")])
+
+    def test_objective(self):
+        self.assertEqual(run_regressions(run(1, 300), run(3, 300)),
+                         [("objective", "objective regressed from 1 to 3")])
+        self.assertEqual(run_regressions(run(3, 300), run(1, 300)), [])
+
+    def test_slowdown(self):
+        self.assertEqual(run_regressions(run(1, 10, True), run(1, 13, True)),
+                         [("slowdown", "time 10s -> 13s (+30%)")])
+        self.assertEqual(run_regressions(run(1, 0, True), run(1, 5, True)), [("slowdown", "time 0s -> 5s (from 0s)")])
+        # Not more than DEFAULT_SLOWDOWN percent, then under MIN_SLOWDOWN_SECONDS
+        self.assertEqual(run_regressions(run(1, 10, True), run(1, 12, True)), [])
+        self.assertEqual(run_regressions(run(1, 1, True), run(1, 2, True)), [])
+        self.assertEqual(run_regressions(run(1, 10, True), run(1, 12, True), slowdown=10),
+                         [("slowdown", "time 10s -> 12s (+20%)")])
+
+    def test_slowdown_needs_both_proven(self):
+        self.assertEqual(run_regressions(run(1, 10), run(1, 300, True)), [])
+
+
+class BatchRegressionsTest(unittest.TestCase):
+
+    def setUp(self):
+        self.tmp = tempfile.TemporaryDirectory()
+        self.runs = []
+        self.add_run("r1", {"SAT/6.json": {"z3_sb_opt": run(1, 10, True), "z3_sb_noopt": run(None, 2, True)}})
+        self.add_run("r2", {"CP/6.json": {"gecode_sb_opt": run(1, 4, True)}})
+        self.add_run("r3", {"SAT/6.json": {"z3_sb_opt": run(3, 300), "glucose_sb_opt": run(3, 300)}})
+        patches = [mock.patch.object(regression, "RUNS_DIR", self.tmp.name),
+                   mock.patch.object(regression, "load_index", lambda: {"runs": self.runs}),
+                   mock.patch.object(regression, "started_run", lambda: None)]
+        for patch in patches:
+            patch.start()
+            self.addCleanup(patch.stop)
+
+    def tearDown(self):
+        self.tmp.cleanup()
+
+    def add_run(self, run_id, files):
+        for name, results in files.items():
+            path = os.path.join(self.tmp.name, run_id, name)
+            os.makedirs(os.path.dirname(path), exist_ok=True)
+            with open(path, "w") as f:
+                json.dump(results, f)
+        self.runs.append({"run_id": run_id, "directory": run_id, "files": list(files)})
+
+    def test_previous_result(self):
+        self.assertEqual(previous_result(self.runs, 2, "SAT/6.json", "z3_sb_opt"), (self.runs[0], run(1, 10, True)))
+        self.assertEqual(previous_result(self.runs, 2, "SAT/6.json", "glucose_sb_opt"), (None, None))
+
+    def test_latest_run(self):
+        found_run, found = batch_regressions()
+        self.assertEqual(found_run["run_id"], "r3")
+        # glucose_sb_opt has no previous run to compare
+        self.assertEqual(found, [("objective", "SAT/6.json", "z3_sb_opt", "r1", "objective regressed from 1 to 3"),
+                                 ("unsolved", "SAT/6.json", "z3_sb_opt", "r1", "optimality claim lost")])
+
+    def test_run_id(self):
+        self.assertEqual(batch_regressions("r2"), (self.runs[1], []))
+        self.assertEqual(batch_regressions("r4"), (None, []))
+
+
+if __name__ == "__main__":
+    unittest.main()
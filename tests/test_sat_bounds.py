@@ -0,0 +1,41 @@
+import unittest
+
+try:
+    import z3
+except ImportError:
+    z3 = None
+
+
+@unittest.skipIf(z3 is None, "the z3 Python bindings are not installed")
+class EvenMaxDiffBoundTest(unittest.TestCase):
+    """
+    With an odd number of weeks the imbalance |2 * home - weeks| is odd: an even bound max_diff
+    must admit the imbalances up to max_diff - 1 only, i.e. min_home is rounded up.
+    """
+
+    def setUp(self):
+        from source.SAT.build_model import build_model
+        from source.SAT.model.sat_model import at_most_k
+        # n = 6: 5 weeks, a team with a single home game has imbalance 3
+        self.solver, self.home, _, self.weeks, _, _ = build_model(6, use_optimization=True)
+        self.teams = list(range(6))
+        self.solver.add(at_most_k([self.home[0][j][w] for j in self.teams if j != 0 for w in self.weeks], 1))
+
+    def test_pb_encoding(self):
+        from source.SAT.model.sat_model import add_max_diff_constraint
+        self.solver.push()
+        add_max_diff_constraint(self.home, self.teams, self.weeks, 3, self.solver)
+        self.assertEqual(self.solver.check(), z3.sat)
+        self.solver.pop()
+        add_max_diff_constraint(self.home, self.teams, self.weeks, 2, self.solver)
+        self.assertEqual(self.solver.check(), z3.unsat)
+
+    def test_totalizer_encoding(self):
+        from source.SAT.model.sat_model import add_max_diff_totalizer
+        bounds = add_max_diff_totalizer(self.home, self.teams, self.weeks, self.solver)
+        self.assertEqual(self.solver.check(bounds[3]), z3.sat)
+        self.assertEqual(self.solver.check(bounds[2]), z3.unsat)
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,27 @@
+import unittest
+from source.report.scoring import borda_scores, pair_score
+
+
+def run(obj, time, optimal=False):
+    return {"time": time, "optimal": optimal, "obj": obj, "sol": [[[1, 2]]] if obj is not None else []}
+
+
+class BordaTest(unittest.TestCase):
+
+    def test_pair_score(self):
+        self.assertEqual(pair_score(run(1, 300), run(2, 10)), 1)
+        self.assertEqual(pair_score(run(2, 10), run(1, 300)), 0)
+        self.assertEqual(pair_score(run(1, 10, True), run(1, 300)), 1)
+        self.assertEqual(pair_score(run(1, 10, True), run(1, 30, True)), 0.75)
+        self.assertEqual(pair_score(run(1, 0, True), run(1, 0, True)), 0.5)
+        self.assertEqual(pair_score(run(None, 300), run(None, 300)), 0)
+        self.assertEqual(pair_score(run(3, 300), None), 1)
+
+    def test_borda_scores(self):
+        results = {"SAT": {6: {"z3_sb_opt": run(1, 10, True), "z3_sb_noopt": run(None, 1)}},
+                   "MIP": {6: {"highs_sb_opt": run(1, 30, True)}, 8: {"highs_sb_opt": run(3, 300)}}}
+        self.assertEqual(borda_scores(results), [("MIP/highs_sb_opt", 1.25, 1), ("SAT/z3_sb_opt", 0.75, 1)])
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,71 @@
+import json
+import os
+import tempfile
+import unittest
+from unittest import mock
+from source.results.serializer import normalize, schema_errors, serialize, write_file
+from source.results.writer import write_solution
+
+# A valid schedule of n = 6 (see test_checker.py)
+SCHEDULE = [[[3, 2], [4, 5], [5, 6], [1, 6], [1, 4]],
+            [[5, 1], [6, 2], [4, 3], [2, 4], [3, 6]],
+            [[6, 4], [1, 3], [2, 1], [3, 5], [2, 5]]]
+
+
+class NormalizeTest(unittest.TestCase):
+
+    def test_solver_values(self):
+        result = {"params": {"seed": 1}, "sol": [[(1.0, 2)]], "obj": 3.0, "optimal": True, "time": 12.7,
+                  "trace": None}
+        normalized = normalize(result)
+        self.assertEqual(normalized, {"time": 12, "optimal": True, "obj": 3, "sol": [[[1, 2]]], "params": {"seed": 1}})
+        self.assertEqual(list(normalized), ["time", "optimal", "obj", "sol", "params"])
+
+    def test_missing_keys(self):
+        self.assertEqual(normalize({"time": 300}), {"time": 300, "optimal": None, "obj": None, "sol": None})
+
+
+class SerializeTest(unittest.TestCase):
+
+    def test_format(self):
+        text = serialize({"z3_sb_opt": {"time": 1, "optimal": True, "obj": 1, "sol": [[[1, 2], [3, 4]]]}})
+        self.assertEqual(text, '{\n  "z3_sb_opt": {\n    "time": 1,\n    "optimal": true,\n    "obj": 1,\n'
+                               '    "sol": [[[1,2],[3,4]]]\n  }\n}\n')
+
+    def test_schema(self):
+        self.assertEqual(schema_errors({"a": {"time": 1, "optimal": True, "obj": None, "sol": []}}), [])
+        self.assertNotEqual(schema_errors({"a": {"time": 1.5, "optimal": True, "obj": None, "sol": []}}), [])
+
+    def test_strict_write(self):
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "6.json")
+            with self.assertRaises(ValueError):
+                write_file(path, {"a": {"time": "12", "optimal": True, "obj": None, "sol": []}})
+            self.assertFalse(os.path.exists(path))
+            write_file(path, {"a": {"time": "12", "optimal": True, "obj": None, "sol": []}}, strict=False)
+            self.assertTrue(os.path.exists(path))
+
+
+@mock.patch("source.results.writer.push_file")
+@mock.patch("source.results.writer.store_file")
+@mock.patch("source.results.writer.archive_result")
+class WriteSolutionTest(unittest.TestCase):
+
+    def test_checked_then_normalized(self, *_):
+        # As stored by the solvers: tuples in sol, None for the optional keys they did not set
+        sol = [[tuple(match) for match in row] for row in SCHEDULE]
+        results = {"valid": {"sol": sol, "time": 12, "optimal": True, "obj": 1, "params": None},
+                   "invalid": {"sol": sol, "time": 12, "optimal": True, "obj": 3}}
+        with tempfile.TemporaryDirectory() as tmp:
+            with mock.patch("sys.stdout"):
+                path = write_solution(tmp, 6, results)
+            with open(path) as f:
+                written = json.load(f)
+        self.assertEqual(written["valid"], {"time": 12, "optimal": True, "obj": 1, "sol": SCHEDULE})
+        self.assertEqual(list(written["valid"]), ["time", "optimal", "obj", "sol"])
+        self.assertEqual((written["invalid"]["time"], written["invalid"]["optimal"], written["invalid"]["sol"],
+                          written["invalid"]["outcome"]), (300, False, [], "internal error"))
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,27 @@
+import unittest
+from source.report.significance import signed_ranks, wilcoxon
+
+
+class WilcoxonTest(unittest.TestCase):
+
+    def test_signed_ranks_ties_and_zeros(self):
+        self.assertEqual(signed_ranks([0, 1, -1, 2]), [(1.5, -1), (1.5, 1), (3.0, 1)])
+
+    def test_all_positive(self):
+        # Exact two-sided p-value of W- = 0 over 5 pairs: 2 / 2^5
+        test = wilcoxon([1, 2, 3, 4, 5])
+        self.assertEqual((test["w_plus"], test["w_minus"], test["statistic"]), (15, 0, 0))
+        self.assertAlmostEqual(test["p_value"], 0.0625)
+
+    def test_symmetric_differences(self):
+        test = wilcoxon([1, -1, 2, -2])
+        self.assertEqual(test["w_plus"], test["w_minus"])
+        self.assertEqual(test["p_value"], 1.0)
+
+    def test_no_differences(self):
+        test = wilcoxon([0, 0])
+        self.assertEqual((test["pairs"], test["statistic"], test["p_value"]), (2, 0, 1.0))
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,69 @@
+import json
+import os
+import tempfile
+import unittest
+from unittest import mock
+from source.results import sqlite_store
+from source.results.sqlite_store import connect, import_directories, store_result
+
+RESULT = {"time": 12, "optimal": True, "obj": 1, "sol": [[[1, 2], [3, 4]], [[3, 4], [2, 1]]],
+          "params": {"seed": 42}, "metadata": {"run_id": "r1", "solver": "z3", "commit": "abc"}}
+
+
+class StoreResultTest(unittest.TestCase):
+
+    def setUp(self):
+        self.conn = connect(":memory:")
+
+    def tearDown(self):
+        self.conn.close()
+
+    def test_rows(self):
+        store_result(self.conn, "SAT", 4, "z3_sb_opt", RESULT, source="res/SAT/4.json")
+        self.assertEqual(self.conn.execute("SELECT run_id, approach, n, configuration, opt, time, optimal, obj, solved, "
+                                           "commit_hash, solver, source FROM runs").fetchall(),
+                         [("r1", "SAT", 4, "z3_sb_opt", 1, 12, 1, 1, 1, "abc", "z3", "res/SAT/4.json")])
+        # 1-based weeks and periods
+        self.assertEqual(self.conn.execute("SELECT week, period, home, away FROM solutions ORDER BY week, period")
+                         .fetchall(), [(1, 1, 1, 2), (1, 2, 3, 4), (2, 1, 3, 4), (2, 2, 2, 1)])
+        self.assertEqual(self.conn.execute("SELECT key, value FROM statistics").fetchall(), [("params.seed", "42")])
+
+    def test_decision_run(self):
+        store_result(self.conn, "SAT", 4, "z3_sb_noopt", {**RESULT, "obj": None})
+        self.assertEqual(self.conn.execute("SELECT opt, obj FROM runs").fetchall(), [(0, None)])
+
+    def test_same_run_replaced(self):
+        store_result(self.conn, "SAT", 4, "z3_sb_opt", {**RESULT, "time": 300, "optimal": False})
+        store_result(self.conn, "SAT", 4, "z3_sb_opt", RESULT)
+        store_result(self.conn, "SAT", 4, "z3_sb_opt", {**RESULT, "metadata": {"run_id": "r2"}})
+        self.assertEqual(self.conn.execute("SELECT run_id, time FROM runs ORDER BY run_id").fetchall(),
+                         [("r1", 12), ("r2", 12)])
+        self.assertEqual(self.conn.execute("SELECT COUNT(*) FROM solutions").fetchone()[0], 8)
+
+
+class ImportTest(unittest.TestCase):
+
+    def test_import_and_store_file(self):
+        with tempfile.TemporaryDirectory() as tmp:
+            os.makedirs(os.path.join(tmp, "res", "SAT"))
+            path = os.path.join(tmp, "res", "SAT", "4.json")
+            with open(path, "w") as f:
+                json.dump({"z3_sb_opt": RESULT, "z3_sb_noopt": {**RESULT, "metadata": {"run_id": "r1"}}}, f)
+            database = os.path.join(tmp, "results.db")
+            with mock.patch("sys.stdout"):
+                import_directories([os.path.join(tmp, "res")], database)
+
+            # Storing the file again (as the writer does with --sqlite) replaces its rows
+            sqlite_store.set_database(database)
+            try:
+                sqlite_store.store_file(path)
+            finally:
+                sqlite_store.set_database(None)
+            conn = connect(database)
+            self.assertEqual(conn.execute("SELECT configuration FROM runs ORDER BY configuration").fetchall(),
+                             [("z3_sb_noopt",), ("z3_sb_opt",)])
+            conn.close()
+
+
+if __name__ == "__main__":
+    unittest.main()
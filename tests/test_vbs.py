@@ -0,0 +1,40 @@
+import unittest
+from source.report.vbs import contributions, runs_cell, total_time, virtual_best
+
+
+def run(obj, time, optimal=False):
+    return {"time": time, "optimal": optimal, "obj": obj, "sol": [[[1, 2]]] if obj is not None else []}
+
+
+RESULTS = {"CP": {6: {"gecode_sb_opt": run(1, 10, True), "gecode_sb_noopt": run(None, 1, True)},
+                  8: {"gecode_sb_opt": run(3, 300)}},
+           "SAT": {6: {"z3_sb_opt": run(1, 10, True), "glucose_sb_opt": run(1, 40, True)},
+                   8: {"z3_sb_opt": run(1, 50, True)}}}
+
+
+class VirtualBestTest(unittest.TestCase):
+
+    def test_virtual_best(self):
+        vbs = virtual_best(RESULTS)
+        self.assertEqual(vbs[6], {"obj": 1, "obj_by": ["CP/gecode_sb_opt", "SAT/z3_sb_opt", "SAT/glucose_sb_opt"],
+                                  "time": 10, "time_by": ["CP/gecode_sb_opt", "SAT/z3_sb_opt"]})
+        self.assertEqual(vbs[8], {"obj": 1, "obj_by": ["SAT/z3_sb_opt"], "time": 50, "time_by": ["SAT/z3_sb_opt"]})
+
+    def test_exclude(self):
+        self.assertEqual(virtual_best(RESULTS, exclude="SAT")[8],
+                         {"obj": 3, "obj_by": ["CP/gecode_sb_opt"], "time": None, "time_by": []})
+
+    def test_total_time_of_unsolved_instances(self):
+        self.assertEqual(total_time(virtual_best(RESULTS, exclude="SAT"), [6, 8, 10]), 10 + 300 + 300)
+
+    def test_contributions(self):
+        rows = contributions(RESULTS, virtual_best(RESULTS))
+        self.assertEqual(rows, [["CP", "1/2", "1/2", "0", "0s"], ["SAT", "2/2", "2/2", "1", "250s"]])
+
+    def test_runs_cell(self):
+        self.assertEqual(runs_cell([]), "-")
+        self.assertEqual(runs_cell(["CP/a", "SAT/b", "SAT/c"]), "CP/a (+2)")
+
+
+if __name__ == "__main__":
+    unittest.main()